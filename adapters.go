@@ -0,0 +1,94 @@
+package iskiplist
+
+// Stack is a last-in first-out stack backed by an ISkipList. All operations
+// run in constant time. The zero value is an empty Stack.
+type Stack struct {
+	l ISkipList
+}
+
+// Seed seeds the random number generator used by the Stack. If Seed is called,
+// it should be called immediately following creation of the Stack.
+func (s *Stack) Seed(seed1 uint64, seed2 uint64) {
+	s.l.Seed(seed1, seed2)
+}
+
+// Length returns the number of elements in the Stack.
+func (s *Stack) Length() int {
+	return s.l.Length()
+}
+
+// Clear empties the Stack.
+func (s *Stack) Clear() {
+	s.l.Clear()
+}
+
+// Push adds an element to the top of the Stack.
+func (s *Stack) Push(elem ElemType) {
+	s.l.PushFront(elem)
+}
+
+// Pop removes the element at the top of the Stack and returns it. The second
+// return value is false iff the Stack was empty.
+func (s *Stack) Pop() (ElemType, bool) {
+	return s.l.PopFront()
+}
+
+// Peek returns the element at the top of the Stack without removing it. The
+// second return value is false iff the Stack is empty.
+func (s *Stack) Peek() (ElemType, bool) {
+	return s.l.PeekFront()
+}
+
+// List returns the ISkipList underlying the Stack, with the top of the Stack
+// at index 0. This gives access to the full indexed API. The ISkipList may be
+// modified.
+func (s *Stack) List() *ISkipList {
+	return &s.l
+}
+
+// Queue is a first-in first-out queue backed by an ISkipList. Enqueue runs in
+// O(log n) time and the other operations run in constant time. The zero value
+// is an empty Queue.
+type Queue struct {
+	l ISkipList
+}
+
+// Seed seeds the random number generator used by the Queue. If Seed is called,
+// it should be called immediately following creation of the Queue.
+func (q *Queue) Seed(seed1 uint64, seed2 uint64) {
+	q.l.Seed(seed1, seed2)
+}
+
+// Length returns the number of elements in the Queue.
+func (q *Queue) Length() int {
+	return q.l.Length()
+}
+
+// Clear empties the Queue.
+func (q *Queue) Clear() {
+	q.l.Clear()
+}
+
+// Enqueue adds an element to the back of the Queue.
+func (q *Queue) Enqueue(elem ElemType) {
+	q.l.PushBack(elem)
+}
+
+// Dequeue removes the element at the front of the Queue and returns it. The
+// second return value is false iff the Queue was empty.
+func (q *Queue) Dequeue() (ElemType, bool) {
+	return q.l.PopFront()
+}
+
+// Peek returns the element at the front of the Queue without removing it. The
+// second return value is false iff the Queue is empty.
+func (q *Queue) Peek() (ElemType, bool) {
+	return q.l.PeekFront()
+}
+
+// List returns the ISkipList underlying the Queue, with the front of the Queue
+// at index 0. This gives access to the full indexed API. The ISkipList may be
+// modified.
+func (q *Queue) List() *ISkipList {
+	return &q.l
+}
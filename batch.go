@@ -0,0 +1,164 @@
+package iskiplist
+
+import (
+	"fmt"
+
+	"github.com/addrummond/iskiplist/v2/sliceutils"
+)
+
+// ApplyOps applies a sequence of operations to the ISkipList. The result is
+// the same as applying each operation in turn via Insert(), Remove(), Swap(),
+// Truncate() or At(), but runs of operations that affect a contiguous block of
+// elements are coalesced so that the ISkipList is traversed once for each run
+// rather than once for each operation. The following runs are coalesced:
+//
+//   - removals at the same index (removing a block from front to back),
+//   - removals at successively decreasing indices (removing a block from back
+//     to front),
+//   - insertions at successively increasing indices (inserting a block in
+//     order), and
+//   - insertions at the same index (inserting a block in reverse order).
+//
+// A coalesced run of k operations runs in O(k + log n) time rather than
+// O(k log n) time, which makes ApplyOps considerably faster than individual
+// method calls when replaying logs of edits to contiguous regions. Runs of
+// insertions are not coalesced if a maximum length has been set, since
+// evictions could then change the meaning of subsequent operations in the
+// run. ApplyOps panics under the same conditions as the corresponding
+// individual methods; operations preceding the failing operation (or, if the
+// capacity set by SetCapacity() would be exceeded, the failing run) will have
+// been applied.
+func (l *ISkipList) ApplyOps(ops []sliceutils.Op) {
+	if tracing(l) {
+		trace(l, "ApplyOps", ops)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	for i := 0; i < len(ops); {
+		switch ops[i].Kind {
+		case sliceutils.OpInsert:
+			i += applyInsertRun(l, ops[i:])
+		case sliceutils.OpRemove:
+			i += applyRemoveRun(l, ops[i:])
+		case sliceutils.OpSwap:
+			op := &ops[i]
+			if op.Index1 < 0 || op.Index1 >= l.length {
+				panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", op.Index1, l))
+			}
+			if op.Index2 < 0 || op.Index2 >= l.length {
+				panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", op.Index2, l))
+			}
+			swap(l, op.Index1, op.Index2)
+			i++
+		case sliceutils.OpTruncate:
+			op := &ops[i]
+			if op.Index1 < 0 || op.Index1 > l.length {
+				panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", op.Index1, l))
+			}
+			truncate(l, op.Index1)
+			i++
+		case sliceutils.OpAt:
+			op := &ops[i]
+			if op.Index1 < 0 || op.Index1 >= l.length {
+				panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", op.Index1, l))
+			}
+			retrieve(l, op.Index1)
+			i++
+		default:
+			panic(fmt.Sprintf("Unrecognized op kind %v", ops[i].Kind))
+		}
+	}
+}
+
+// applyInsertRun applies the longest coalescable run of insertions at the start
+// of 'ops' and returns the number of operations applied.
+func applyInsertRun(l *ISkipList, ops []sliceutils.Op) int {
+	index := ops[0].Index1
+	if index < 0 || index > l.length {
+		panic("Index out of range in call to 'Insert'")
+	}
+
+	n := 1
+	reversed := false
+	if len(ops) > 1 && ops[1].Kind == sliceutils.OpInsert && (l.ext == nil || l.ext.maxLength == 0) {
+		reversed = ops[1].Index1 == index
+		for n < len(ops) && ops[n].Kind == sliceutils.OpInsert && ops[n].Index1 == insertRunIndex(index, n, reversed) {
+			n++
+		}
+	}
+
+	checkCapacity(l, n)
+
+	if n == 1 {
+		insert(l, index, ops[0].Elem)
+		return 1
+	}
+
+	var b chainBuilder
+	if reversed {
+		for i := n - 1; i >= 0; i-- {
+			b.add(ops[i].Elem)
+		}
+	} else {
+		for i := 0; i < n; i++ {
+			b.add(ops[i].Elem)
+		}
+	}
+	insertListAt(l, index, b.build(l))
+	return n
+}
+
+func insertRunIndex(index, i int, reversed bool) int {
+	if reversed {
+		return index
+	}
+	return index + i
+}
+
+// applyRemoveRun applies the longest coalescable run of removals at the start
+// of 'ops' and returns the number of operations applied.
+func applyRemoveRun(l *ISkipList, ops []sliceutils.Op) int {
+	index := ops[0].Index1
+	if index < 0 || index >= l.length {
+		panic(fmt.Sprintf("Index %v %v out of range in call to 'Remove'", index, l.length))
+	}
+
+	// Runs are only extended while the removals are valid, so that an invalid
+	// removal panics when it's applied individually.
+	n := 1
+	from, to := index, index+1
+	if len(ops) > 1 && ops[1].Kind == sliceutils.OpRemove {
+		if ops[1].Index1 == index {
+			for n < len(ops) && ops[n].Kind == sliceutils.OpRemove && ops[n].Index1 == index && to < l.length {
+				n++
+				to++
+			}
+		} else {
+			for n < len(ops) && ops[n].Kind == sliceutils.OpRemove && ops[n].Index1 == index-n && from > 0 {
+				n++
+				from--
+			}
+		}
+	}
+
+	if n == 1 {
+		removeIndex(l, index)
+	} else {
+		removeRange(l, from, to)
+	}
+	return n
+}
+
+// removeRange removes the elements in [from, to). It runs in O(log n) time if
+// no optional features that track elements are enabled.
+func removeRange(l *ISkipList, from, to int) {
+	if to == l.length {
+		truncate(l, from)
+		return
+	}
+	tail := splitStructure(l, to)
+	splitAt(l, from)
+	joinStructure(l, tail, l)
+}
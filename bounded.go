@@ -0,0 +1,183 @@
+package iskiplist
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SetMaxLength bounds the length of the ISkipList, turning it into a sliding
+// window over a stream of elements. Once the ISkipList has reached its maximum
+// length, adding an element causes an element to be evicted from the other
+// end: PushBack() evicts the first element, PushFront() evicts the last
+// element, and Insert() evicts the first element unless the index is 0, in
+// which case it evicts the last element. (Note that in the last case the
+// inserted element ends up at index-1.)
+//
+// If the ISkipList is longer than n when SetMaxLength is called, elements are
+// evicted from the front until it is n elements long. An argument of 0 removes
+// the bound.
+func (l *ISkipList) SetMaxLength(n int) {
+	if n < 0 {
+		panic(fmt.Sprintf("Negative maximum length %v in call to 'SetMaxLength'", n))
+	}
+
+	if tracing(l) {
+		trace(l, "SetMaxLength", n)
+	}
+
+	if n == 0 {
+		if l.ext != nil {
+			l.ext.maxLength = 0
+		}
+		return
+	}
+
+	getExt(l).maxLength = n
+	for l.length > n {
+		evict(l, 0)
+	}
+}
+
+// MaxLength returns the maximum length set by SetMaxLength, or 0 if the length
+// of the ISkipList is unbounded.
+func (l *ISkipList) MaxLength() int {
+	if l.ext == nil {
+		return 0
+	}
+	return l.ext.maxLength
+}
+
+// enforceMaxLength evicts an element if the ISkipList has grown beyond its
+// maximum length following the insertion of an element at 'index'.
+func enforceMaxLength(l *ISkipList, index int) {
+	if l.ext == nil || l.ext.maxLength == 0 || l.length <= l.ext.maxLength {
+		return
+	}
+
+	if index == 0 {
+		evict(l, l.length-1)
+	} else {
+		evict(l, 0)
+	}
+}
+
+// evict removes the element at 'index' to enforce the maximum length.
+func evict(l *ISkipList, index int) {
+	e := removeIndex(l, index)
+	if l.ext.onEvict != nil {
+		l.ext.onEvict(e)
+	}
+}
+
+// evictAfter truncates the ISkipList to n elements to enforce the maximum
+// length.
+func evictAfter(l *ISkipList, n int) {
+	var evicted []ElemType
+	if l.ext.onEvict != nil && n < l.length {
+		evicted = make([]ElemType, l.length-n)
+		l.CopyRangeToSlice(n, l.length, evicted)
+	}
+	truncate(l, n)
+	for _, e := range evicted {
+		l.ext.onEvict(e)
+	}
+}
+
+// ErrCapacityExceeded is returned by TryInsert(), TryPushFront() and
+// TryPushBack(), and used as the panic value by other methods, when adding
+// elements would take the ISkipList beyond the capacity set by SetCapacity().
+var ErrCapacityExceeded = errors.New("ISkipList capacity exceeded")
+
+// SetCapacity sets a hard limit on the length of the ISkipList. Unlike
+// SetMaxLength(), which silently evicts elements, exceeding the capacity is
+// treated as an error: any method that would add elements beyond the capacity
+// panics with ErrCapacityExceeded before modifying the ISkipList (or, for
+// ApplyOps(), before applying the offending operation). TryInsert(),
+// TryPushFront() and TryPushBack() return ErrCapacityExceeded instead of
+// panicking. If a maximum length is also set, elements evicted to enforce it
+// are taken into account, so the capacity can only be exceeded if it is less
+// than the maximum length.
+//
+// If the ISkipList is already longer than n, no elements are removed, but no
+// elements can be added until it is shorter than n. An argument of 0 removes
+// the limit.
+func (l *ISkipList) SetCapacity(n int) {
+	if n < 0 {
+		panic(fmt.Sprintf("Negative capacity %v in call to 'SetCapacity'", n))
+	}
+
+	if tracing(l) {
+		trace(l, "SetCapacity", n)
+	}
+
+	if n == 0 {
+		if l.ext != nil {
+			l.ext.capacity = 0
+		}
+		return
+	}
+	getExt(l).capacity = n
+}
+
+// Capacity returns the capacity set by SetCapacity, or 0 if there is no limit.
+func (l *ISkipList) Capacity() int {
+	if l.ext == nil {
+		return 0
+	}
+	return l.ext.capacity
+}
+
+// TryInsert is like Insert() except that it returns ErrCapacityExceeded
+// rather than panicking if the ISkipList is at capacity.
+func (l *ISkipList) TryInsert(index int, elem ElemType) error {
+	if err := capacityError(l, 1); err != nil {
+		return err
+	}
+	l.Insert(index, elem)
+	return nil
+}
+
+// TryPushFront is like PushFront() except that it returns
+// ErrCapacityExceeded rather than panicking if the ISkipList is at capacity.
+func (l *ISkipList) TryPushFront(elem ElemType) error {
+	if err := capacityError(l, 1); err != nil {
+		return err
+	}
+	l.PushFront(elem)
+	return nil
+}
+
+// TryPushBack is like PushBack() except that it returns ErrCapacityExceeded
+// rather than panicking if the ISkipList is at capacity.
+func (l *ISkipList) TryPushBack(elem ElemType) error {
+	if err := capacityError(l, 1); err != nil {
+		return err
+	}
+	l.PushBack(elem)
+	return nil
+}
+
+// capacityError returns ErrCapacityExceeded iff adding n elements would take
+// the ISkipList beyond its capacity once any evictions required to enforce
+// its maximum length have been made.
+func capacityError(l *ISkipList, n int) error {
+	if l.ext == nil || l.ext.capacity == 0 || n <= 0 {
+		return nil
+	}
+	newLength := l.length + n
+	if l.ext.maxLength > 0 && newLength > l.ext.maxLength {
+		newLength = l.ext.maxLength
+	}
+	if newLength > l.ext.capacity {
+		return ErrCapacityExceeded
+	}
+	return nil
+}
+
+// checkCapacity panics with ErrCapacityExceeded iff adding n elements would
+// take the ISkipList beyond its capacity.
+func checkCapacity(l *ISkipList, n int) {
+	if err := capacityError(l, n); err != nil {
+		panic(err)
+	}
+}
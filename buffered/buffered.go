@@ -13,10 +13,12 @@
 package bufferediskiplist
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
 
-	"github.com/addrummond/iskiplist"
-	"github.com/addrummond/iskiplist/sliceutils"
+	"github.com/addrummond/iskiplist/v2"
+	"github.com/addrummond/iskiplist/v2/sliceutils"
 )
 
 type BufferedISkipList struct {
@@ -209,6 +211,20 @@ func (l *BufferedISkipList) PopFront() (r iskiplist.ElemType, ok bool) {
 	return
 }
 
+func (l *BufferedISkipList) PeekBack() (r iskiplist.ElemType, ok bool) {
+	if l.Length() == 0 {
+		return
+	}
+	return l.At(l.Length() - 1), true
+}
+
+func (l *BufferedISkipList) PeekFront() (r iskiplist.ElemType, ok bool) {
+	if l.Length() == 0 {
+		return
+	}
+	return l.At(0), true
+}
+
 func (l *BufferedISkipList) At(i int) iskiplist.ElemType {
 	if i < 0 || i >= l.Length() {
 		panic(fmt.Sprintf("Out of bounds index %v into BufferedISkipList %+v", i, l))
@@ -531,3 +547,99 @@ func (l *BufferedISkipList) Update(i int, upd func(iskiplist.ElemType) iskiplist
 	p := l.PtrAt(i)
 	*p = upd(*p)
 }
+
+// Validate checks the internal invariants of the BufferedISkipList (including
+// those of the underlying ISkipList) and returns a non-nil error describing the
+// first violation found. It is intended for use in tests and fuzzing.
+func (l *BufferedISkipList) Validate() error {
+	if len(l.start) > maxSliceLength {
+		return fmt.Errorf("'start' slice has length %v (max %v)", len(l.start), maxSliceLength)
+	}
+	if len(l.end) > maxSliceLength {
+		return fmt.Errorf("'end' slice has length %v (max %v)", len(l.end), maxSliceLength)
+	}
+	return l.iskiplist.Validate()
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. The buffered elements at
+// the start and end of the sequence are serialized as they are, followed by
+// the serialization of the underlying ISkipList (see
+// ISkipList.MarshalBinary()), so that UnmarshalBinary() restores an identical
+// BufferedISkipList.
+func (l *BufferedISkipList) MarshalBinary() ([]byte, error) {
+	sl, err := l.iskiplist.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, 0, 2*binary.MaxVarintLen64+2*(len(l.start)+len(l.end))+len(sl))
+	data = appendElems(data, l.start)
+	data = appendElems(data, l.end)
+	return append(data, sl...), nil
+}
+
+func appendElems(data []byte, elems []iskiplist.ElemType) []byte {
+	data = binary.AppendUvarint(data, uint64(len(elems)))
+	for _, e := range elems {
+		data = binary.AppendVarint(data, int64(e))
+	}
+	return data
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. It replaces the
+// contents of the BufferedISkipList with those serialized by MarshalBinary().
+// An error is returned (and the BufferedISkipList is not modified) if the data
+// are malformed.
+func (l *BufferedISkipList) UnmarshalBinary(data []byte) error {
+	start, data, err := readElems(data)
+	if err != nil {
+		return err
+	}
+	end, data, err := readElems(data)
+	if err != nil {
+		return err
+	}
+	if len(start) > maxSliceLength || len(end) > maxSliceLength {
+		return errors.New("malformed serialized BufferedISkipList: buffer too long")
+	}
+	if err := l.iskiplist.UnmarshalBinary(data); err != nil {
+		return err
+	}
+	l.start = start
+	l.end = end
+	return nil
+}
+
+func readElems(data []byte) ([]iskiplist.ElemType, []byte, error) {
+	n, m := binary.Uvarint(data)
+	if m <= 0 {
+		return nil, nil, errors.New("malformed serialized BufferedISkipList: bad varint")
+	}
+	data = data[m:]
+	if n > uint64(len(data)) {
+		return nil, nil, fmt.Errorf("malformed serialized BufferedISkipList: length %v exceeds available data", n)
+	}
+	var elems []iskiplist.ElemType
+	if n > 0 {
+		elems = make([]iskiplist.ElemType, n)
+	}
+	for i := range elems {
+		e, m := binary.Varint(data)
+		if m <= 0 {
+			return nil, nil, errors.New("malformed serialized BufferedISkipList: bad varint")
+		}
+		elems[i] = iskiplist.ElemType(e)
+		data = data[m:]
+	}
+	return elems, data, nil
+}
+
+// GobEncode implements gob.GobEncoder. The encoding is that of
+// MarshalBinary().
+func (l *BufferedISkipList) GobEncode() ([]byte, error) {
+	return l.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder. See UnmarshalBinary().
+func (l *BufferedISkipList) GobDecode(data []byte) error {
+	return l.UnmarshalBinary(data)
+}
@@ -1,11 +1,13 @@
 package bufferediskiplist
 
 import (
+	"bytes"
+	"encoding/gob"
 	"fmt"
 	"testing"
 
-	"github.com/addrummond/iskiplist"
-	"github.com/addrummond/iskiplist/sliceutils"
+	"github.com/addrummond/iskiplist/v2"
+	"github.com/addrummond/iskiplist/v2/sliceutils"
 )
 
 const (
@@ -25,6 +27,10 @@ func applyOpToISkipList(op *sliceutils.Op, sl *iskiplist.ISkipList) {
 		sl.Remove(op.Index1)
 	case sliceutils.OpSwap:
 		sl.Swap(op.Index1, op.Index2)
+	case sliceutils.OpTruncate:
+		sl.Truncate(op.Index1)
+	case sliceutils.OpAt:
+		sl.At(op.Index1)
 	}
 }
 
@@ -164,6 +170,32 @@ func benchmarkRandomOpSequenceWithSlice(ops []sliceutils.Op, a []int, l int) {
 	}
 }
 
+func FuzzBufferedOps(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0, 0, 0, 1, 0, 0, 0, 2, 0, 1, 0, 3, 2, 0, 1, 0, 1, 1, 0, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ops := sliceutils.DecodeOps(data, 0)
+		var sl BufferedISkipList
+		sl.Seed(randSeed1, randSeed2)
+		a := make([]iskiplist.ElemType, 0)
+		for i, o := range ops {
+			sliceutils.ApplyOpToSlice(&o, &a)
+			applyOpToBufferedISkipList(&o, &sl)
+			if err := sl.Validate(); err != nil {
+				t.Fatalf("Invalid BufferedISkipList after op %v (%s): %v\n\n%s", i, sliceutils.PrintOp(&o), err, sliceutils.GoCode(ops[:i+1], "sl"))
+			}
+			if sl.Length() != len(a) {
+				t.Fatalf("BufferedISkipList has wrong length (%v instead of %v)", sl.Length(), len(a))
+			}
+		}
+		sl.ForAllI(func(i int, v *iskiplist.ElemType) {
+			if *v != a[i] {
+				t.Fatalf("Expected value %v at index %v, got %v instead\n\n%s", a[i], i, *v, sliceutils.GoCode(ops, "sl"))
+			}
+		})
+	})
+}
+
 func BenchmarkRandomOpSequence(b *testing.B) {
 	const nops = 500
 
@@ -214,3 +246,39 @@ func BenchmarkRandomOpSequence(b *testing.B) {
 		})
 	}
 }
+
+func TestGob(t *testing.T) {
+	var sl BufferedISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 3000; i++ {
+		sl.PushBack(intToElem(i))
+	}
+	for i := 0; i < 10; i++ {
+		sl.PushFront(intToElem(-i))
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&sl); err != nil {
+		t.Fatalf("Unexpected error encoding: %v", err)
+	}
+	var decoded BufferedISkipList
+	decoded.PushBack(99)
+	if err := gob.NewDecoder(&buf).Decode(&decoded); err != nil {
+		t.Fatalf("Unexpected error decoding: %v", err)
+	}
+	if err := decoded.Validate(); err != nil {
+		t.Fatalf("Decoded BufferedISkipList is invalid: %v", err)
+	}
+	if decoded.Length() != sl.Length() {
+		t.Fatalf("Decoded BufferedISkipList has length %v, expected %v", decoded.Length(), sl.Length())
+	}
+	for i := 0; i < sl.Length(); i++ {
+		if decoded.At(i) != sl.At(i) {
+			t.Errorf("Values don't match at index %v", i)
+		}
+	}
+
+	if err := decoded.UnmarshalBinary([]byte{5, 1}); err == nil {
+		t.Errorf("Expected error unmarshaling truncated data")
+	}
+}
@@ -0,0 +1,96 @@
+package iskiplist
+
+import (
+	"errors"
+
+	"github.com/addrummond/iskiplist/v2/pcg"
+)
+
+// A Checkpoint is a snapshot of the complete state of an ISkipList, as
+// returned by ISkipList.Checkpoint(). A Checkpoint is immutable and may be
+// restored any number of times.
+type Checkpoint struct {
+	list           *ISkipList
+	pcg64          *pcg.Pcg64 // nil if the built-in PCG32 generator is used
+	cacheIndex     int        // -1 if the cache was invalid
+	maxLength      int
+	capacity       int
+	reseedInterval int
+	untilReseed    int
+}
+
+// Checkpoint returns a snapshot of the complete state of the ISkipList: its
+// elements, its level structure, the state of its pseudorandom number
+// generator (including a generator set by SeedPCG64()), its maximum length,
+// capacity and reseeding interval, and the position of its index cache.
+// Restoring the Checkpoint with Restore() returns the ISkipList to exactly
+// this state, so that a simulation using the ISkipList can be checkpointed
+// and resumed deterministically. Optional indices and augmentations (such as
+// the value index) are not part of the snapshot. Checkpoint runs in O(n)
+// time. It returns an error if the ISkipList uses a custom LevelSource (see
+// SetLevelSource()), as the state of the source can't be captured. See also
+// MarshalBinary() for a serializable snapshot.
+func (l *ISkipList) Checkpoint() (*Checkpoint, error) {
+	c := &Checkpoint{
+		list:       l.Copy(),
+		cacheIndex: -1,
+	}
+	c.list.rand = l.rand
+	if l.cache != nil && l.cache.isValid() {
+		c.cacheIndex = l.cache.index
+	}
+	if l.ext != nil {
+		if l.ext.levelSource != nil {
+			src, ok := l.ext.levelSource.(pcg64LevelSource)
+			if !ok {
+				return nil, errors.New("cannot checkpoint an ISkipList with a custom LevelSource")
+			}
+			r := *src.rand
+			c.pcg64 = &r
+		}
+		c.maxLength = l.ext.maxLength
+		c.capacity = l.ext.capacity
+		c.reseedInterval = l.ext.reseedInterval
+		c.untilReseed = l.ext.untilReseed
+	}
+	return c, nil
+}
+
+// Restore returns the ISkipList to the state captured by a Checkpoint, which
+// need not have been taken from the same ISkipList. Optional indices and
+// augmentations enabled for the ISkipList remain enabled and are updated to
+// reflect the restored contents (element IDs are reassigned). Restore runs in
+// O(n) time.
+func (l *ISkipList) Restore(c *Checkpoint) {
+	if tracing(l) {
+		trace(l, "Restore", c)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	cp := c.list.Copy()
+	clearList(l)
+	l.root = cp.root
+	l.nLevels = cp.nLevels
+	l.length = cp.length
+	l.rand = c.list.rand
+
+	if c.pcg64 != nil || c.maxLength != 0 || c.capacity != 0 || c.reseedInterval != 0 || l.ext != nil {
+		ext := getExt(l)
+		ext.levelSource = nil
+		if c.pcg64 != nil {
+			r := *c.pcg64
+			ext.levelSource = pcg64LevelSource{&r}
+		}
+		ext.maxLength = c.maxLength
+		ext.capacity = c.capacity
+		ext.reseedInterval = c.reseedInterval
+		ext.untilReseed = c.untilReseed
+	}
+	noteInsertRange(l, 0, l.length)
+
+	if c.cacheIndex >= 0 && c.cacheIndex < l.length {
+		retrieve(l, c.cacheIndex)
+	}
+}
@@ -0,0 +1,165 @@
+package iskiplist
+
+import "fmt"
+
+// This file contains operations that compare the elements of an ISkipList with
+// those of a slice or another ISkipList. Rather than exporting both operands to
+// slices, they walk the densest level(s) in step and exit as soon as the
+// result is known.
+
+// densestAt returns the node on the densest level at the specified index, or
+// nil if the index is equal to the length of the ISkipList.
+func densestAt(l *ISkipList, index int) *listNode {
+	if index == l.length {
+		return nil
+	}
+	// getTo rather than retrieve, as the latter allocates.
+	return getTo(l.root, index, searchObserver(l))
+}
+
+// nodesEqualSlice returns true iff the len(s) elements starting at 'node' are
+// equal to the elements of s.
+func nodesEqualSlice(node *listNode, s []ElemType) bool {
+	for _, v := range s {
+		if node.elem != v {
+			return false
+		}
+		node = node.next
+	}
+	return true
+}
+
+// nodesEqual returns true iff the n elements starting at 'a' are equal to the
+// n elements starting at 'b'.
+func nodesEqual(a, b *listNode, n int) bool {
+	for i := 0; i < n; i++ {
+		if a.elem != b.elem {
+			return false
+		}
+		a = a.next
+		b = b.next
+	}
+	return true
+}
+
+// Equal returns true iff the ISkipList and 'other' have the same length and
+// equal elements at each index. The level structures of the two ISkipLists
+// are not compared. Equal runs in O(n) time, and in constant time if the
+// lengths differ. 'other' may be the same ISkipList.
+func (l *ISkipList) Equal(other *ISkipList) bool {
+	if l.length != other.length {
+		return false
+	}
+	if l == other {
+		return true
+	}
+	return nodesEqual(densestAt(l, 0), densestAt(other, 0), l.length)
+}
+
+// StartsWith returns true iff the first len(s) elements of the ISkipList are
+// equal to the elements of s. It runs in O(len(s)) time.
+func (l *ISkipList) StartsWith(s []ElemType) bool {
+	if len(s) > l.length {
+		return false
+	}
+	return nodesEqualSlice(densestAt(l, 0), s)
+}
+
+// StartsWithList returns true iff the ISkipList starts with the elements of
+// 'other'. It runs in O(m) time, where m is the length of 'other'.
+func (l *ISkipList) StartsWithList(other *ISkipList) bool {
+	if other.length > l.length {
+		return false
+	}
+	return nodesEqual(densestAt(l, 0), densestAt(other, 0), other.length)
+}
+
+// EndsWith returns true iff the last len(s) elements of the ISkipList are equal
+// to the elements of s. It runs in O(log n + len(s)) time.
+func (l *ISkipList) EndsWith(s []ElemType) bool {
+	if len(s) > l.length {
+		return false
+	}
+	return nodesEqualSlice(densestAt(l, l.length-len(s)), s)
+}
+
+// EndsWithList returns true iff the ISkipList ends with the elements of
+// 'other'. It runs in O(log n + m) time, where m is the length of 'other'.
+func (l *ISkipList) EndsWithList(other *ISkipList) bool {
+	if other.length > l.length {
+		return false
+	}
+	return nodesEqual(densestAt(l, l.length-other.length), densestAt(other, 0), other.length)
+}
+
+// ContainsSubsequence returns true iff the elements of s occur in the ISkipList
+// in the same order, though not necessarily contiguously. It walks the
+// ISkipList once, exiting as soon as all the elements of s have been found or
+// there are too few elements left for them to be found, so it runs in O(n)
+// time.
+func (l *ISkipList) ContainsSubsequence(s []ElemType) bool {
+	j := 0
+	remaining := l.length
+	for node := densestAt(l, 0); j < len(s) && remaining >= len(s)-j; node = node.next {
+		if node.elem == s[j] {
+			j++
+		}
+		remaining--
+	}
+	return j == len(s)
+}
+
+// ContainsSubsequenceList returns true iff the elements of 'other' occur in the
+// ISkipList in the same order, though not necessarily contiguously. See
+// ContainsSubsequence().
+func (l *ISkipList) ContainsSubsequenceList(other *ISkipList) bool {
+	want := densestAt(other, 0)
+	found := 0
+	remaining := l.length
+	for node := densestAt(l, 0); found < other.length && remaining >= other.length-found; node = node.next {
+		if node.elem == want.elem {
+			want = want.next
+			found++
+		}
+		remaining--
+	}
+	return found == other.length
+}
+
+// RangeEqualsSlice returns true iff the len(s) elements of the ISkipList
+// starting at index 'from' are equal to the elements of s. It returns false if
+// there are fewer than len(s) elements starting at 'from'. The 'from' argument
+// must be >= 0 and <= the length of the ISkipList. RangeEqualsSlice runs in
+// O(log n + len(s)) time.
+func (l *ISkipList) RangeEqualsSlice(from int, s []ElemType) bool {
+	if from < 0 || from > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", from, l))
+	}
+	if from+len(s) > l.length {
+		return false
+	}
+	return nodesEqualSlice(densestAt(l, from), s)
+}
+
+// RangeEquals returns true iff the n elements of the ISkipList starting at
+// index 'from' are equal to the n elements of 'other' starting at index
+// 'otherFrom'. It returns false if either ISkipList has fewer than n elements
+// starting at the relevant index. The 'from' and 'otherFrom' arguments must be
+// >= 0 and <= the length of the relevant ISkipList. RangeEquals runs in
+// O(log n + log m + n) time, where m is the length of 'other'. The ISkipList
+// 'other' may be the same ISkipList.
+func (l *ISkipList) RangeEquals(from int, other *ISkipList, otherFrom, n int) bool {
+	if from < 0 || from > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", from, l))
+	}
+	if otherFrom < 0 || otherFrom > other.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", otherFrom, other))
+	}
+	if n < 0 || from+n > l.length || otherFrom+n > other.length {
+		return false
+	}
+	if n == 0 {
+		return true
+	}
+	return nodesEqual(densestAt(l, from), densestAt(other, otherFrom), n)
+}
@@ -0,0 +1,84 @@
+package iskiplist
+
+// DistinctCount returns the number of distinct values in the ISkipList. If the
+// value index is enabled (see EnableValueIndex()), this takes O(1) time;
+// otherwise, the ISkipList is scanned once, taking O(n) time.
+func (l *ISkipList) DistinctCount() int {
+	if l.HasValueIndex() {
+		return len(l.ext.byValue)
+	}
+	return len(l.Frequencies())
+}
+
+// Frequencies returns a map from each value in the ISkipList to the number of
+// times that it occurs. If the value index is enabled, the map is built from
+// the index in O(d) time, where d is the number of distinct values;
+// otherwise, the ISkipList is scanned once, taking O(n) time.
+func (l *ISkipList) Frequencies() map[ElemType]int {
+	if l.HasValueIndex() {
+		freqs := make(map[ElemType]int, len(l.ext.byValue))
+		for v, nodes := range l.ext.byValue {
+			freqs[v] = len(nodes)
+		}
+		return freqs
+	}
+
+	freqs := make(map[ElemType]int)
+	l.ForAll(func(e *ElemType) {
+		freqs[*e]++
+	})
+	return freqs
+}
+
+// Mode returns the most frequent value in the ISkipList and the number of
+// times that it occurs. If several values are equally frequent, the one that
+// occurs first in the ISkipList is returned. The count is 0 iff the ISkipList
+// is empty. Mode first looks for a majority element (one that makes up more
+// than half of the ISkipList) using the Boyer-Moore majority vote algorithm,
+// which takes two passes and no additional memory. If there is no majority
+// element, it falls back to counting the frequencies of all values (see
+// Frequencies()).
+func (l *ISkipList) Mode() (ElemType, int) {
+	var zero ElemType
+	if l.length == 0 {
+		return zero, 0
+	}
+
+	candidate, votes := zero, 0
+	l.ForAll(func(e *ElemType) {
+		if votes == 0 {
+			candidate = *e
+			votes = 1
+		} else if *e == candidate {
+			votes++
+		} else {
+			votes--
+		}
+	})
+	count := 0
+	l.ForAll(func(e *ElemType) {
+		if *e == candidate {
+			count++
+		}
+	})
+	if count > l.length/2 {
+		return candidate, count
+	}
+
+	freqs := l.Frequencies()
+	max := 0
+	for _, n := range freqs {
+		if n > max {
+			max = n
+		}
+	}
+	mode := zero
+	l.Iterate(func(e *ElemType) bool {
+		if freqs[*e] == max {
+			mode = *e
+			return false
+		}
+		return true
+	})
+	return mode, max
+}
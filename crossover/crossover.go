@@ -0,0 +1,197 @@
+// Package crossover runs the slice vs. ISkipList vs. BufferedISkipList
+// benchmark matrix programmatically and reports the results in a structured
+// form. The performance of an ISkipList relative to a slice depends heavily
+// on the hardware, so an application can use this package (e.g. at build or
+// deployment time) to find the length at which it's worth switching from a
+// slice to an ISkipList on a given target.
+//
+// Each benchmark applies the same random sequence of insertions, removals and
+// swaps to a sequence of a given initial length. Only the time taken to apply
+// the operations is measured; the time taken to construct the initial
+// sequence is not.
+//
+// A Report can be converted to JSON using encoding/json.
+package crossover
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/addrummond/iskiplist/v2"
+	bufferediskiplist "github.com/addrummond/iskiplist/v2/buffered"
+	"github.com/addrummond/iskiplist/v2/sliceutils"
+)
+
+// Config specifies the benchmark matrix to run. The zero value is not useful;
+// start from DefaultConfig().
+type Config struct {
+	// The initial lengths of the sequences to benchmark, in increasing order.
+	Lengths []int
+	// The number of random operations applied in each run.
+	NOps int
+	// Each benchmark is repeated until it has run for at least this long.
+	MinDuration time.Duration
+	// Seeds for the ISkipLists.
+	Seed1, Seed2 uint64
+}
+
+// DefaultConfig returns a Config that benchmarks initial lengths from 0 to
+// 100,000. Running the full matrix takes several seconds.
+func DefaultConfig() Config {
+	lengths := []int{0, 16, 32, 64, 128, 256, 512}
+	for i := 1000; i <= 100000; i *= 2 {
+		lengths = append(lengths, i)
+	}
+	return Config{
+		Lengths:     lengths,
+		NOps:        500,
+		MinDuration: 100 * time.Millisecond,
+		Seed1:       12345,
+		Seed2:       67891,
+	}
+}
+
+// Result gives the mean time taken per operation for each kind of sequence at
+// a given initial length.
+type Result struct {
+	InitialLength            int     `json:"initial_length"`
+	SliceNsPerOp             float64 `json:"slice_ns_per_op"`
+	ISkipListNsPerOp         float64 `json:"iskiplist_ns_per_op"`
+	BufferedISkipListNsPerOp float64 `json:"buffered_iskiplist_ns_per_op"`
+}
+
+// Report is the result of running a benchmark matrix.
+type Report struct {
+	GOOS    string   `json:"goos"`
+	GOARCH  string   `json:"goarch"`
+	NOps    int      `json:"n_ops"`
+	Results []Result `json:"results"`
+	// The smallest initial length at which an ISkipList was faster than a
+	// slice, or -1 if it was never faster.
+	ISkipListCrossover int `json:"iskiplist_crossover"`
+	// The smallest initial length at which a BufferedISkipList was faster
+	// than a slice, or -1 if it was never faster.
+	BufferedISkipListCrossover int `json:"buffered_iskiplist_crossover"`
+}
+
+// Run runs the benchmark matrix specified by the Config.
+func Run(cfg Config) Report {
+	ops := sliceutils.GenOps(cfg.NOps, 0)
+
+	r := Report{
+		GOOS:                       runtime.GOOS,
+		GOARCH:                     runtime.GOARCH,
+		NOps:                       cfg.NOps,
+		ISkipListCrossover:         -1,
+		BufferedISkipListCrossover: -1,
+	}
+
+	for _, length := range cfg.Lengths {
+		res := Result{InitialLength: length}
+
+		res.SliceNsPerOp = measure(cfg, func() func() {
+			a := make([]iskiplist.ElemType, length)
+			for i := range a {
+				a[i] = i
+			}
+			return func() {
+				for _, o := range ops {
+					sliceutils.ApplyOpToSlice(&o, &a)
+				}
+			}
+		})
+
+		res.ISkipListNsPerOp = measure(cfg, func() func() {
+			var sl iskiplist.ISkipList
+			sl.Seed(cfg.Seed1, cfg.Seed2)
+			for i := 0; i < length; i++ {
+				if i%2 == 0 {
+					sl.PushBack(i)
+				} else {
+					sl.PushFront(i)
+				}
+			}
+			return func() {
+				for _, o := range ops {
+					applyOpToISkipList(&o, &sl)
+				}
+			}
+		})
+
+		res.BufferedISkipListNsPerOp = measure(cfg, func() func() {
+			var sl bufferediskiplist.BufferedISkipList
+			sl.Seed(cfg.Seed1, cfg.Seed2)
+			for i := 0; i < length; i++ {
+				if i%2 == 0 {
+					sl.PushBack(i)
+				} else {
+					sl.PushFront(i)
+				}
+			}
+			return func() {
+				for _, o := range ops {
+					applyOpToBufferedISkipList(&o, &sl)
+				}
+			}
+		})
+
+		if r.ISkipListCrossover == -1 && res.ISkipListNsPerOp < res.SliceNsPerOp {
+			r.ISkipListCrossover = length
+		}
+		if r.BufferedISkipListCrossover == -1 && res.BufferedISkipListNsPerOp < res.SliceNsPerOp {
+			r.BufferedISkipListCrossover = length
+		}
+
+		r.Results = append(r.Results, res)
+	}
+
+	return r
+}
+
+// measure repeatedly calls 'prepare' to set up a run and then times the
+// function that it returns. It returns the mean time taken per op.
+func measure(cfg Config, prepare func() func()) float64 {
+	var total time.Duration
+	runs := 0
+	for total < cfg.MinDuration || runs == 0 {
+		run := prepare()
+		start := time.Now()
+		run()
+		total += time.Since(start)
+		runs++
+	}
+	if cfg.NOps == 0 {
+		return 0
+	}
+	return float64(total.Nanoseconds()) / float64(runs*cfg.NOps)
+}
+
+func applyOpToISkipList(op *sliceutils.Op, sl *iskiplist.ISkipList) {
+	switch op.Kind {
+	case sliceutils.OpInsert:
+		sl.Insert(op.Index1, op.Elem)
+	case sliceutils.OpRemove:
+		sl.Remove(op.Index1)
+	case sliceutils.OpSwap:
+		sl.Swap(op.Index1, op.Index2)
+	case sliceutils.OpTruncate:
+		sl.Truncate(op.Index1)
+	case sliceutils.OpAt:
+		sl.At(op.Index1)
+	}
+}
+
+func applyOpToBufferedISkipList(op *sliceutils.Op, sl *bufferediskiplist.BufferedISkipList) {
+	switch op.Kind {
+	case sliceutils.OpInsert:
+		sl.Insert(op.Index1, op.Elem)
+	case sliceutils.OpRemove:
+		sl.Remove(op.Index1)
+	case sliceutils.OpSwap:
+		sl.Swap(op.Index1, op.Index2)
+	case sliceutils.OpTruncate:
+		sl.Truncate(op.Index1)
+	case sliceutils.OpAt:
+		sl.At(op.Index1)
+	}
+}
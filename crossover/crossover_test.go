@@ -0,0 +1,41 @@
+package crossover
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Lengths = []int{0, 100, 10000}
+	cfg.NOps = 50
+	cfg.MinDuration = time.Millisecond
+
+	r := Run(cfg)
+	if len(r.Results) != len(cfg.Lengths) {
+		t.Fatalf("Expected %v results, got %v\n", len(cfg.Lengths), len(r.Results))
+	}
+	for i, res := range r.Results {
+		if res.InitialLength != cfg.Lengths[i] {
+			t.Errorf("Expected initial length %v, got %v\n", cfg.Lengths[i], res.InitialLength)
+		}
+		if res.SliceNsPerOp <= 0 || res.ISkipListNsPerOp <= 0 || res.BufferedISkipListNsPerOp <= 0 {
+			t.Errorf("Expected positive timings, got %+v\n", res)
+		}
+	}
+
+	j, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%s\n", j)
+
+	var r2 Report
+	if err := json.Unmarshal(j, &r2); err != nil {
+		t.Fatal(err)
+	}
+	if len(r2.Results) != len(r.Results) || r2.ISkipListCrossover != r.ISkipListCrossover {
+		t.Errorf("JSON round trip failed: %+v\n", r2)
+	}
+}
@@ -0,0 +1,174 @@
+package iskiplist
+
+import "fmt"
+
+// A Cursor is a position within an ISkipList that can be moved backwards and
+// forwards, and through which the ISkipList can be modified. A Cursor is
+// positioned either at an element or at the end of the ISkipList (i.e. at the
+// index equal to its length). Moving to the next element takes constant time.
+// Other movements take O(log n) time, but each Cursor keeps its own finger
+// (the last node visited on each level), so that seeking forwards starts from
+// the Cursor's previous position rather than from the root.
+//
+// Unlike the Iterate* methods, a Cursor allows elements to be inserted and
+// removed as the ISkipList is traversed, so long as this is done via the
+// Cursor. Any other modification of the ISkipList invalidates the Cursor
+// (though it may be reused after calling Seek()).
+type Cursor struct {
+	l           *ISkipList
+	index       int
+	node        *listNode // nil iff at the end
+	prevs       []*listNode
+	prevIndices []int
+	hasFinger   bool
+}
+
+// Cursor returns a new Cursor positioned at the specified index, which must be
+// >= 0 and <= the length of the ISkipList.
+func (l *ISkipList) Cursor(index int) *Cursor {
+	c := &Cursor{l: l}
+	c.Seek(index)
+	return c
+}
+
+// Seek moves the Cursor to the specified index, which must be >= 0 and <= the
+// length of the ISkipList.
+func (c *Cursor) Seek(index int) {
+	l := c.l
+	if index < 0 || index > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", index, l))
+	}
+
+	c.index = index
+	if index == l.length {
+		c.node = nil
+		return
+	}
+
+	if len(c.prevs) != int(l.nLevels) {
+		c.prevs = make([]*listNode, l.nLevels)
+		c.prevIndices = make([]int, l.nLevels)
+		c.hasFinger = false
+	}
+	if c.hasFinger && len(c.prevs) > 0 && c.prevIndices[0] <= index {
+		pi := c.prevIndices[0]
+		c.node = getToWithPrevIndices(c.prevs[0], index-pi, c.prevs, c.prevIndices, searchObserver(l))
+		for j := range c.prevIndices {
+			c.prevIndices[j] += pi
+		}
+	} else {
+		c.node = getToWithPrevIndices(l.root, index, c.prevs, c.prevIndices, searchObserver(l))
+	}
+	c.hasFinger = true
+}
+
+// Index returns the index of the Cursor.
+func (c *Cursor) Index() int {
+	return c.index
+}
+
+// Valid returns true iff the Cursor is positioned at an element (rather than at
+// the end of the ISkipList).
+func (c *Cursor) Valid() bool {
+	return c.node != nil
+}
+
+// Next moves the Cursor to the next element (or to the end of the ISkipList)
+// and returns true iff it is then positioned at an element. If the Cursor is
+// already at the end, it stays there. Next runs in constant time.
+func (c *Cursor) Next() bool {
+	if c.node == nil {
+		return false
+	}
+	c.node = c.node.next
+	c.index++
+	return c.node != nil
+}
+
+// Prev moves the Cursor to the previous element and returns true, or returns
+// false if the Cursor is at index 0 (in which case it doesn't move).
+func (c *Cursor) Prev() bool {
+	if c.index == 0 {
+		return false
+	}
+	c.Seek(c.index - 1)
+	return true
+}
+
+func (c *Cursor) checkValid() {
+	if c.node == nil {
+		panic(fmt.Sprintf("Cursor at index %v is not positioned at an element of ISkipList %+v", c.index, c.l))
+	}
+}
+
+// Value returns the element at the Cursor. It panics if the Cursor is at the
+// end of the ISkipList.
+func (c *Cursor) Value() ElemType {
+	c.checkValid()
+	return c.node.elem
+}
+
+// Set sets the element at the Cursor. It panics if the Cursor is at the end of
+// the ISkipList.
+func (c *Cursor) Set(v ElemType) {
+	c.checkValid()
+	l := c.l
+	if tracing(l) {
+		trace(l, "Set", c.index, v)
+	}
+
+	old := c.node.elem
+	c.node.elem = v
+	noteSet(l, c.index, old, v)
+}
+
+// InsertBefore inserts an element before the element at the Cursor (or at the
+// end of the ISkipList if the Cursor is at the end). The Cursor remains
+// positioned at the same element, whose index increases by one. If an element
+// is evicted to enforce the ISkipList's maximum length (see SetMaxLength()),
+// the Cursor is instead repositioned at its original index (or at the end of
+// the ISkipList if it is now shorter than that).
+func (c *Cursor) InsertBefore(v ElemType) {
+	l := c.l
+	checkCapacity(l, 1)
+
+	if tracing(l) {
+		trace(l, "Insert", c.index, v)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	before := l.length
+	insert(l, c.index, v)
+	c.hasFinger = false
+	if l.length > before {
+		c.index++
+		return
+	}
+	if c.index > l.length {
+		c.index = l.length
+	}
+	c.Seek(c.index)
+}
+
+// Remove removes the element at the Cursor and returns it. The Cursor is then
+// positioned at the following element (or at the end of the ISkipList), which
+// has the same index as the removed element. It panics if the Cursor is at the
+// end of the ISkipList.
+func (c *Cursor) Remove() ElemType {
+	c.checkValid()
+	l := c.l
+	if tracing(l) {
+		trace(l, "Remove", c.index)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	next := c.node.next
+	e := removeIndex(l, c.index)
+	c.node = next
+	c.hasFinger = false
+	return e
+}
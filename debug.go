@@ -0,0 +1,525 @@
+package iskiplist
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	// 'unsafe' is used only for Sizeof.
+	"unsafe"
+)
+
+// StructurallyEqual returns true iff two ISkipLists have the same elements,
+// the same number of levels, and the same nodes on each level (i.e. the same
+// level assignments and the same distances between nodes). This is stricter
+// than value equality, and is useful for testing that operations such as
+// Copy() preserve the exact structure of an ISkipList. The cache and the
+// pseudorandom number generator state are not compared.
+func StructurallyEqual(a, b *ISkipList) bool {
+	if a.length != b.length || a.nLevels != b.nLevels {
+		return false
+	}
+
+	la, lb := a.root, b.root
+	for la != nil && lb != nil {
+		na, nb := la, lb
+		for na != nil && nb != nil {
+			if (na.nextLevel == nil) != (nb.nextLevel == nil) {
+				return false
+			}
+			// On sparse levels, 'elem' is a distance, and is meaningless if
+			// there is no next node.
+			if (na.nextLevel == nil || na.next != nil) && na.elem != nb.elem {
+				return false
+			}
+			na, nb = na.next, nb.next
+		}
+		if na != nil || nb != nil {
+			return false
+		}
+		la, lb = la.nextLevel, lb.nextLevel
+	}
+
+	return la == nil && lb == nil
+}
+
+// Validate checks the internal invariants of an ISkipList and returns a
+// non-nil error describing the first violation found. It is intended for use
+// in tests and fuzzing. Validate runs in O(n log n) time.
+//
+// The following invariants are checked:
+//
+//   - The number of levels is consistent with nLevels, and the root node is
+//     present on every level.
+//   - The densest level has exactly Length() nodes.
+//   - Every node on a sparse level points down to the node at the same
+//     position on the level below, and stores the correct distance to the
+//     next node on its level.
+//   - The index cache (if valid) points to a node on the sparsest level at
+//     the recorded position.
+//   - State maintained for optional features (e.g. the value index) is
+//     consistent with the elements of the ISkipList.
+func (l *ISkipList) Validate() error {
+	if l.length < 0 {
+		return fmt.Errorf("negative length %v", l.length)
+	}
+	if l.root == nil {
+		if l.length != 0 {
+			return fmt.Errorf("nil root but length is %v", l.length)
+		}
+		return validateExt(l)
+	}
+	if l.nLevels < 0 {
+		return fmt.Errorf("negative nLevels %v", l.nLevels)
+	}
+
+	var levels []*listNode
+	for n := l.root; n != nil; n = n.nextLevel {
+		levels = append(levels, n)
+	}
+	if len(levels) != int(l.nLevels)+1 {
+		return fmt.Errorf("expected %v levels, found %v", l.nLevels+1, len(levels))
+	}
+
+	// Positions of nodes on the level below the one currently being checked.
+	below := make(map[*listNode]int)
+	dense := 0
+	for n := levels[len(levels)-1]; n != nil; n = n.next {
+		if n.nextLevel != nil {
+			return fmt.Errorf("node at index %v on densest level has a nextLevel pointer", dense)
+		}
+		below[n] = dense
+		dense++
+	}
+	if dense != l.length {
+		return fmt.Errorf("densest level has %v nodes but length is %v", dense, l.length)
+	}
+
+	for li := len(levels) - 2; li >= 0; li-- {
+		current := make(map[*listNode]int)
+		prevPos := -1
+		for n := levels[li]; n != nil; n = n.next {
+			pos, ok := below[n.nextLevel]
+			if !ok {
+				return fmt.Errorf("node on level %v does not point down to a node on level %v", li, li+1)
+			}
+			if pos <= prevPos {
+				return fmt.Errorf("nodes on level %v are out of order (position %v follows %v)", li, pos, prevPos)
+			}
+			if prevPos == -1 && pos != 0 {
+				return fmt.Errorf("level %v does not start at position 0", li)
+			}
+			if n.next != nil {
+				nextPos, ok := below[n.next.nextLevel]
+				if !ok {
+					return fmt.Errorf("node on level %v does not point down to a node on level %v", li, li+1)
+				}
+				if d := elemToDist(n.elem); d != nextPos-pos {
+					return fmt.Errorf("node at position %v on level %v has distance %v, expected %v", pos, li, d, nextPos-pos)
+				}
+			}
+			current[n] = pos
+			prevPos = pos
+		}
+		below = current
+	}
+
+	if c := l.cache; c != nil && c.isValid() && len(c.prevs) > 0 {
+		if len(c.prevs) != len(c.prevIndices) {
+			return fmt.Errorf("index cache has %v nodes but %v indices", len(c.prevs), len(c.prevIndices))
+		}
+		pos, ok := below[c.prevs[0]]
+		if !ok {
+			return fmt.Errorf("index cache does not point to a node on the sparsest level")
+		}
+		if pos != c.prevIndices[0] || pos > c.index {
+			return fmt.Errorf("index cache records position %v for a node at position %v (cached index %v)", c.prevIndices[0], pos, c.index)
+		}
+	}
+
+	return validateExt(l)
+}
+
+func validateExt(l *ISkipList) error {
+	if l.ext == nil {
+		return nil
+	}
+	if l.ext.maxLength > 0 && l.length > l.ext.maxLength {
+		return fmt.Errorf("length %v exceeds maximum length %v", l.length, l.ext.maxLength)
+	}
+	if l.ext.spans != nil {
+		if err := validateSpans(l); err != nil {
+			return err
+		}
+	}
+	if t := l.ext.positions; t != nil {
+		if t.length() != l.length {
+			return fmt.Errorf("position tree has %v nodes but length is %v", t.length(), l.length)
+		}
+		if l.ext.byID != nil && len(l.ext.byID) != l.length {
+			return fmt.Errorf("ID map has %v entries but length is %v", len(l.ext.byID), l.length)
+		}
+		var err error
+		l.IterateI(func(i int, e *ElemType) bool {
+			n := t.at(i)
+			if n.value != *e {
+				err = fmt.Errorf("position tree has value %v at index %v, expected %v", n.value, i, *e)
+				return false
+			}
+			if l.ext.byValue != nil && !containsPosNode(l.ext.byValue[*e], n) {
+				err = fmt.Errorf("value index has no entry for %v at index %v", *e, i)
+				return false
+			}
+			if l.ext.byID != nil && l.ext.byID[n.id] != n {
+				err = fmt.Errorf("ID map has no entry for ID %v at index %v", n.id, i)
+				return false
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func containsPosNode(nodes []*posNode, n *posNode) bool {
+	for _, o := range nodes {
+		if o == n {
+			return true
+		}
+	}
+	return false
+}
+
+// LevelHistogram returns the number of nodes on each level of the ISkipList,
+// starting with the densest level (which has one node per element). It runs
+// in O(n) time.
+func (l *ISkipList) LevelHistogram() []int {
+	var levels []*listNode
+	for n := l.root; n != nil; n = n.nextLevel {
+		levels = append(levels, n)
+	}
+
+	h := make([]int, len(levels))
+	for i, n := range levels {
+		for ; n != nil; n = n.next {
+			h[len(levels)-i-1]++
+		}
+	}
+	return h
+}
+
+// NodeCount returns the total number of nodes on all levels of the
+// ISkipList. This is Length() plus the number of nodes on the sparse levels,
+// which is about 0.58 times Length() on average. It runs in O(n) time.
+func (l *ISkipList) NodeCount() int {
+	count := 0
+	for levelRoot := l.root; levelRoot != nil; levelRoot = levelRoot.nextLevel {
+		for n := levelRoot; n != nil; n = n.next {
+			count++
+		}
+	}
+	return count
+}
+
+// MemoryFootprint returns an estimate of the number of bytes of memory used by
+// the ISkipList: the ISkipList itself, its nodes on all levels, and its index
+// cache. It doesn't include memory used by optional features (such as the
+// value index) or overhead imposed by the memory allocator. Comparing the
+// result with Length() times the size of an element gives the overhead of
+// using an ISkipList rather than a slice. It runs in O(n) time.
+func (l *ISkipList) MemoryFootprint() int {
+	size := int(unsafe.Sizeof(*l)) + l.NodeCount()*int(unsafe.Sizeof(listNode{}))
+	if l.cache != nil {
+		size += int(unsafe.Sizeof(*l.cache))
+		size += cap(l.cache.prevs) * int(unsafe.Sizeof((*listNode)(nil)))
+		size += cap(l.cache.prevIndices) * int(unsafe.Sizeof(0))
+	}
+	return size
+}
+
+// LevelStats gives the actual and expected number of nodes on one level of an
+// ISkipList. See DepthReport().
+type LevelStats struct {
+	Nodes    int
+	Expected float64
+	// True iff Nodes deviates from Expected by considerably more than could
+	// reasonably be expected by chance.
+	Suspicious bool
+}
+
+// DepthReport compares the level structure of an ISkipList with the
+// theoretical expectation. It is returned by ISkipList.DepthReport().
+type DepthReport struct {
+	Length int
+	// Statistics for each level, starting with the densest level. There may
+	// be trailing levels with an expected node count well below 1.
+	Levels []LevelStats
+	// True iff any level is suspicious.
+	Suspicious bool
+}
+
+// DepthReport compares the number of nodes on each level of the ISkipList
+// with the number expected given that each node is promoted to the next
+// sparser level with probability 1/e. Levels with a node count more than four
+// standard deviations (plus one node, to allow for noise in very sparse
+// levels) from the expected count are flagged as suspicious. A suspicious
+// report is not necessarily evidence of a bug, but it may indicate a poor
+// choice of seed or a structure that has drifted following a long sequence of
+// insertions and removals at the same position. It runs in O(n) time.
+func (l *ISkipList) DepthReport() DepthReport {
+	h := l.LevelHistogram()
+	r := DepthReport{
+		Length: l.length,
+		Levels: make([]LevelStats, len(h)),
+	}
+
+	// The first node is present on every level, so only the remaining n-1
+	// nodes are subject to chance.
+	others := float64(l.length - 1)
+	for i, nodes := range h {
+		p := math.Pow(1/math.E, float64(i))
+		expected := 1 + others*p
+		sd := math.Sqrt(others * p * (1 - p))
+		suspicious := math.Abs(float64(nodes)-expected) > 4*sd+1
+		r.Levels[i] = LevelStats{
+			Nodes:      nodes,
+			Expected:   expected,
+			Suspicious: suspicious,
+		}
+		r.Suspicious = r.Suspicious || suspicious
+	}
+
+	return r
+}
+
+// String formats a DepthReport as a table with one row per level.
+func (r DepthReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Length %v, %v levels\n", r.Length, len(r.Levels))
+	for i, lev := range r.Levels {
+		flag := ""
+		if lev.Suspicious {
+			flag = " (suspicious)"
+		}
+		fmt.Fprintf(&sb, "level %2v: %10v nodes, expected %12.1f%s\n", i, lev.Nodes, lev.Expected, flag)
+	}
+	return sb.String()
+}
+
+// Stats gives structural statistics for an ISkipList. It is returned by
+// ISkipList.Stats().
+type Stats struct {
+	// The actual and expected distribution of nodes over levels.
+	Depth DepthReport
+	// The average distance between adjacent nodes on each level, starting
+	// with the densest level (for which it is always 1). The average span is
+	// zero for a level with a single node. Ideally, the average span on level
+	// i is close to e^i.
+	AverageSpan []float64
+	// The index of the element whose position is cached (see the package
+	// documentation), or -1 if the cache is empty or has been invalidated.
+	CacheIndex int
+}
+
+// Stats returns structural statistics for the ISkipList, which can be used to
+// diagnose pathological level assignments following unusual workloads. For
+// example, heavy use of Truncate() can leave sparse levels with few nodes,
+// which shows up as an average span much greater than expected. It runs in
+// O(n) time.
+func (l *ISkipList) Stats() Stats {
+	s := Stats{
+		Depth:       l.DepthReport(),
+		AverageSpan: make([]float64, l.nLevels+1),
+		CacheIndex:  -1,
+	}
+	level := int(l.nLevels)
+	for levelRoot := l.root; levelRoot != nil; levelRoot = levelRoot.nextLevel {
+		span, nodes := 0, 0
+		for n := levelRoot; n.next != nil; n = n.next {
+			if level == 0 {
+				span++
+			} else {
+				span += elemToDist(n.elem)
+			}
+			nodes++
+		}
+		if nodes > 0 {
+			s.AverageSpan[level] = float64(span) / float64(nodes)
+		}
+		level--
+	}
+	if l.root == nil {
+		s.AverageSpan = nil
+	}
+	if l.cache != nil && l.cache.isValid() {
+		s.CacheIndex = l.cache.index
+	}
+	return s
+}
+
+// String formats Stats as a table with one row per level, followed by the
+// state of the cache.
+func (s Stats) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Length %v, %v levels\n", s.Depth.Length, len(s.Depth.Levels))
+	for i, lev := range s.Depth.Levels {
+		flag := ""
+		if lev.Suspicious {
+			flag = " (suspicious)"
+		}
+		fmt.Fprintf(&sb, "level %2v: %10v nodes, expected %12.1f, average span %10.1f%s\n", i, lev.Nodes, lev.Expected, s.AverageSpan[i], flag)
+	}
+	if s.CacheIndex >= 0 {
+		fmt.Fprintf(&sb, "cache: index %v\n", s.CacheIndex)
+	} else {
+		sb.WriteString("cache: empty\n")
+	}
+	return sb.String()
+}
+
+// WriteDot writes a Graphviz DOT graph of the structure of the ISkipList to w.
+// Each level is drawn as a row of nodes linked by their next pointers, with
+// the edges on sparse levels labeled with the distances that they span, and
+// each node is linked to the node below it by a dashed edge. Nodes are labeled
+// with their index (and nodes on the densest level with their element), and
+// the nodes for each index are aligned in a column. Unlike
+// DebugPrintISkipList(), the output remains legible for ISkipLists with
+// hundreds of elements when rendered (e.g. with 'dot -Tsvg'). WriteDot runs in
+// O(n log n) time. Writes to w are buffered.
+func (l *ISkipList) WriteDot(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("digraph ISkipList {\n")
+	bw.WriteString("\trankdir=LR;\n")
+	bw.WriteString("\tnode [shape=box, fontname=monospace];\n")
+	fmt.Fprintf(bw, "\tlabel=\"ISkipList of length %v with %v levels\";\n", l.length, l.nLevels+1)
+
+	// The names of the nodes at each index, from the sparsest level down.
+	var columns [][]string
+
+	level := int(l.nLevels)
+	for levelRoot := l.root; levelRoot != nil; levelRoot = levelRoot.nextLevel {
+		i := 0
+		for n := levelRoot; n != nil; n = n.next {
+			name := fmt.Sprintf("n%v_%v", level, i)
+			if level == 0 {
+				fmt.Fprintf(bw, "\t%v [label=\"%v: %v\"];\n", name, i, n.elem)
+			} else {
+				fmt.Fprintf(bw, "\t%v [label=\"%v\"];\n", name, i)
+			}
+			for len(columns) <= i {
+				columns = append(columns, nil)
+			}
+			columns[i] = append(columns[i], name)
+
+			if n.nextLevel != nil {
+				fmt.Fprintf(bw, "\t%v -> n%v_%v [style=dashed, arrowhead=none];\n", name, level-1, i)
+			}
+			if n.next == nil {
+				break
+			}
+			d := 1
+			if level > 0 {
+				d = elemToDist(n.elem)
+				fmt.Fprintf(bw, "\t%v -> n%v_%v [label=\"%v\"];\n", name, level, i+d, d)
+			} else {
+				fmt.Fprintf(bw, "\t%v -> n%v_%v;\n", name, level, i+d)
+			}
+			i += d
+		}
+		level--
+	}
+
+	for _, col := range columns {
+		if len(col) > 1 {
+			fmt.Fprintf(bw, "\t{ rank=same; %v; }\n", strings.Join(col, "; "))
+		}
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// NodeDump describes one node of an ISkipList. See DebugDump().
+type NodeDump struct {
+	// An ID for the node that is unique within the dump. IDs are assigned
+	// sequentially in the order in which nodes appear in the dump, so dumps of
+	// ISkipLists with the same structure are identical.
+	ID int `json:"id"`
+	// The index of the element that the node corresponds to.
+	Index int `json:"index"`
+	// The element at Index.
+	Elem ElemType `json:"elem"`
+	// The distance to the next node on the same level, or 0 if this is the
+	// last node on its level.
+	Span int `json:"span"`
+	// The ID of the next node on the same level, or -1 if there is none.
+	Next int `json:"next"`
+	// The ID of the node at the same index on the level below, or -1 if this
+	// node is on the densest level.
+	Down int `json:"down"`
+}
+
+// LevelDump describes one level of an ISkipList. See DebugDump().
+type LevelDump struct {
+	// The level number, where 0 is the densest level.
+	Level int        `json:"level"`
+	Nodes []NodeDump `json:"nodes"`
+}
+
+// DebugDump returns a machine-readable description of the structure of the
+// ISkipList, with one LevelDump per level, starting with the densest level.
+// Nodes are identified by stable IDs rather than pointers, so the result can
+// be marshaled with encoding/json and attached to the output of a failing
+// test for offline analysis. DebugDump runs in O(n) time.
+func (l *ISkipList) DebugDump() []LevelDump {
+	if l.root == nil {
+		return []LevelDump{}
+	}
+
+	elems := make([]ElemType, l.length)
+	l.CopyToSlice(elems)
+
+	// Collect the indices of the nodes on each level, starting with the
+	// densest.
+	var roots []*listNode
+	for n := l.root; n != nil; n = n.nextLevel {
+		roots = append(roots, n)
+	}
+	levels := make([]LevelDump, len(roots))
+	id := 0
+	for h := range levels {
+		levels[h].Level = h
+		i := 0
+		for n := roots[len(roots)-1-h]; n != nil; n = n.next {
+			nd := NodeDump{ID: id, Index: i, Elem: elems[i], Next: -1, Down: -1}
+			if n.next != nil {
+				nd.Span = 1
+				if h > 0 {
+					nd.Span = elemToDist(n.elem)
+				}
+				nd.Next = id + 1
+			}
+			levels[h].Nodes = append(levels[h].Nodes, nd)
+			i += nd.Span
+			id++
+		}
+	}
+
+	// Every node on a sparse level has a node at the same index below it, so
+	// the IDs of the nodes below can be found by merging.
+	for h := 1; h < len(levels); h++ {
+		below := levels[h-1].Nodes
+		j := 0
+		for k := range levels[h].Nodes {
+			nd := &levels[h].Nodes[k]
+			for below[j].Index < nd.Index {
+				j++
+			}
+			nd.Down = below[j].ID
+		}
+	}
+
+	return levels
+}
@@ -0,0 +1,69 @@
+package iskiplist
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Internal consistency checks are enabled by building with the 'iskiplistdebug'
+// build tag:
+//
+//	go test -tags iskiplistdebug ./...
+//
+// The checks are expensive (some of them validate the entire ISkipList after
+// each modification), so they should not be enabled in production builds.
+// Calls to the functions below should be guarded by 'if debugAssertions', so
+// that the compiler can remove them (and the computation of their arguments)
+// entirely from normal builds.
+
+// DebugAssertionsEnabled returns true iff the package was built with the
+// 'iskiplistdebug' build tag, which enables expensive internal consistency
+// checks.
+func DebugAssertionsEnabled() bool {
+	return debugAssertions
+}
+
+func assertf(cond bool, format string, args ...interface{}) {
+	if !cond {
+		panic("Internal error (iskiplistdebug): " + fmt.Sprintf(format, args...))
+	}
+}
+
+// Validating an ISkipList takes O(n log n) time, so validating after every
+// modification would make tests that build long ISkipLists unbearably slow.
+// Short ISkipLists are always validated; longer ones are validated after a
+// fraction of modifications such that the amortized cost per modification is
+// roughly that of validating an ISkipList of length validateEveryTimeMaxLength.
+const validateEveryTimeMaxLength = 512
+
+var validateCounter uint64
+
+func assertValid(l *ISkipList) {
+	if l.length > validateEveryTimeMaxLength {
+		c := atomic.AddUint64(&validateCounter, 1)
+		if c%uint64(l.length/validateEveryTimeMaxLength) != 0 {
+			return
+		}
+	}
+	if err := l.Validate(); err != nil {
+		panic(fmt.Sprintf("Internal error (iskiplistdebug): invalid ISkipList: %v", err))
+	}
+}
+
+// In debug builds, each ISkipList counts the modifications made to its
+// structure (i.e. insertions and removals, but not changes to the values of
+// elements), and the iteration methods panic if the count changes during
+// iteration. In normal builds, the counter has zero size and the checks are
+// removed by the compiler.
+
+func noteModified(l *ISkipList) {
+	if debugAssertions {
+		l.mods.bump()
+	}
+}
+
+func checkNotModified(l *ISkipList, mods uint64) {
+	if debugAssertions && l.mods.get() != mods {
+		panic(fmt.Sprintf("ISkipList %p modified during iteration (concurrent modification)", l))
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !iskiplistdebug
+// +build !iskiplistdebug
+
+package iskiplist
+
+const debugAssertions = false
+
+// modCounter is a zero-size placeholder in normal builds.
+type modCounter struct{}
+
+func (m *modCounter) bump() {}
+
+func (m *modCounter) get() uint64 {
+	return 0
+}
@@ -0,0 +1,20 @@
+//go:build iskiplistdebug
+// +build iskiplistdebug
+
+package iskiplist
+
+const debugAssertions = true
+
+// modCounter counts the structural modifications made to an ISkipList, so that
+// modifications made during iteration can be detected.
+type modCounter struct {
+	n uint64
+}
+
+func (m *modCounter) bump() {
+	m.n++
+}
+
+func (m *modCounter) get() uint64 {
+	return m.n
+}
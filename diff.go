@@ -0,0 +1,208 @@
+package iskiplist
+
+// This file contains operations that compute the differences between two
+// ISkipLists.
+
+// lcsMatches returns the pairs of indices (i, j) such that x[i] and y[j] are
+// paired in a longest common subsequence of x and y, in ascending order. It
+// uses Myers' O((n+m)D) algorithm, where D is the size of the minimum edit
+// script between x and y, so it's fast when x and y are similar. The V array
+// is saved at each step so that the path can be traced back, which takes
+// O((n+m)D) space.
+func lcsMatches(x, y []ElemType) [][2]int {
+	n, m := len(x), len(y)
+	max := n + m
+	off := max + 1
+	v := make([]int, 2*max+3)
+
+	var trace [][]int
+	xi, yi := 0, 0
+search:
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				xi = v[off+k+1]
+			} else {
+				xi = v[off+k-1] + 1
+			}
+			yi = xi - k
+			for xi < n && yi < m && x[xi] == y[yi] {
+				xi++
+				yi++
+			}
+			v[off+k] = xi
+			if xi >= n && yi >= m {
+				break search
+			}
+		}
+	}
+
+	var matches [][2]int
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d]
+		k := xi - yi
+		var prevK int
+		if k == -d || (k != d && prev[off+k-1] < prev[off+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := prev[off+prevK]
+		prevY := prevX - prevK
+		for xi > prevX && yi > prevY {
+			xi--
+			yi--
+			matches = append(matches, [2]int{xi, yi})
+		}
+		xi, yi = prevX, prevY
+	}
+	for xi > 0 && yi > 0 {
+		xi--
+		yi--
+		matches = append(matches, [2]int{xi, yi})
+	}
+
+	for i, j := 0, len(matches)-1; i < j; i, j = i+1, j-1 {
+		matches[i], matches[j] = matches[j], matches[i]
+	}
+	return matches
+}
+
+// LCS returns a new ISkipList containing a longest common subsequence of the
+// elements of a and b (i.e. a longest sequence of elements that occur in the
+// same order, though not necessarily contiguously, in both). If there is more
+// than one longest common subsequence, which one is returned is unspecified.
+// The common prefix and suffix of a and b are found by walking both
+// ISkipLists in step. The remaining elements are compared using Myers'
+// algorithm, which takes O((n+m)D) time and space, where D is the number of
+// elements that have to be inserted or removed to turn a into b. LCS is
+// therefore fast for similar sequences, but may be slow and memory hungry for
+// long, dissimilar sequences.
+func LCS(a, b *ISkipList) *ISkipList {
+	var bld chainBuilder
+
+	prefix := 0
+	an, bn := densestAt(a, 0), densestAt(b, 0)
+	for an != nil && bn != nil && an.elem == bn.elem {
+		bld.add(an.elem)
+		prefix++
+		an, bn = an.next, bn.next
+	}
+
+	x := make([]ElemType, a.length-prefix)
+	y := make([]ElemType, b.length-prefix)
+	a.CopyRangeToSlice(prefix, a.length, x)
+	b.CopyRangeToSlice(prefix, b.length, y)
+
+	suffix := 0
+	for suffix < len(x) && suffix < len(y) && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	for _, p := range lcsMatches(x[:len(x)-suffix], y[:len(y)-suffix]) {
+		bld.add(x[p[0]])
+	}
+	for _, e := range x[len(x)-suffix:] {
+		bld.add(e)
+	}
+
+	return bld.build(a)
+}
+
+// EditDistance returns the Levenshtein distance between a and b: the minimum
+// number of single element insertions, removals and substitutions needed to
+// turn a into b. If maxDist is non-negative and the distance is greater than
+// maxDist, then EditDistance returns maxDist+1. Only the band of the dynamic
+// programming table within maxDist of the diagonal is computed, and the
+// computation stops as soon as every entry of a row exceeds maxDist, so
+// EditDistance runs in O(n * maxDist) time (or O(nm) time if maxDist is
+// negative). It walks both ISkipLists rather than copying them to slices.
+func EditDistance(a, b *ISkipList, maxDist int) int {
+	an, bn := densestAt(a, 0), densestAt(b, 0)
+	n, m := a.length, b.length
+	for an != nil && bn != nil && an.elem == bn.elem {
+		an, bn = an.next, bn.next
+		n--
+		m--
+	}
+
+	k := maxDist
+	if k < 0 {
+		k = n
+		if m > k {
+			k = m
+		}
+	}
+	if n-m > k || m-n > k {
+		return k + 1
+	}
+	if n == 0 || m == 0 {
+		return n + m
+	}
+
+	inf := k + 1
+	prev := make([]int, m+1)
+	cur := make([]int, m+1)
+	for j := range prev {
+		if j <= k {
+			prev[j] = j
+		} else {
+			prev[j] = inf
+		}
+	}
+
+	// rowStart is the node of b corresponding to column jlo.
+	rowStart := bn
+	jlo := 1
+	for i := 1; i <= n; i, an = i+1, an.next {
+		if i-k > jlo {
+			jlo = i - k
+			rowStart = rowStart.next
+		}
+		jhi := i + k
+		if jhi > m {
+			jhi = m
+		}
+
+		if jlo == 1 {
+			cur[0] = i
+			if i > k {
+				cur[0] = inf
+			}
+		} else {
+			cur[jlo-1] = inf
+		}
+		if jhi < m {
+			cur[jhi+1] = inf
+		}
+
+		rowMin := cur[jlo-1]
+		node := rowStart
+		for j := jlo; j <= jhi; j, node = j+1, node.next {
+			d := prev[j-1]
+			if an.elem != node.elem {
+				d++
+			}
+			if v := prev[j] + 1; v < d {
+				d = v
+			}
+			if v := cur[j-1] + 1; v < d {
+				d = v
+			}
+			if d > inf {
+				d = inf
+			}
+			cur[j] = d
+			if d < rowMin {
+				rowMin = d
+			}
+		}
+		if rowMin > k {
+			return k + 1
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[m]
+}
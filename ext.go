@@ -0,0 +1,174 @@
+package iskiplist
+
+// extensions holds the state of optional features, most of which have to be
+// kept in sync with the contents of an ISkipList. It is nil for ISkipLists that
+// don't use any of these features, so that the common case pays only for a nil
+// check on each mutation.
+type extensions struct {
+	positions *posTree
+	byValue   map[ElemType][]*posNode
+	byID      map[ElemID]*posNode
+	nextID    ElemID
+	handles   bool
+	maxLength int
+	capacity  int
+	// If non-nil, this is called with each element evicted to enforce the
+	// maximum length (see Store).
+	onEvict func(ElemType)
+	summary *summaryStats
+	spans   *spanConfig
+	// If non-nil, this is used instead of the ISkipList's built-in PCG32
+	// generator.
+	levelSource LevelSource
+
+	reseedInterval int
+	untilReseed    int
+
+	tracer    Tracer
+	telemetry *Telemetry
+	metrics   MetricsSink
+}
+
+func getExt(l *ISkipList) *extensions {
+	if l.ext == nil {
+		l.ext = &extensions{}
+	}
+	return l.ext
+}
+
+// The note* functions are called by every operation that adds, removes or
+// replaces elements. They are called after the ISkipList itself has been
+// updated.
+
+func noteInsert(l *ISkipList, index int, elem ElemType) {
+	if l.ext == nil {
+		return
+	}
+	if l.ext.positions != nil {
+		n := l.ext.positions.insert(index, elem)
+		if l.ext.byValue != nil {
+			addToValueIndex(l.ext.byValue, elem, n)
+		}
+		if l.ext.byID != nil {
+			assignID(l.ext, n)
+		}
+	}
+	if l.ext.summary != nil {
+		l.ext.summary.add(elem, 1)
+	}
+	if l.ext.metrics != nil {
+		l.ext.metrics.OnInsert(1)
+	}
+}
+
+func noteRemove(l *ISkipList, index int, elem ElemType) {
+	if l.ext == nil {
+		return
+	}
+	if l.ext.positions != nil {
+		n := l.ext.positions.remove(index)
+		if l.ext.byValue != nil {
+			removeFromValueIndex(l.ext.byValue, elem, n)
+		}
+		if l.ext.byID != nil {
+			delete(l.ext.byID, n.id)
+		}
+	}
+	if l.ext.summary != nil {
+		l.ext.summary.add(elem, -1)
+	}
+	if l.ext.metrics != nil {
+		l.ext.metrics.OnRemove(1)
+	}
+}
+
+func noteSet(l *ISkipList, index int, old, elem ElemType) {
+	if l.ext == nil {
+		return
+	}
+	if l.ext.spans != nil {
+		invalidateSpans(l, index, index)
+	}
+	if l.ext.positions != nil {
+		n := l.ext.positions.at(index)
+		n.value = elem
+		if l.ext.byValue != nil {
+			removeFromValueIndex(l.ext.byValue, old, n)
+			addToValueIndex(l.ext.byValue, elem, n)
+		}
+	}
+	if l.ext.summary != nil {
+		l.ext.summary.add(old, -1)
+		l.ext.summary.add(elem, 1)
+	}
+}
+
+// noteSwap is called after the elements at two indices of the same ISkipList
+// have been swapped. The position tree nodes are swapped along with the
+// elements, so that the value index, element IDs and Handles all follow the
+// swapped elements. Swapping doesn't add or remove elements, so apart from
+// span aggregates nothing else needs updating.
+func noteSwap(l *ISkipList, index1, index2 int) {
+	if l.ext == nil {
+		return
+	}
+	if l.ext.spans != nil {
+		invalidateSpans(l, index1, index1)
+		invalidateSpans(l, index2, index2)
+	}
+	if l.ext.positions != nil {
+		l.ext.positions.swap(index1, index2)
+	}
+}
+
+// noteRotate is called after the elements in [from, to) have been rotated to
+// the left by k positions, where 0 < k < to-from. The rotation doesn't add or
+// remove elements, so only the positions of elements need updating.
+func noteRotate(l *ISkipList, from, to, k int) {
+	if l.ext == nil || l.ext.positions == nil {
+		return
+	}
+	l.ext.positions.rotate(from, to, k)
+}
+
+// noteTruncate is called before the ISkipList is truncated to n elements.
+func noteTruncate(l *ISkipList, n int) {
+	if l.ext == nil || n >= l.length {
+		return
+	}
+	removed := make([]ElemType, l.length-n)
+	l.CopyRangeToSlice(n, l.length, removed)
+	for i := len(removed) - 1; i >= 0; i-- {
+		noteRemove(l, n+i, removed[i])
+	}
+}
+
+// noteClear is called after all n elements of the ISkipList have been removed.
+func noteClear(l *ISkipList, n int) {
+	if l.ext == nil {
+		return
+	}
+	if l.ext.positions != nil {
+		l.ext.positions = newPosTree()
+		if l.ext.byValue != nil {
+			l.ext.byValue = make(map[ElemType][]*posNode)
+		}
+		if l.ext.byID != nil {
+			l.ext.byID = make(map[ElemID]*posNode)
+		}
+	}
+	if l.ext.summary != nil {
+		l.ext.summary.reset()
+	}
+	if l.ext.metrics != nil && n > 0 {
+		l.ext.metrics.OnRemove(n)
+	}
+}
+
+// releasePositions discards the position tree if no enabled feature requires
+// it.
+func releasePositions(ext *extensions) {
+	if ext.byValue == nil && ext.byID == nil && !ext.handles {
+		ext.positions = nil
+	}
+}
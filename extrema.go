@@ -0,0 +1,205 @@
+package iskiplist
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// MinMax returns the minimum and maximum elements of the ISkipList. The third
+// return value is false iff the ISkipList is empty. Elements are compared in
+// pairs, so MinMax makes a single pass over the ISkipList and performs about
+// 3n/2 comparisons rather than 2n.
+func (l *ISkipList) MinMax() (min, max ElemType, ok bool) {
+	node := densestAt(l, 0)
+	if node == nil {
+		return
+	}
+
+	min, max = node.elem, node.elem
+	node = node.next
+	for node != nil && node.next != nil {
+		a, b := node.elem, node.next.elem
+		if b < a {
+			a, b = b, a
+		}
+		if a < min {
+			min = a
+		}
+		if b > max {
+			max = b
+		}
+		node = node.next.next
+	}
+	if node != nil {
+		if node.elem < min {
+			min = node.elem
+		}
+		if node.elem > max {
+			max = node.elem
+		}
+	}
+	return min, max, true
+}
+
+// MinIndex returns the index of the first minimum element of the ISkipList
+// according to 'less', or -1 if the ISkipList is empty. If 'less' is nil, the
+// usual ordering of integers is used. MinIndex makes a single pass over the
+// densest level, so it runs in O(n) time.
+func (l *ISkipList) MinIndex(less func(a, b ElemType) bool) int {
+	if less == nil {
+		less = func(a, b ElemType) bool { return a < b }
+	}
+	return extremeIndex(l, less)
+}
+
+// MaxIndex returns the index of the first maximum element of the ISkipList
+// according to 'less', or -1 if the ISkipList is empty. If 'less' is nil, the
+// usual ordering of integers is used. See MinIndex().
+func (l *ISkipList) MaxIndex(less func(a, b ElemType) bool) int {
+	if less == nil {
+		return extremeIndex(l, func(a, b ElemType) bool { return a > b })
+	}
+	return extremeIndex(l, func(a, b ElemType) bool { return less(b, a) })
+}
+
+// Min returns the first minimum element of the ISkipList according to 'less'
+// (or the usual ordering of integers if 'less' is nil). The second return value
+// is false iff the ISkipList is empty. See MinIndex().
+func (l *ISkipList) Min(less func(a, b ElemType) bool) (ElemType, bool) {
+	i := l.MinIndex(less)
+	if i == -1 {
+		return 0, false
+	}
+	return getTo(l.root, i, searchObserver(l)).elem, true
+}
+
+// Max returns the first maximum element of the ISkipList according to 'less'
+// (or the usual ordering of integers if 'less' is nil). The second return value
+// is false iff the ISkipList is empty. See MaxIndex().
+func (l *ISkipList) Max(less func(a, b ElemType) bool) (ElemType, bool) {
+	i := l.MaxIndex(less)
+	if i == -1 {
+		return 0, false
+	}
+	return getTo(l.root, i, searchObserver(l)).elem, true
+}
+
+// extremeIndex returns the index of the first element e such that there is no
+// element f for which before(f, e), or -1 if the ISkipList is empty.
+func extremeIndex(l *ISkipList, before func(a, b ElemType) bool) int {
+	node := densestAt(l, 0)
+	if node == nil {
+		return -1
+	}
+
+	best, bestIndex := node.elem, 0
+	i := 1
+	for node = node.next; node != nil; node = node.next {
+		if before(node.elem, best) {
+			best, bestIndex = node.elem, i
+		}
+		i++
+	}
+	return bestIndex
+}
+
+// Quantiles returns the elements of the ISkipList at each of the specified
+// quantiles, using the nearest-rank method: the q quantile is the element at
+// index ceil(q*n)-1 (or 0 if q is 0) of the sorted elements, so q = 0 gives
+// the minimum, q = 0.5 gives the (lower) median, and q = 1 gives the maximum.
+// Each q must be in the range [0, 1]. Elements are compared using 'less', or
+// using '<' if 'less' is nil. Quantiles returns nil if the ISkipList is empty.
+//
+// The ISkipList is copied to a scratch buffer once, and all the requested
+// quantiles are then found by a single multi-way quickselect over the buffer,
+// so Quantiles takes O(n log k) expected time, where k is the number of
+// quantiles requested, rather than the O(n log n) time required to sort.
+func (l *ISkipList) Quantiles(qs []float64, less func(a, b ElemType) bool) []ElemType {
+	for _, q := range qs {
+		if !(q >= 0 && q <= 1) {
+			panic(fmt.Sprintf("Quantile %v passed to Quantiles is not in the range [0, 1]", q))
+		}
+	}
+	if l.length == 0 {
+		return nil
+	}
+	if less == nil {
+		less = func(a, b ElemType) bool { return a < b }
+	}
+
+	ranks := make([]int, len(qs))
+	for i, q := range qs {
+		r := int(math.Ceil(q*float64(l.length))) - 1
+		if r < 0 {
+			r = 0
+		}
+		ranks[i] = r
+	}
+	sorted := make([]int, len(ranks))
+	copy(sorted, ranks)
+	sort.Ints(sorted)
+
+	buf := make([]ElemType, l.length)
+	l.CopyToSlice(buf)
+	multiSelect(buf, 0, len(buf), sorted, less)
+
+	result := make([]ElemType, len(qs))
+	for i, r := range ranks {
+		result[i] = buf[r]
+	}
+	return result
+}
+
+// multiSelect partially sorts a[lo:hi] so that a[k] is the element that would
+// be at index k if a were sorted, for each k in ks. The indices in ks must be
+// in ascending order and in the range [lo, hi).
+func multiSelect(a []ElemType, lo, hi int, ks []int, less func(a, b ElemType) bool) {
+	for len(ks) > 0 {
+		if hi-lo <= 12 {
+			for i := lo + 1; i < hi; i++ {
+				for j := i; j > lo && less(a[j], a[j-1]); j-- {
+					a[j], a[j-1] = a[j-1], a[j]
+				}
+			}
+			return
+		}
+
+		lt, gt := partition3(a, lo, hi, less)
+		i := sort.SearchInts(ks, lt)
+		j := sort.SearchInts(ks, gt)
+		multiSelect(a, lo, lt, ks[:i], less)
+		lo, ks = gt, ks[j:]
+	}
+}
+
+// partition3 partitions a[lo:hi] around a pivot chosen by median of three, so
+// that a[lo:lt] are less than the pivot, a[lt:gt] are equal to it, and
+// a[gt:hi] are greater than it. Partitioning three ways avoids quadratic
+// behavior when there are many duplicates.
+func partition3(a []ElemType, lo, hi int, less func(a, b ElemType) bool) (lt, gt int) {
+	mid := lo + (hi-lo)/2
+	x, y, z := a[lo], a[mid], a[hi-1]
+	pivot := y
+	if less(x, y) != less(x, z) {
+		pivot = x
+	} else if less(z, x) != less(z, y) {
+		pivot = z
+	}
+
+	lt, gt = lo, hi
+	for i := lo; i < gt; {
+		switch {
+		case less(a[i], pivot):
+			a[lt], a[i] = a[i], a[lt]
+			lt++
+			i++
+		case less(pivot, a[i]):
+			gt--
+			a[gt], a[i] = a[i], a[gt]
+		default:
+			i++
+		}
+	}
+	return lt, gt
+}
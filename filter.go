@@ -0,0 +1,161 @@
+package iskiplist
+
+// removeIf removes all the elements for which 'pred' returns true and returns
+// the number of elements removed. It makes a single pass over the densest
+// level, unlinking the removed nodes, and then rebuilds the sparse levels if
+// any elements were removed, so it runs in O(n) time.
+func removeIf(l *ISkipList, pred func(ElemType) bool) int {
+	var kept, keptLast *listNode
+	nKept, nRemoved := 0, 0
+	for node := densest(l); node != nil; node = node.next {
+		if pred(node.elem) {
+			noteRemove(l, nKept, node.elem)
+			nRemoved++
+			continue
+		}
+		if keptLast == nil {
+			kept = node
+		} else {
+			keptLast.next = node
+		}
+		keptLast = node
+		nKept++
+	}
+
+	if nRemoved == 0 {
+		return 0
+	}
+
+	if keptLast != nil {
+		keptLast.next = nil
+	}
+	rebuild(l, kept, nKept, l)
+	return nRemoved
+}
+
+// RemoveIf removes all the elements of the ISkipList for which 'pred' returns
+// true and returns the number of elements removed. The ISkipList is filtered
+// in a single pass over the densest level, after which its sparse levels are
+// rebuilt, so RemoveIf runs in O(n) time (rather than the O(n log n) time
+// required to Remove() each matching element). The ISkipList is not modified
+// if no elements are removed. The predicate must not access the ISkipList.
+func (l *ISkipList) RemoveIf(pred func(ElemType) bool) int {
+	if tracing(l) {
+		trace(l, "RemoveIf", pred)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	return removeIf(l, pred)
+}
+
+// RetainIf removes all the elements of the ISkipList for which 'pred' returns
+// false and returns the number of elements removed. See RemoveIf().
+func (l *ISkipList) RetainIf(pred func(ElemType) bool) int {
+	if tracing(l) {
+		trace(l, "RetainIf", pred)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	return removeIf(l, func(e ElemType) bool { return !pred(e) })
+}
+
+// Dedup removes all but the first of each run of adjacent equal elements and
+// returns the number of elements removed. If the ISkipList is sorted, this
+// leaves exactly one copy of each value. See DedupFunc().
+func (l *ISkipList) Dedup() int {
+	if tracing(l) {
+		trace(l, "Dedup")
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	return dedup(l, func(a, b ElemType) bool { return a == b })
+}
+
+// DedupFunc removes all but the first of each run of adjacent elements that
+// are equal according to 'eq' and returns the number of elements removed.
+// Each element is compared with the last element retained, as for C++'s
+// std::unique. Like RemoveIf(), DedupFunc filters the ISkipList in a single
+// pass and then rebuilds its sparse levels, so it runs in O(n) time. The
+// function must not access the ISkipList.
+func (l *ISkipList) DedupFunc(eq func(a, b ElemType) bool) int {
+	if tracing(l) {
+		trace(l, "DedupFunc", eq)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	return dedup(l, eq)
+}
+
+func dedup(l *ISkipList, eq func(a, b ElemType) bool) int {
+	first := true
+	var prev ElemType
+	return removeIf(l, func(e ElemType) bool {
+		if !first && eq(prev, e) {
+			return true
+		}
+		first = false
+		prev = e
+		return false
+	})
+}
+
+// RemoveValues removes every occurrence of each of the specified values from
+// the ISkipList and returns the number of elements removed. The values are
+// put into a set, and the ISkipList is then filtered in a single pass, so
+// RemoveValues runs in O(n + m) time, where m is the number of values. This
+// is much faster than calling Remove() for each occurrence when purging a
+// large number of handles.
+func (l *ISkipList) RemoveValues(values []ElemType) int {
+	if tracing(l) {
+		trace(l, "RemoveValues", values)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	if len(values) == 0 {
+		return 0
+	}
+	set := make(map[ElemType]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return removeIf(l, func(e ElemType) bool {
+		_, ok := set[e]
+		return ok
+	})
+}
+
+// RemoveValuesIn removes every occurrence of each of the elements of 'other'
+// from the ISkipList and returns the number of elements removed. The result
+// is thus the sequence difference of the ISkipList and 'other'. See
+// RemoveValues(). The ISkipList 'other' is not modified (and may be the same
+// ISkipList, in which case all elements are removed).
+func (l *ISkipList) RemoveValuesIn(other *ISkipList) int {
+	if tracing(l) {
+		trace(l, "RemoveValuesIn", other)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	if other.length == 0 {
+		return 0
+	}
+	set := make(map[ElemType]struct{}, other.length)
+	other.ForAll(func(e *ElemType) {
+		set[*e] = struct{}{}
+	})
+	return removeIf(l, func(e ElemType) bool {
+		_, ok := set[e]
+		return ok
+	})
+}
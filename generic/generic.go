@@ -0,0 +1,737 @@
+// Package generic provides a version of ISkipList that is parameterized by its
+// element type. The root iskiplist package fixes the element type as 'int' (the
+// idea being that elements are indices into a slice of the data structure of
+// your choice) and stores the distances between nodes on the sparse levels in
+// the same field as the elements themselves. An ISkipList[T] instead keeps
+// distances in a separate field, so it can hold strings, structs, pointers or
+// any other type directly. The cost is an additional word per node.
+//
+// ISkipList[T] supports the core operations of iskiplist.ISkipList, with the
+// same complexity guarantees and the same index cache for efficient sequential
+// access. The optional features of iskiplist.ISkipList (the value index,
+// summary statistics, tracing and so on) are not supported.
+package generic
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/addrummond/iskiplist/v2/pcg"
+)
+
+// This is approximately (1/e)*UINT32_MAX. According to the following article,
+// 1/e is the optimal value for a general purpose skip list.
+// https://www.sciencedirect.com/science/article/pii/030439759400296U
+const pWithUint32Denom = 1580030168
+
+// As in the iskiplist package, this guards against the possibility of the
+// pseudorandom number generator going haywire.
+const maxLevels = 30
+
+// In the interests of keeping small ISkipLists small, don't cache small
+// indices.
+const minIndexToCache = 8
+
+// autoSeedCounter is incremented each time an ISkipList is automatically
+// seeded, so that ISkipLists seeded in quick succession get different seeds.
+var autoSeedCounter uint64
+
+type listNode[T any] struct {
+	elem      T   // only meaningful on the densest level
+	dist      int // distance to next; only meaningful on sparser levels
+	next      *listNode[T]
+	nextLevel *listNode[T] // level lists start with the sparsest level first
+}
+
+type indexCache[T any] struct {
+	index       int
+	prevs       []*listNode[T]
+	prevIndices []int
+}
+
+func (c *indexCache[T]) invalidate() {
+	c.index = -1
+	for i := range c.prevs {
+		c.prevs[i] = nil // just to stop references to deleted nodes hanging around
+	}
+}
+
+func (c *indexCache[T]) isValid() bool {
+	return c.index >= 0
+}
+
+// ISkipList is an indexable skip list with elements of type T. The zero value
+// is an empty ISkipList ready for use.
+type ISkipList[T any] struct {
+	length  int
+	nLevels int32 // number of levels - 1
+	root    *listNode[T]
+	rand    pcg.Pcg32
+	cache   *indexCache[T]
+}
+
+// mix64 is the finalizer from SplitMix64.
+func mix64(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Seed seeds the random number generator used for the ISkipList. If Seed is
+// called, it should be called immediately following creation of the ISkipList.
+// If Seed is not called, the random number generator is automatically seeded
+// using a counter and the current time.
+func (l *ISkipList[T]) Seed(seed1 uint64, seed2 uint64) {
+	seed1 |= 1 // pcg algo requires seed1 (= state) to be odd
+	l.rand.Seed(seed1, seed2)
+}
+
+func random[T any](l *ISkipList[T]) uint32 {
+	// The PCG state has to be odd, so we know that it's uninitialized if the
+	// state is zero.
+	if l.rand.IsUninitialized() {
+		c := atomic.AddUint64(&autoSeedCounter, 1)
+		l.Seed(mix64(c), mix64(uint64(time.Now().UnixNano())))
+	}
+	return l.rand.Random()
+}
+
+// nTosses returns the number of successive 'heads' in a sequence of coin
+// tosses where heads has probability 1/e.
+func nTosses[T any](l *ISkipList[T]) int {
+	n := 0
+	for n < maxLevels-1 && random(l) < pWithUint32Denom {
+		n++
+	}
+	return n
+}
+
+// Length returns the length of an ISkipList. It runs in constant time.
+func (l *ISkipList[T]) Length() int {
+	return l.length
+}
+
+// Clear empties an ISkipList.
+func (l *ISkipList[T]) Clear() {
+	l.length = 0
+	l.nLevels = 0
+	l.root = nil
+	l.cache = nil
+}
+
+func getTo[T any](node *listNode[T], index int) *listNode[T] {
+	for node.nextLevel != nil {
+		if index >= node.dist && node.next != nil {
+			index -= node.dist
+			node = node.next
+		} else {
+			node = node.nextLevel
+		}
+	}
+	for index != 0 {
+		index--
+		node = node.next
+	}
+	return node
+}
+
+func getToWithPrevIndices[T any](node *listNode[T], index int, prevs []*listNode[T], prevIndices []int) *listNode[T] {
+	li := 0
+	i := 0
+	for node.nextLevel != nil {
+		prevs[li] = node
+		prevIndices[li] = i
+		if index-i >= node.dist && node.next != nil {
+			i += node.dist
+			node = node.next
+		} else {
+			node = node.nextLevel
+			li++
+		}
+	}
+	for i < index {
+		i++
+		node = node.next
+	}
+	return node
+}
+
+func copyToCache[T any](l *ISkipList[T], index int, prevs []*listNode[T], prevIndices []int) {
+	if l.cache == nil {
+		l.cache = &indexCache[T]{}
+	}
+	l.cache.index = index
+	l.cache.prevs = append(l.cache.prevs[:0], prevs...)
+	l.cache.prevIndices = append(l.cache.prevIndices[:0], prevIndices...)
+}
+
+// getToWithPrevIndicesTryingCache is like getToWithPrevIndices except that
+// the search starts from the cached position if possible.
+func getToWithPrevIndicesTryingCache[T any](l *ISkipList[T], i int, prevs []*listNode[T], prevIndices []int) *listNode[T] {
+	if l.cache != nil && l.cache.isValid() && len(l.cache.prevs) > 0 && l.cache.index <= i {
+		p := l.cache.prevs[0]
+		pi := l.cache.prevIndices[0]
+		node := getToWithPrevIndices(p, i-pi, prevs, prevIndices)
+		for j := range prevIndices {
+			prevIndices[j] += pi
+		}
+		return node
+	}
+	return getToWithPrevIndices(l.root, i, prevs, prevIndices)
+}
+
+func retrieve[T any](l *ISkipList[T], i int) *listNode[T] {
+	if i < minIndexToCache {
+		return getTo(l.root, i)
+	}
+
+	prevs := make([]*listNode[T], l.nLevels)
+	prevIndices := make([]int, l.nLevels)
+	node := getToWithPrevIndicesTryingCache(l, i, prevs, prevIndices)
+	copyToCache(l, i, prevs, prevIndices)
+	return node
+}
+
+func checkIndex[T any](l *ISkipList[T], i int) {
+	if i < 0 || i >= l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList of length %v", i, l.length))
+	}
+}
+
+func checkRange[T any](l *ISkipList[T], from, to int) {
+	if from < 0 || from > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList of length %v", from, l.length))
+	}
+	if to < 0 || to > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList of length %v", to, l.length))
+	}
+}
+
+// Copy copies the ISkipList. It does not rerandomize. Seed() may be called on
+// the result prior to any other operations. The cache of the ISkipList is not
+// copied.
+func (l *ISkipList[T]) Copy() *ISkipList[T] {
+	oldLRoot := l.root
+	var newRoot *listNode[T]
+	var aboveN, oldAboveN *listNode[T]
+	for oldLRoot != nil { // one for each level
+		oldn := oldLRoot
+		var newn, prevNewn, newL *listNode[T]
+
+		for oldn != nil {
+			cp := *oldn
+			newn = &cp
+
+			if newRoot == nil {
+				newRoot = newn
+			}
+			if newL == nil {
+				newL = newn
+			}
+
+			if prevNewn != nil {
+				prevNewn.next = newn
+			}
+			prevNewn = newn
+
+			if oldAboveN != nil && oldAboveN.nextLevel == oldn {
+				aboveN.nextLevel = newn
+				aboveN = aboveN.next
+				oldAboveN = oldAboveN.next
+			}
+
+			oldn = oldn.next
+		}
+
+		aboveN = newL
+		oldAboveN = oldLRoot
+		oldLRoot = oldLRoot.nextLevel
+	}
+
+	return &ISkipList[T]{
+		length:  l.length,
+		nLevels: l.nLevels,
+		root:    newRoot,
+	}
+}
+
+// At retrieves the element at the specified index.
+func (l *ISkipList[T]) At(i int) T {
+	checkIndex(l, i)
+	return retrieve(l, i).elem
+}
+
+// PtrAt retrieves a pointer to the element at the specified index. This pointer
+// remains valid following any subsequent operations on the ISkipList. Keeping
+// a pointer to a deleted element will prevent full garbage collection of the
+// associated skip list nodes.
+func (l *ISkipList[T]) PtrAt(i int) *T {
+	checkIndex(l, i)
+	return &retrieve(l, i).elem
+}
+
+// Set updates the element at the specified index.
+func (l *ISkipList[T]) Set(i int, v T) {
+	checkIndex(l, i)
+	retrieve(l, i).elem = v
+}
+
+// Update applies an update function to the element at the specified index.
+func (l *ISkipList[T]) Update(i int, upd func(T) T) {
+	checkIndex(l, i)
+	node := retrieve(l, i)
+	node.elem = upd(node.elem)
+}
+
+// CopyRangeToSlice copies a range of the ISkipList to a slice. The 'from'
+// argument must be >= 0 and <= the length of the ISkipList. The 'to' argument
+// must be >= 0 and <= the length of the ISkipList. If neither 'from' nor 'to'
+// is out of bounds but to <= from, then this is a no-op.
+func (l *ISkipList[T]) CopyRangeToSlice(from, to int, slice []T) {
+	l.IterateRangeI(from, to, func(i int, e *T) bool {
+		slice[i-from] = *e
+		return true
+	})
+}
+
+// CopyToSlice(slice) is a shorthand for l.CopyRangeToSlice(0, l.Length(), slice)
+func (l *ISkipList[T]) CopyToSlice(slice []T) {
+	l.CopyRangeToSlice(0, l.length, slice)
+}
+
+// IterateRangeI iterates over a range of the ISkipList and passes to the
+// supplied function the index of each visited element and a pointer to it. The
+// iteration is halted if the function returns false. The 'from' argument must
+// be >= 0 and <= the length of the ISkipList. The 'to' argument must be >= 0
+// and <= the length of the ISkipList. If neither 'from' nor 'to' is out of
+// bounds but to <= from, then this is a no-op.
+func (l *ISkipList[T]) IterateRangeI(from, to int, f func(int, *T) bool) {
+	checkRange(l, from, to)
+
+	// Returning early for this case saves the cost of finding the 'from' node.
+	if to <= from {
+		return
+	}
+
+	node := retrieve(l, from)
+	for i := from; i < to; i++ {
+		if !f(i, &node.elem) {
+			return
+		}
+		node = node.next
+	}
+}
+
+// IterateRange is like IterateRangeI except that the index of each element is
+// not passed to the supplied function.
+func (l *ISkipList[T]) IterateRange(from, to int, f func(*T) bool) {
+	l.IterateRangeI(from, to, func(_ int, e *T) bool {
+		return f(e)
+	})
+}
+
+// Iterate(f) is a shorthand for l.IterateRange(0, l.Length(), f)
+func (l *ISkipList[T]) Iterate(f func(*T) bool) {
+	l.IterateRange(0, l.length, f)
+}
+
+// IterateI(f) is a shorthand for l.IterateRangeI(0, l.Length(), f)
+func (l *ISkipList[T]) IterateI(f func(int, *T) bool) {
+	l.IterateRangeI(0, l.length, f)
+}
+
+// ForAll is like Iterate except that the iteration always continues to the
+// end of the ISkipList.
+func (l *ISkipList[T]) ForAll(f func(*T)) {
+	l.IterateRange(0, l.length, func(e *T) bool {
+		f(e)
+		return true
+	})
+}
+
+// ForAllI is like IterateI except that the iteration always continues to the
+// end of the ISkipList.
+func (l *ISkipList[T]) ForAllI(f func(int, *T)) {
+	l.IterateRangeI(0, l.length, func(i int, e *T) bool {
+		f(i, e)
+		return true
+	})
+}
+
+// assumes that list is of length >= 2
+func removeFirst[T any](l *ISkipList[T]) T {
+	// Remove any root levels with no subsequent nodes
+	for l.root.next == nil && l.root.nextLevel != nil {
+		l.root = l.root.nextLevel
+		l.nLevels--
+	}
+
+	// Make sure all root levels exist for the next item.
+	var prev, n *listNode[T]
+	for n = l.root; n.nextLevel != nil; n = n.nextLevel {
+		if n.dist > 1 {
+			n.next = &listNode[T]{
+				dist: n.dist - 1,
+				next: n.next,
+			}
+		}
+		if prev != nil {
+			prev.nextLevel = n.next
+		}
+		prev = n.next
+	}
+	if prev != nil {
+		prev.nextLevel = n.next
+	}
+
+	l.root = l.root.next
+
+	return n.elem
+}
+
+func remove[T any](node *listNode[T], index int, prevs []*listNode[T], prevIndices []int) {
+	node.next = node.next.next             // node.next can't be nil because it precedes the element to be removed
+	for i := len(prevs) - 1; i >= 0; i-- { // from densest to sparsest
+		p := prevs[i]
+		pi := prevIndices[i]
+		if p.next != nil {
+			if index == p.dist+pi {
+				p.dist += p.next.dist - 1
+				p.next = p.next.next
+			} else if index < p.dist+pi {
+				p.dist--
+			} else {
+				panic("Internal error in 'remove': unexpected index/distance value")
+			}
+		}
+	}
+}
+
+// Remove removes the element at the specified index. It returns the value of
+// the removed element.
+func (l *ISkipList[T]) Remove(index int) T {
+	checkIndex(l, index)
+
+	if l.cache != nil && l.cache.index >= index {
+		l.cache.invalidate()
+	}
+
+	if l.length == 1 {
+		v := l.root.elem
+		l.Clear()
+		return v
+	}
+
+	if index == 0 {
+		v := removeFirst(l)
+		l.length--
+		return v
+	}
+
+	prevs := make([]*listNode[T], l.nLevels)
+	prevIndices := make([]int, l.nLevels)
+	node := getToWithPrevIndices(l.root, index-1, prevs, prevIndices)
+	e := node.next.elem
+	remove(node, index, prevs, prevIndices)
+	l.length--
+	copyToCache(l, index-1, prevs, prevIndices)
+
+	return e
+}
+
+// Truncate reduces the length of the ISkipList to n, keeping the first n
+// elements. If n is equal to the length of the ISkipList, this is a no-op.
+// If n is zero, this is equivalent to Clear().
+func (l *ISkipList[T]) Truncate(n int) {
+	if n < 0 || n > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList of length %v", n, l.length))
+	}
+	if n == l.length {
+		return
+	}
+	if n == 0 {
+		l.Clear()
+		return
+	}
+
+	if l.cache != nil && l.cache.index >= n {
+		l.cache.invalidate()
+	}
+
+	prevs := make([]*listNode[T], l.nLevels)
+	prevIndices := make([]int, l.nLevels)
+	node := getToWithPrevIndicesTryingCache(l, n-1, prevs, prevIndices)
+	node.next = nil
+	for _, p := range prevs {
+		p.next = nil
+	}
+	l.length = n
+
+	// Remove any root levels with no subsequent nodes.
+	for l.root.next == nil && l.root.nextLevel != nil {
+		l.root = l.root.nextLevel
+		l.nLevels--
+		if l.cache != nil {
+			l.cache.invalidate()
+		}
+	}
+}
+
+func addNRootLevels[T any](l *ISkipList[T], n int) {
+	for i := 0; i < n; i++ {
+		clone := *l.root
+		l.root.nextLevel = &clone
+		l.root.next = nil
+		// We don't set l.root.dist, as it's considered meaningless when
+		// 'next' is nil.
+	}
+}
+
+func distance[T any](from *listNode[T], to *listNode[T]) int {
+	d := 0
+	for from != to {
+		if from.nextLevel == nil {
+			d++
+		} else {
+			d += from.dist
+		}
+		if from.next == nil {
+			panic("Internal error: could not find 'to' node")
+		}
+		from = from.next
+	}
+	return d
+}
+
+func addSparserLevel[T any](l *ISkipList[T], prevAtLevel, node *listNode[T], level, index int) *listNode[T] {
+	// Make sure level exists at root
+	if level > int(l.nLevels) {
+		if l.cache != nil {
+			l.cache.invalidate()
+		}
+		addNRootLevels(l, level-int(l.nLevels))
+		l.nLevels = int32(level)
+	}
+
+	clone := &listNode[T]{nextLevel: node}
+	if prevAtLevel == nil {
+		l.root.next = clone
+		l.root.dist = index
+	} else {
+		oldNext := prevAtLevel.next
+		clone.next = oldNext
+		prevAtLevel.next = clone
+
+		d := distance(prevAtLevel.nextLevel, node)
+		if oldNext != nil {
+			clone.dist = prevAtLevel.dist - d + 1
+		}
+		prevAtLevel.dist = d
+	}
+
+	return clone
+}
+
+func insertAtBeginning[T any](l *ISkipList[T], elem T) {
+	// As in the iskiplist package, we in effect pretend that the newly
+	// inserted node was always the root node, and that the old root node has
+	// just been inserted, so that repeated insertions at the beginning don't
+	// give every node the same number of levels.
+
+	if l.cache != nil {
+		l.cache.invalidate()
+	}
+
+	if l.length == 0 {
+		l.root = &listNode[T]{elem: elem}
+		return
+	}
+
+	// The new root node
+	var rt = &listNode[T]{}
+	for i := 0; i < int(l.nLevels); i++ {
+		rt = &listNode[T]{nextLevel: rt}
+	}
+
+	// Figure out how many levels the previous root node should have now.
+	oldrl := nTosses(l)
+
+	r := l.root
+	n := rt
+	for i := 0; i < int(l.nLevels)-oldrl; i++ {
+		n.next = r.next
+		n.dist = r.dist + 1
+		r = r.nextLevel
+		n = n.nextLevel
+	}
+	for n.nextLevel != nil {
+		n.next = r
+		n.dist = 1
+		r = r.nextLevel
+		n = n.nextLevel
+	}
+
+	n.next = r
+	n.elem = elem
+
+	l.root = rt
+
+	if oldrl > int(l.nLevels) {
+		addNRootLevels(l, oldrl-int(l.nLevels))
+		l.nLevels = int32(oldrl)
+	}
+}
+
+// PushFront adds an element to the beginning of the ISkipList. PushFront runs
+// in constant time.
+func (l *ISkipList[T]) PushFront(elem T) {
+	insertAtBeginning(l, elem)
+	l.length++
+}
+
+// PushBack adds an element to the end of the ISkipList. It runs in amortized
+// constant time when used repeatedly, due to caching.
+func (l *ISkipList[T]) PushBack(elem T) {
+	l.Insert(l.length, elem)
+}
+
+// PopFront removes the first element of the ISkipList and returns it. The
+// second return value is true iff the ISkipList was non-empty prior to the
+// pop. PopFront runs in constant time.
+func (l *ISkipList[T]) PopFront() (r T, ok bool) {
+	if l.length == 0 {
+		return
+	}
+	return l.Remove(0), true
+}
+
+// PopBack removes the last element of the ISkipList and returns it. The second
+// return value is true iff the ISkipList was non-empty prior to the pop.
+func (l *ISkipList[T]) PopBack() (r T, ok bool) {
+	if l.length == 0 {
+		return
+	}
+	return l.Remove(l.length - 1), true
+}
+
+// PeekFront returns the first element of the ISkipList without removing it.
+// The second return value is false iff the ISkipList is empty.
+func (l *ISkipList[T]) PeekFront() (r T, ok bool) {
+	if l.length == 0 {
+		return
+	}
+	return getTo(l.root, 0).elem, true
+}
+
+// PeekBack returns the last element of the ISkipList without removing it. The
+// second return value is false iff the ISkipList is empty.
+func (l *ISkipList[T]) PeekBack() (r T, ok bool) {
+	if l.length == 0 {
+		return
+	}
+	return retrieve(l, l.length-1).elem, true
+}
+
+// Insert inserts an element before the element at the specified index, or at
+// the end of the ISkipList if the index is equal to its length.
+func (l *ISkipList[T]) Insert(index int, elem T) {
+	if index < 0 || index > l.length {
+		panic("Index out of range in call to 'Insert'")
+	}
+
+	if l.cache != nil && l.cache.index >= index {
+		l.cache.invalidate()
+	}
+
+	if index == 0 {
+		l.PushFront(elem)
+		return
+	}
+
+	l.length++
+
+	prevs := make([]*listNode[T], l.nLevels)
+	prevIndices := make([]int, l.nLevels)
+	node := getToWithPrevIndicesTryingCache(l, index-1, prevs, prevIndices)
+	if index-1 >= minIndexToCache {
+		copyToCache(l, index-1, prevs, prevIndices)
+	}
+
+	after := &listNode[T]{elem: elem, next: node.next}
+	node.next = after
+
+	n := after
+	prevsI := len(prevs) - 1
+	nlev := nTosses(l)
+	for i := 1; i <= nlev; i++ {
+		var p *listNode[T]
+		if prevsI >= 0 {
+			p = prevs[prevsI]
+			prevsI--
+		}
+		n = addSparserLevel(l, p, n, i, index)
+	}
+
+	for ; prevsI >= 0; prevsI-- {
+		prevs[prevsI].dist++
+	}
+}
+
+// Swap swaps the values of the elements at the specified indices.
+func (l *ISkipList[T]) Swap(index1, index2 int) {
+	checkIndex(l, index1)
+	checkIndex(l, index2)
+	if index1 == index2 {
+		return
+	}
+	if index1 > index2 {
+		index1, index2 = index2, index1
+	}
+	node1 := retrieve(l, index1)
+	node2 := getTo(node1, index2-index1)
+	node1.elem, node2.elem = node2.elem, node1.elem
+}
+
+// Validate checks the internal consistency of the ISkipList and returns an
+// error describing the first problem found, or nil if there are no problems.
+// It runs in O(n log n) time and is intended for use in tests.
+func (l *ISkipList[T]) Validate() error {
+	if l.root == nil {
+		if l.length != 0 || l.nLevels != 0 {
+			return fmt.Errorf("empty ISkipList has length %v and %v levels", l.length, l.nLevels+1)
+		}
+		return nil
+	}
+
+	levels := 0
+	for n := l.root; n != nil; n = n.nextLevel {
+		levels++
+	}
+	if levels != int(l.nLevels)+1 {
+		return fmt.Errorf("root column has %v levels, expected %v", levels, l.nLevels+1)
+	}
+
+	densest := getTo(l.root, 0)
+	positions := make(map[*listNode[T]]int)
+	count := 0
+	for n := densest; n != nil; n = n.next {
+		positions[n] = count
+		count++
+	}
+	if count != l.length {
+		return fmt.Errorf("densest level has %v nodes but length is %v", count, l.length)
+	}
+
+	// Check that the distances on each sparse level agree with the positions
+	// of the nodes beneath.
+	for lev := l.root; lev.nextLevel != nil; lev = lev.nextLevel {
+		for n := lev; n.next != nil; n = n.next {
+			from, to := getTo(n, 0), getTo(n.next, 0)
+			if positions[to]-positions[from] != n.dist {
+				return fmt.Errorf("sparse node records distance %v, actual distance is %v", n.dist, positions[to]-positions[from])
+			}
+		}
+	}
+	return nil
+}
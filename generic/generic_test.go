@@ -0,0 +1,130 @@
+package generic
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/addrummond/iskiplist/v2/sliceutils"
+)
+
+const (
+	randSeed1 = 12345
+	randSeed2 = 67891
+)
+
+func toSlice[T any](l *ISkipList[T]) []T {
+	s := make([]T, l.Length())
+	l.CopyToSlice(s)
+	return s
+}
+
+func TestRandomOpSequences(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		var l ISkipList[int]
+		l.Seed(randSeed1, uint64(randSeed2+i))
+		a := make([]int, 0)
+		for _, o := range sliceutils.GenOps(1000, 0) {
+			sliceutils.ApplyOpToSlice(&o, &a)
+			switch o.Kind {
+			case sliceutils.OpInsert:
+				l.Insert(o.Index1, o.Elem)
+			case sliceutils.OpRemove:
+				l.Remove(o.Index1)
+			case sliceutils.OpSwap:
+				l.Swap(o.Index1, o.Index2)
+			}
+		}
+		if err := l.Validate(); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		if fmt.Sprint(toSlice(&l)) != fmt.Sprint(a) {
+			t.Fatalf("Expected %v, got %v\n", a, toSlice(&l))
+		}
+		for j, v := range a {
+			if l.At(j) != v {
+				t.Fatalf("Expected %v at index %v, got %v\n", v, j, l.At(j))
+			}
+		}
+	}
+}
+
+func TestPushPopAndTruncate(t *testing.T) {
+	var l ISkipList[int]
+	l.Seed(randSeed1, randSeed2)
+	for i := 0; i < 1000; i++ {
+		l.PushBack(i)
+		l.PushFront(-i - 1)
+	}
+	if err := l.Validate(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if l.Length() != 2000 || l.At(0) != -1000 || l.At(1999) != 999 {
+		t.Errorf("Unexpected contents after pushes\n")
+	}
+
+	if v, ok := l.PopFront(); !ok || v != -1000 {
+		t.Errorf("Unexpected result of PopFront (%v, %v)\n", v, ok)
+	}
+	if v, ok := l.PopBack(); !ok || v != 999 {
+		t.Errorf("Unexpected result of PopBack (%v, %v)\n", v, ok)
+	}
+	if v, ok := l.PeekFront(); !ok || v != -999 {
+		t.Errorf("Unexpected result of PeekFront (%v, %v)\n", v, ok)
+	}
+	if v, ok := l.PeekBack(); !ok || v != 998 {
+		t.Errorf("Unexpected result of PeekBack (%v, %v)\n", v, ok)
+	}
+
+	for _, n := range []int{1500, 700, 20, 1} {
+		l.Truncate(n)
+		if err := l.Validate(); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		if l.Length() != n || l.At(n-1) != -999+n-1 {
+			t.Errorf("Unexpected contents after Truncate(%v)\n", n)
+		}
+		l.PushBack(0)
+		l.Remove(n)
+	}
+
+	l.Clear()
+	if _, ok := l.PopBack(); ok {
+		t.Errorf("Expected PopBack of empty ISkipList to return false\n")
+	}
+}
+
+func TestNonIntElements(t *testing.T) {
+	type point struct{ x, y int }
+
+	var ps ISkipList[point]
+	ps.Seed(randSeed1, randSeed2)
+	for i := 0; i < 100; i++ {
+		ps.Insert(i/2, point{i, -i})
+	}
+	ps.Update(0, func(p point) point { return point{p.x * 10, p.y} })
+	ps.PtrAt(1).y = 42
+	if ps.At(0) != (point{10, -1}) || ps.At(1).y != 42 {
+		t.Errorf("Unexpected elements %v %v\n", ps.At(0), ps.At(1))
+	}
+
+	var ss ISkipList[string]
+	for _, s := range []string{"b", "c", "a"} {
+		ss.PushBack(s)
+	}
+	ss.Swap(0, 2)
+	ss.Set(1, "x")
+	cp := ss.Copy()
+	ss.Remove(0)
+	if fmt.Sprint(toSlice(cp)) != "[a x b]" || fmt.Sprint(toSlice(&ss)) != "[x b]" {
+		t.Errorf("Unexpected contents %v %v\n", toSlice(cp), toSlice(&ss))
+	}
+
+	n := 0
+	cp.IterateI(func(i int, s *string) bool {
+		n++
+		return i < 1
+	})
+	if n != 2 {
+		t.Errorf("Expected IterateI to stop after 2 elements, visited %v\n", n)
+	}
+}
@@ -1,5 +1,3 @@
 module github.com/addrummond/iskiplist/v2
 
-go 1.17
-
-require github.com/addrummond/iskiplist v0.0.0-20190621154336-f9e2774e19f9
+go 1.23
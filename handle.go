@@ -0,0 +1,89 @@
+package iskiplist
+
+import "fmt"
+
+// A Handle refers to a particular element of an ISkipList (see HandleAt()).
+// The zero value is a Handle that refers to no element.
+type Handle struct {
+	node *posNode
+}
+
+// EnableHandles enables the use of Handles. A Handle continues to refer to the
+// same element regardless of the insertion, removal or movement of other
+// elements, and the current index of the element can be found in O(log n)
+// time. Operations that move elements (e.g. Swap(), SwapRanges(), Sort() and
+// RotateRange()) move Handles along with their elements, as for element IDs.
+// Replacing an element's value (e.g. via Set() or FillFunc()) does not change
+// the element that a Handle refers to. Handles are thus a more lightweight
+// alternative to element IDs (see EnableIDs()) for callers that don't need an
+// integer identifier. Unlike a pointer obtained from PtrAt(), a Handle keeps
+// track of the position of its element. Every operation that adds or removes
+// elements has an additional O(log n) of work to do. Enabling Handles on a
+// non-empty ISkipList takes O(n log n) time. Copies of an ISkipList do not
+// inherit Handles.
+func (l *ISkipList) EnableHandles() {
+	enablePositions(l)
+	l.ext.handles = true
+}
+
+// DisableHandles disables the Handles enabled by EnableHandles. Handles
+// obtained before Handles were disabled should not be used again.
+func (l *ISkipList) DisableHandles() {
+	if l.ext == nil || !l.ext.handles {
+		return
+	}
+	l.ext.handles = false
+	releasePositions(l.ext)
+}
+
+// HasHandles returns true iff Handles are enabled.
+func (l *ISkipList) HasHandles() bool {
+	return l.ext != nil && l.ext.handles
+}
+
+// HandleAt returns a Handle referring to the element at the specified index.
+// It runs in O(log n) time. It panics if Handles are not enabled.
+func (l *ISkipList) HandleAt(i int) Handle {
+	if !l.HasHandles() {
+		panic("Handles have not been enabled for this ISkipList; call EnableHandles first")
+	}
+	if i < 0 || i >= l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", i, l))
+	}
+	return Handle{l.ext.positions.at(i)}
+}
+
+// IndexOfHandle returns the current index of the element referred to by a
+// Handle, or -1 if the Handle no longer refers to an element of the ISkipList
+// (e.g. because the element has been removed, or the Handle was obtained from
+// a different ISkipList). It runs in O(log n) time. It panics if Handles are
+// not enabled.
+func (l *ISkipList) IndexOfHandle(h Handle) int {
+	if !l.HasHandles() {
+		panic("Handles have not been enabled for this ISkipList; call EnableHandles first")
+	}
+	if h.node == nil {
+		return -1
+	}
+	return l.ext.positions.indexOfIn(h.node)
+}
+
+// RemoveHandle removes the element referred to by a Handle and returns it.
+// The second return value is false (and the ISkipList is not modified) iff the
+// Handle doesn't refer to an element of the ISkipList. It runs in O(log n)
+// time. It panics if Handles are not enabled.
+func (l *ISkipList) RemoveHandle(h Handle) (ElemType, bool) {
+	i := l.IndexOfHandle(h)
+	if i == -1 {
+		return 0, false
+	}
+
+	if tracing(l) {
+		trace(l, "Remove", i)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	return removeIndex(l, i), true
+}
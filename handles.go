@@ -0,0 +1,140 @@
+package iskiplist
+
+// The functions and types in this file support the recommended way of using
+// an ISkipList, in which each element is an int that indexes a backing slice.
+
+// MapToValues returns a slice containing the values in 'backing' indexed by the
+// elements of the ISkipList, in order. In other words, the value at index i of
+// the result is backing[l.At(i)]. It runs in O(n) time.
+func MapToValues[T any](l *ISkipList, backing []T) []T {
+	return MapRangeToValues(l, 0, l.Length(), backing)
+}
+
+// MapRangeToValues is like MapToValues, but resolves only the elements in the
+// range [from, to) of the ISkipList. Bounds are checked as for IterateRange().
+func MapRangeToValues[T any](l *ISkipList, from, to int, backing []T) []T {
+	var vals []T
+	if to > from {
+		vals = make([]T, 0, to-from)
+	}
+	l.ForAllRange(from, to, func(e *ElemType) {
+		vals = append(vals, backing[elemToDist(*e)])
+	})
+	return vals
+}
+
+// Store pairs an ISkipList with the backing slice that its elements index, and
+// manages the allocation of slots in the backing slice. Slots freed by removing
+// elements are reused by subsequent insertions, so the backing slice grows
+// only as large as the maximum number of values stored at any one time. The
+// zero value is an empty Store.
+type Store[T any] struct {
+	l       ISkipList
+	backing []T
+	free    []int
+}
+
+// Seed seeds the random number generator of the underlying ISkipList. If Seed
+// is called, it should be called immediately following creation of the Store.
+func (s *Store[T]) Seed(seed1 uint64, seed2 uint64) {
+	s.l.Seed(seed1, seed2)
+}
+
+// Length returns the number of values in the Store.
+func (s *Store[T]) Length() int {
+	return s.l.Length()
+}
+
+// Clear empties the Store and releases its backing slice.
+func (s *Store[T]) Clear() {
+	s.l.Clear()
+	s.backing = nil
+	s.free = nil
+}
+
+func (s *Store[T]) alloc(v T) ElemType {
+	if n := len(s.free); n > 0 {
+		slot := s.free[n-1]
+		s.free = s.free[:n-1]
+		s.backing[slot] = v
+		return distToElem(slot)
+	}
+	s.backing = append(s.backing, v)
+	return distToElem(len(s.backing) - 1)
+}
+
+func (s *Store[T]) release(e ElemType) T {
+	slot := elemToDist(e)
+	v := s.backing[slot]
+	var zero T
+	s.backing[slot] = zero // don't keep references to removed values alive
+	s.free = append(s.free, slot)
+	return v
+}
+
+// At returns the value at the specified index.
+func (s *Store[T]) At(i int) T {
+	return s.backing[elemToDist(s.l.At(i))]
+}
+
+// Set replaces the value at the specified index.
+func (s *Store[T]) Set(i int, v T) {
+	s.backing[elemToDist(s.l.At(i))] = v
+}
+
+// Insert inserts a value before the value at the specified index, or at the end
+// of the Store if the index is equal to its length.
+func (s *Store[T]) Insert(i int, v T) {
+	if i < 0 || i > s.l.Length() {
+		panic("Index out of range in call to 'Insert'")
+	}
+	checkCapacity(&s.l, 1)
+	s.l.Insert(i, s.alloc(v))
+}
+
+// PushFront adds a value to the beginning of the Store.
+func (s *Store[T]) PushFront(v T) {
+	checkCapacity(&s.l, 1)
+	s.l.PushFront(s.alloc(v))
+}
+
+// PushBack adds a value to the end of the Store.
+func (s *Store[T]) PushBack(v T) {
+	checkCapacity(&s.l, 1)
+	s.l.PushBack(s.alloc(v))
+}
+
+// Remove removes the value at the specified index and returns it. Its slot in
+// the backing slice is freed for reuse.
+func (s *Store[T]) Remove(i int) T {
+	return s.release(s.l.Remove(i))
+}
+
+// Swap swaps the values at the specified indices. The values themselves are
+// not moved within the backing slice.
+func (s *Store[T]) Swap(i, j int) {
+	s.l.Swap(i, j)
+}
+
+// Iterate calls f with the index and value of each element of the Store in
+// turn, halting if f returns false.
+func (s *Store[T]) Iterate(f func(i int, v T) bool) {
+	s.l.IterateI(func(i int, e *ElemType) bool {
+		return f(i, s.backing[elemToDist(*e)])
+	})
+}
+
+// Values returns a slice containing the values in the Store, in order.
+func (s *Store[T]) Values() []T {
+	return MapToValues(&s.l, s.backing)
+}
+
+// List returns the ISkipList of handles (indices into the backing slice)
+// underlying the Store. It may be used to call read-only methods and to
+// reorder elements (e.g. via Swap()), but elements must not be added or
+// removed except via the Store. SetMaxLength() may also be called: the slots
+// of elements evicted to enforce the maximum length are freed for reuse.
+func (s *Store[T]) List() *ISkipList {
+	getExt(&s.l).onEvict = func(e ElemType) { s.release(e) }
+	return &s.l
+}
@@ -0,0 +1,30 @@
+package iskiplist
+
+// Hash64 returns a 64-bit hash of the sequence of elements of the ISkipList.
+// ISkipLists that are Equal() have the same hash for a given seed, regardless
+// of their level structures. The hash is FNV-1a applied to the seed followed
+// by each element, all encoded as 8-byte little-endian integers, so it is
+// stable across processes and can be used for change detection or as a cache
+// key. It is not a cryptographic hash. Hash64 runs in O(n) time and does not
+// allocate.
+func (l *ISkipList) Hash64(seed uint64) uint64 {
+	h := fnvAdd(fnvOffset64, seed)
+	for node := densestAt(l, 0); node != nil; node = node.next {
+		h = fnvAdd(h, uint64(elemToDist(node.elem)))
+	}
+	return h
+}
+
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+func fnvAdd(h uint64, v uint64) uint64 {
+	for i := 0; i < 8; i++ {
+		h ^= v & 0xff
+		h *= fnvPrime64
+		v >>= 8
+	}
+	return h
+}
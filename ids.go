@@ -0,0 +1,80 @@
+package iskiplist
+
+import "fmt"
+
+// An ElemID is a stable identifier for an element of an ISkipList. IDs are
+// assigned by an ISkipList with IDs enabled (see EnableIDs()) and are never
+// zero.
+type ElemID uint64
+
+// EnableIDs enables the assignment of a stable ID to each element of the
+// ISkipList. An element keeps its ID until it is removed, regardless of the
+// insertion, removal or movement of other elements, so IDs can be used to
+// refer to a particular element from outside the ISkipList. Operations that
+// move elements (e.g. Swap(), SwapRanges(), Sort() and RotateRange()) move IDs
+// along with the elements that they move. Replacing an element's value (e.g.
+// via Set()) does not change its ID. IDs are not reused by an ISkipList, even
+// after Clear().
+//
+// Once IDs are enabled, IDAt() and IndexOfID() run in O(log n) time. As with
+// the value index, every operation that adds or removes elements has an
+// additional O(log n) of work to do. Enabling IDs on a non-empty ISkipList
+// takes O(n log n) time and assigns IDs to the existing elements in order.
+// Copies of an ISkipList do not inherit IDs.
+func (l *ISkipList) EnableIDs() {
+	ext := getExt(l)
+	if ext.byID != nil {
+		return
+	}
+	enablePositions(l)
+	ext.byID = make(map[ElemID]*posNode)
+	for i := 0; i < ext.positions.length(); i++ {
+		assignID(ext, ext.positions.at(i))
+	}
+}
+
+// DisableIDs disables the IDs enabled by EnableIDs.
+func (l *ISkipList) DisableIDs() {
+	if l.ext == nil {
+		return
+	}
+	l.ext.byID = nil
+	releasePositions(l.ext)
+}
+
+// HasIDs returns true iff IDs are enabled.
+func (l *ISkipList) HasIDs() bool {
+	return l.ext != nil && l.ext.byID != nil
+}
+
+// IDAt returns the ID of the element at the specified index. It panics if IDs
+// are not enabled.
+func (l *ISkipList) IDAt(i int) ElemID {
+	if !l.HasIDs() {
+		panic("IDs have not been enabled for this ISkipList; call EnableIDs first")
+	}
+	if i < 0 || i >= l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", i, l))
+	}
+	return l.ext.positions.at(i).id
+}
+
+// IndexOfID returns the current index of the element with the specified ID.
+// The second return value is false iff there is no such element (e.g. because
+// it has been removed). It panics if IDs are not enabled.
+func (l *ISkipList) IndexOfID(id ElemID) (int, bool) {
+	if !l.HasIDs() {
+		panic("IDs have not been enabled for this ISkipList; call EnableIDs first")
+	}
+	n, ok := l.ext.byID[id]
+	if !ok {
+		return -1, false
+	}
+	return l.ext.positions.indexOf(n), true
+}
+
+func assignID(ext *extensions, n *posNode) {
+	ext.nextID++
+	n.id = ext.nextID
+	ext.byID[n.id] = n
+}
@@ -5,10 +5,12 @@
 // to modify the code to use interface{} as the element type instead.
 //
 // Each ISkipList maintains its own pseudorandom number generator state. The
-// algorithm used is PCG32. By default, seed initialization piggybacks on
-// address space randomization by using the address of an ISkipList to generate
-// a seed. A seed can be supplied manually via Seed() if more entropy is
-// required.
+// algorithm used is PCG32 (or optionally PCG64 – see SeedPCG64()). By default,
+// seed initialization piggybacks on address space randomization by using the
+// address of an ISkipList to generate a seed, mixed with a process-wide counter
+// and the current time. A seed can be supplied manually via Seed() if more
+// entropy is required, or SetAutoSeedFromCryptoRand() can be used to seed
+// automatically from crypto/rand.
 //
 // A cache is maintained of the index and set of nodes associated with the last
 // element access. This increases the efficiency of common iteration patterns
@@ -31,10 +33,12 @@
 // elements.) If you wish to mutate an ISkipList while iterating through it, you
 // should iterate by index.
 //
-// The most efficient way to build an ISkipList is to add elements sequentially
-// using PushFront(). The next most efficient method is to add elements
-// sequentially using PushBack(). Both run in constant time (the latter due
-// to caching), but PushFront() has a lower constant overhead.
+// The most efficient way to build an ISkipList is to use FromSlice(), which
+// links together the nodes of all the levels in a single pass. If elements
+// must be added one at a time, the most efficient method is to add them
+// sequentially using PushFront(). The next most efficient method is to add
+// elements sequentially using PushBack(). Both run in constant time (the
+// latter due to caching), but PushFront() has a lower constant overhead.
 //
 // Slices can often be faster in practice than more sophisticated data
 // structures. The following cautionary notes should be borne in mind:
@@ -58,14 +62,18 @@
 package iskiplist
 
 import (
+	crand "crypto/rand"
+	"encoding/binary"
 	"fmt"
 	"strings"
+	"sync/atomic"
+	"time"
 	// 'unsafe' is used only to get integer values from pointers, which is not
 	// actually unsafe (so long as conversion isn't performed in the other
 	// direction!)
 	"unsafe"
 
-	"github.com/addrummond/iskiplist/pcg"
+	"github.com/addrummond/iskiplist/v2/pcg"
 )
 
 // This is approximately (1/e)*UINT32_MAX. According to the following article,
@@ -83,9 +91,46 @@ const maxLevels = 30
 // indices.
 const minIndexToCache = 8
 
+// autoSeedCounter is incremented each time an ISkipList is automatically
+// seeded, so that ISkipLists seeded in quick succession at the same address
+// (e.g. when allocated from a pool) still get different seeds.
+var autoSeedCounter uint64
+
+// Non-zero iff SetAutoSeedFromCryptoRand(true) has been called.
+var autoSeedFromCryptoRand int32
+
+// SetAutoSeedFromCryptoRand determines whether ISkipLists that are not seeded
+// via Seed() (or similar) are automatically seeded using crypto/rand. This
+// gives the best quality seeds, but is considerably slower than the default
+// method. The setting is process wide and may be changed at any time; it
+// affects only ISkipLists seeded after the change.
+func SetAutoSeedFromCryptoRand(enable bool) {
+	var v int32
+	if enable {
+		v = 1
+	}
+	atomic.StoreInt32(&autoSeedFromCryptoRand, v)
+}
+
+// mix64 is the finalizer from SplitMix64. It scrambles the bits of its input
+// so that inputs differing in only a few bits give very different outputs.
+func mix64(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
 func fastSeed(l *ISkipList) {
 	l.rand = *pcg.NewPCG32()
 
+	if atomic.LoadInt32(&autoSeedFromCryptoRand) != 0 {
+		var b [16]byte
+		if _, err := crand.Read(b[:]); err == nil {
+			seed(l, binary.LittleEndian.Uint64(b[0:]), binary.LittleEndian.Uint64(b[8:]))
+			return
+		}
+	}
+
 	// Use the address of the ISkipList to seed the RNG. This is not ideal,
 	// but it's cheap. For any given execution of any given program,
 	// there'll be more variation in the lower bits of the address
@@ -105,7 +150,52 @@ func fastSeed(l *ISkipList) {
 		seed1 = (s & 7) | (((s >> 8) & 7) << 4) | (((s >> 16) & 7) << 8) | (((s >> 24) & 7) << 12) | (((s >> 32) & 7) << 16) | (((s >> 40) & 7) << 20) | (((s >> 48) & 7) << 24) | (((s >> 56) & 7) << 28)
 		seed2 = ((s >> 4) & 7) | (((s >> 12) & 7) << 4) | (((s >> 20) & 7) << 8) | (((s >> 28) & 7) << 12) | (((s >> 36) & 7) << 16) | (((s >> 44) & 7) << 20) | (((s >> 52) & 7) << 24)
 	}
-	l.Seed(seed1, seed2)
+
+	// The address alone clusters badly when ISkipLists are allocated from an
+	// arena or pool (or reused at the same address), so we mix in a counter
+	// and a timestamp.
+	c := atomic.AddUint64(&autoSeedCounter, 1)
+	t := uint64(time.Now().UnixNano())
+	seed1 = mix64(seed1 ^ mix64(c))
+	seed2 = mix64(seed2 ^ mix64(t))
+
+	seed(l, seed1, seed2)
+}
+
+// LevelSource is the interface implemented by sources of the random values
+// used to assign levels to the nodes of an ISkipList. Each value should be
+// uniformly distributed over the full range of a uint32. *pcg.Pcg32 implements
+// this interface. See SetLevelSource().
+type LevelSource interface {
+	Random() uint32
+}
+
+// pcg64LevelSource adapts a Pcg64 to the LevelSource interface by taking the
+// high 32 bits of each value (which are the better-quality bits).
+type pcg64LevelSource struct {
+	rand *pcg.Pcg64
+}
+
+func (s pcg64LevelSource) Random() uint32 {
+	return uint32(s.rand.Random() >> 32)
+}
+
+// random returns the next 32-bit value from the ISkipList's pseudorandom number
+// generator, seeding the generator first if necessary.
+func random(l *ISkipList) uint32 {
+	if l.ext != nil {
+		maybeReseed(l)
+		if l.ext.levelSource != nil {
+			return l.ext.levelSource.Random()
+		}
+	}
+
+	// The PCG state has to be odd, so we know that it's uninitialized if the
+	// state is zero.
+	if l.rand.IsUninitialized() {
+		fastSeed(l)
+	}
+	return l.rand.Random()
 }
 
 // ElemType is the type of an element of an ISkipList.
@@ -130,6 +220,7 @@ type listNode struct {
 	elem      ElemType // elem if on densest level; distance to next otherwise
 	next      *listNode
 	nextLevel *listNode // level lists start with the sparsest level first
+	aug       *spanAug  // cached span aggregates; see spans.go
 }
 
 type indexCache struct {
@@ -154,28 +245,106 @@ func (c *indexCache) isValid() bool {
 // sequenced and accessed by key).
 type ISkipList struct {
 	length  int
-	nLevels int32 // number of levels - 1; int32 is more than enough for this, saves a bit of space on archs that allow 4-byte align
+	nLevels int32      // number of levels - 1; int32 is more than enough for this, saves a bit of space on archs that allow 4-byte align
+	mods    modCounter // zero size unless built with the 'iskiplistdebug' tag
 	root    *listNode
 	rand    pcg.Pcg32
 	cache   *indexCache
+	ext     *extensions
 }
 
 // Seed seeds the random number generator used for the ISkipList. If Seed is
 // called, it should be called immediately following creation of the ISkipList.
 // If Seed is not called, the random number generator is automatically seeded
-// using the address of the ISkipList. This works fine, but may not be
-// sufficiently random if the ISkipList could be the target of adversarial
-// usage.
+// using the address of the ISkipList, a counter and the current time (or
+// using crypto/rand – see SetAutoSeedFromCryptoRand()). This works fine, but
+// may not be sufficiently random if the ISkipList could be the target of
+// adversarial usage. Calling Seed switches the ISkipList back to its built-in PCG32
+// generator if SeedPCG64 or SetLevelSource was previously called.
 func (l *ISkipList) Seed(seed1 uint64, seed2 uint64) {
+	if tracing(l) {
+		trace(l, "Seed", seed1, seed2)
+	}
+	seed(l, seed1, seed2)
+}
+
+func seed(l *ISkipList, seed1 uint64, seed2 uint64) {
 	seed1 |= 1 // pcg algo requires seed1 (= state) to be odd
 	l.rand.Seed(seed1, seed2)
+	if l.ext != nil {
+		l.ext.levelSource = nil
+	}
+}
+
+// SeedStream seeds the random number generator used for the ISkipList with the
+// nth non-overlapping stream derived from (seed1, seed2), as described in the
+// documentation for pcg.NthStream(). SeedStream(seed1, seed2, 0) is equivalent
+// to Seed(seed1, seed2). This is useful for giving ISkipLists in parallel test
+// shards reproducible but independent level assignments. If SeedStream is
+// called, it should be called immediately following creation of the ISkipList.
+func (l *ISkipList) SeedStream(seed1, seed2 uint64, n uint32) {
+	if tracing(l) {
+		trace(l, "SeedStream", seed1, seed2, n)
+	}
+	seed(l, seed1, seed2)
+	l.rand.Advance(uint64(n) * pcg.StreamLength)
+}
+
+// SeedPCG64 switches the ISkipList to the PCG64 pseudorandom number generator
+// and seeds it with a 128-bit state (seed1 and seed2 being the high and low
+// halves) and a 128-bit stream selector (seed3 and seed4). PCG64 has more state
+// and a longer period than the default PCG32 generator, at the cost of some
+// additional memory per ISkipList and slightly slower level generation. This
+// may be of interest for very long-lived ISkipLists, or where millions of
+// ISkipLists are seeded from related values. If SeedPCG64 is called, it should
+// be called immediately following creation of the ISkipList.
+func (l *ISkipList) SeedPCG64(seed1, seed2, seed3, seed4 uint64) {
+	if tracing(l) {
+		trace(l, "SeedPCG64", seed1, seed2, seed3, seed4)
+	}
+	getExt(l).levelSource = pcg64LevelSource{pcg.NewPCG64().Seed(seed1, seed2, seed3, seed4)}
+}
+
+// SetLevelSource sets a custom source of the random values used to assign
+// levels to nodes, replacing the ISkipList's built-in generator. This is
+// mainly useful for testing: a source that returns a fixed sequence of values
+// can be used to force a particular structure so that an edge case can be
+// reproduced. Passing nil reverts to the built-in generator. If SetLevelSource
+// is called, it should be called immediately following creation of the
+// ISkipList.
+func (l *ISkipList) SetLevelSource(src LevelSource) {
+	if tracing(l) {
+		trace(l, "SetLevelSource", src)
+	}
+	if src == nil {
+		if l.ext != nil {
+			l.ext.levelSource = nil
+		}
+		return
+	}
+	getExt(l).levelSource = src
 }
 
 // SeedFrom sets the pseudorandom number generator state of an ISkipList by
 // copying it from another ISkipList. If SeedFrom is called, it should be called
-// immediately following creation of the ISkipList.
+// immediately following creation of the ISkipList. If the other ISkipList uses
+// a custom LevelSource (see SetLevelSource()), then the source is shared rather
+// than copied.
 func (l *ISkipList) SeedFrom(l2 *ISkipList) {
+	if tracing(l) {
+		trace(l, "SeedFrom", l2)
+	}
 	l.rand = l2.rand
+	if l2.ext != nil && l2.ext.levelSource != nil {
+		src := l2.ext.levelSource
+		if p, ok := src.(pcg64LevelSource); ok {
+			r := *p.rand
+			src = pcg64LevelSource{&r}
+		}
+		getExt(l).levelSource = src
+	} else if l.ext != nil {
+		l.ext.levelSource = nil
+	}
 }
 
 func insertAfter(node *listNode, after *listNode) {
@@ -189,12 +358,23 @@ func (l *ISkipList) Length() int {
 }
 
 // Clear empties an ISkipList. Following a call to Clear(), an ISkipList behaves
-// the same as an ISkipList initialized with its default value.
+// the same as an ISkipList initialized with its default value, except that
+// optional features such as the value index remain enabled.
 func (l *ISkipList) Clear() {
+	if tracing(l) {
+		trace(l, "Clear")
+	}
+	clearList(l)
+}
+
+func clearList(l *ISkipList) {
+	noteModified(l)
+	n := l.length
 	l.length = 0
 	l.nLevels = 0
 	l.root = nil
 	l.cache = nil
+	noteClear(l, n)
 }
 
 func first(l *ISkipList) ElemType {
@@ -207,19 +387,25 @@ func first(l *ISkipList) ElemType {
 	return r
 }
 
-func getTo(node *listNode, index int) *listNode {
+func getTo(node *listNode, index int, obs *extensions) *listNode {
 	li := 0
+	hops := 0
 	for node.nextLevel != nil {
 		d := elemToDist(node.elem)
 		if index >= d && node.next != nil {
 			index -= d
 			node = node.next
+			hops++
 		} else {
 			node = node.nextLevel
 			li++
 		}
 	}
 
+	if searchStatsEnabled || obs != nil {
+		countSearch(obs, hops, li, index)
+	}
+
 	for index != 0 {
 		index--
 		node = node.next
@@ -228,9 +414,10 @@ func getTo(node *listNode, index int) *listNode {
 	return node
 }
 
-func getToWithPrevIndices(node *listNode, index int, prevs []*listNode, prevIndices []int) *listNode {
+func getToWithPrevIndices(node *listNode, index int, prevs []*listNode, prevIndices []int, obs *extensions) *listNode {
 	li := 0
 	i := 0
+	hops := 0
 	for node.nextLevel != nil {
 		prevs[li] = node
 		prevIndices[li] = i
@@ -238,12 +425,17 @@ func getToWithPrevIndices(node *listNode, index int, prevs []*listNode, prevIndi
 		if index-i >= d && node.next != nil {
 			i += d
 			node = node.next
+			hops++
 		} else {
 			node = node.nextLevel
 			li++
 		}
 	}
 
+	if searchStatsEnabled || obs != nil {
+		countSearch(obs, hops, li, index-i)
+	}
+
 	for i < index {
 		i++
 		node = node.next
@@ -289,23 +481,34 @@ func copyToCache(l *ISkipList, index int, prevs []*listNode, prevIndices []int)
 
 func getToWithPrevIndicesTryingCache(l *ISkipList, i int, prevs []*listNode, prevIndices []int) *listNode {
 	var node *listNode
+	obs := searchObserver(l)
 	if l.cache != nil && l.cache.isValid() && len(l.cache.prevs) > 0 && l.cache.index <= i {
+		if searchStatsEnabled || obs != nil {
+			countCacheLookup(obs, true)
+		}
 		p := l.cache.prevs[0]
 		pi := l.cache.prevIndices[0]
-		node = getToWithPrevIndices(p, i-pi, prevs, prevIndices)
+		node = getToWithPrevIndices(p, i-pi, prevs, prevIndices, obs)
 
 		for j := range prevIndices {
 			prevIndices[j] += pi
 		}
+
+		if debugAssertions {
+			assertf(node == getTo(l.root, i, nil), "index cache gave wrong node for index %v (cached index %v)", i, l.cache.index)
+		}
 	} else {
-		node = getToWithPrevIndices(l.root, i, prevs, prevIndices)
+		if searchStatsEnabled || obs != nil {
+			countCacheLookup(obs, false)
+		}
+		node = getToWithPrevIndices(l.root, i, prevs, prevIndices, obs)
 	}
 	return node
 }
 
 func retrieve(l *ISkipList, i int) *listNode {
 	if i < minIndexToCache {
-		return getTo(l.root, i)
+		return getTo(l.root, i, searchObserver(l))
 	}
 
 	// Some of the copying in subsequent code is in the service of ensuring
@@ -333,6 +536,7 @@ func (l *ISkipList) Copy() *ISkipList {
 
 		for oldn != nil {
 			cp := *oldn
+			cp.aug = nil // copies don't inherit span aggregates
 			newn = &cp
 
 			if newRoot == nil {
@@ -413,7 +617,14 @@ func (l *ISkipList) Set(i int, v ElemType) {
 		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", i, l))
 	}
 
-	retrieve(l, i).elem = v
+	if tracing(l) {
+		trace(l, "Set", i, v)
+	}
+
+	node := retrieve(l, i)
+	old := node.elem
+	node.elem = v
+	noteSet(l, i, old, v)
 }
 
 // Update applies an update function to the element at the specified index.
@@ -422,8 +633,14 @@ func (l *ISkipList) Update(i int, upd func(ElemType) ElemType) {
 		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", i, l))
 	}
 
+	if tracing(l) {
+		trace(l, "Update", i, upd)
+	}
+
 	node := retrieve(l, i)
+	old := node.elem
 	node.elem = upd(node.elem)
+	noteSet(l, i, old, node.elem)
 }
 
 // CopyRangeToSlice copies a range of the ISkipList to a slice. The 'from'
@@ -463,7 +680,10 @@ func (l *ISkipList) CopyToSlice(slice []ElemType) {
 // ISkipList. If neither 'from' nor 'to' is out of bounds but to <= from, then
 // this is a no-op. Element pointers remain valid following any subsequent
 // operations on the ISkipList. Keeping a pointer to a deleted element will
-// prevent full garbage collection of the associated skip list nodes.
+// prevent full garbage collection of the associated skip list nodes. The
+// function may modify the values of elements but must not add or remove
+// elements; when built with the 'iskiplistdebug' tag, IterateRange and the
+// other iteration methods panic if it does so.
 func (l *ISkipList) IterateRange(from, to int, f func(*ElemType) bool) {
 	if from < 0 || from > l.length {
 		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", from, l))
@@ -477,12 +697,14 @@ func (l *ISkipList) IterateRange(from, to int, f func(*ElemType) bool) {
 		return
 	}
 
+	mods := l.mods.get()
 	node := retrieve(l, from)
 	dist := to - from
 	for i := 0; i < dist; i++ {
 		if !f(&node.elem) {
 			return
 		}
+		checkNotModified(l, mods)
 		node = node.next
 	}
 }
@@ -509,6 +731,7 @@ func (l *ISkipList) IterateRangeI(from, to int, f func(int, *ElemType) bool) {
 		return
 	}
 
+	mods := l.mods.get()
 	node := retrieve(l, from)
 	dist := to - from
 	index := from
@@ -516,6 +739,7 @@ func (l *ISkipList) IterateRangeI(from, to int, f func(int, *ElemType) bool) {
 		if !f(index, &node.elem) {
 			return
 		}
+		checkNotModified(l, mods)
 		node = node.next
 		index++
 	}
@@ -601,10 +825,18 @@ func removeFirst(l *ISkipList) ElemType {
 }
 
 func remove(l *ISkipList, node *listNode, index int, prevs []*listNode, prevIndices []int) {
+	if debugAssertions {
+		assertf(getTo(l.root, index-1, nil) == node, "'remove' called with node that is not at index %v", index-1)
+		for i, pi := range prevIndices {
+			assertf(getTo(l.root, pi, nil) == getTo(prevs[i], 0, nil), "prevIndices[%v] = %v does not match position of node", i, pi)
+			assertf(pi < index, "prevIndices[%v] = %v is not before index %v", i, pi, index)
+		}
+	}
 	node.next = node.next.next             // node.next can't be nil because it precedes the element to be removed
 	for i := len(prevs) - 1; i >= 0; i-- { // from densest to sparsest
 		p := prevs[i]
 		pi := prevIndices[i]
+		p.aug = nil
 		if p.next != nil {
 			d := elemToDist(p.elem) // if it's in prevs, we know it's not on the densest level, so elem is the distance
 			if index == d+pi {
@@ -627,13 +859,33 @@ func (l *ISkipList) Remove(index int) ElemType {
 		panic(fmt.Sprintf("Index %v %v out of range in call to 'Remove'", index, l.length))
 	}
 
+	if tracing(l) {
+		trace(l, "Remove", index)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	return removeIndex(l, index)
+}
+
+func removeIndex(l *ISkipList, index int) ElemType {
+	e := removeAt(l, index)
+	noteRemove(l, index, e)
+	return e
+}
+
+func removeAt(l *ISkipList, index int) ElemType {
+	noteModified(l)
 	if l.cache != nil && l.cache.index >= index {
 		l.cache.invalidate()
 	}
 
 	if l.length-1 == 0 {
 		l.length--
-		v := l.root.elem
+		// The root column may still have sparse levels (e.g. following
+		// Truncate()), so the element is found on the densest level.
+		v := densest(l).elem
 		l.root = nil
 		l.nLevels = 0
 		return v
@@ -647,7 +899,7 @@ func (l *ISkipList) Remove(index int) ElemType {
 
 	prevs := make([]*listNode, l.nLevels)
 	prevIndices := make([]int, l.nLevels)
-	node := getToWithPrevIndices(l.root, index-1, prevs, prevIndices)
+	node := getToWithPrevIndices(l.root, index-1, prevs, prevIndices, searchObserver(l))
 	e := node.next.elem
 	remove(l, node, index, prevs, prevIndices)
 	l.length--
@@ -663,15 +915,30 @@ func (l *ISkipList) Truncate(n int) {
 	if n < 0 || n > l.length {
 		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", n, l))
 	}
+
+	if tracing(l) {
+		trace(l, "Truncate", n)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	truncate(l, n)
+}
+
+func truncate(l *ISkipList, n int) {
 	if n >= l.length {
 		return
 	}
 
 	if n == 0 {
-		l.Clear()
+		clearList(l)
 		return
 	}
 
+	noteTruncate(l, n)
+	noteModified(l)
+
 	if l.cache != nil && l.cache.index >= n {
 		l.cache.invalidate()
 	}
@@ -683,6 +950,7 @@ func (l *ISkipList) Truncate(n int) {
 	node.next = nil
 	for _, p := range prevs {
 		p.next = nil
+		p.aug = nil
 	}
 
 	l.length = n
@@ -722,6 +990,7 @@ func addNRootLevels(l *ISkipList, n int) {
 		clone := *l.root
 		l.root.nextLevel = &clone
 		l.root.next = nil
+		l.root.aug = nil
 		// We don't set l.root.elem, as its value (which is the distance to the
 		// next node for nodes on levels other than the densest) is considered
 		// meaningless when 'next' is nil.
@@ -741,11 +1010,13 @@ func addSparserLevel(l *ISkipList, prevAtLevel, node *listNode, level, index int
 
 	clone := *node
 	clone.nextLevel = node
+	clone.aug = nil
 	if prevAtLevel == nil {
 		l.root.next = &clone
 		l.root.elem = distToElem(index)
 		clone.next = nil
 	} else {
+		prevAtLevel.aug = nil
 		oldNext := prevAtLevel.next
 		clone.next = oldNext
 		prevAtLevel.next = &clone
@@ -761,10 +1032,17 @@ func addSparserLevel(l *ISkipList, prevAtLevel, node *listNode, level, index int
 }
 
 func shrink(l *ISkipList, levels int) {
+	if levels <= 0 {
+		return
+	}
 	for i := 0; i < levels; i++ {
 		l.root = l.root.nextLevel
 	}
 	l.nLevels -= int32(levels)
+	// The cache holds a node for each level, including those just dropped.
+	if l.cache != nil {
+		l.cache.invalidate()
+	}
 }
 
 func maybeShrink(l *ISkipList) {
@@ -787,6 +1065,7 @@ func insertAtBeginning(l *ISkipList, elem ElemType) {
 	// root node, and that the old root node has just been inserted. Thus, we
 	// randomly choose again the number of levels for the old root node.
 
+	noteModified(l)
 	if l.cache != nil {
 		l.cache.invalidate()
 	}
@@ -837,8 +1116,19 @@ func insertAtBeginning(l *ISkipList, elem ElemType) {
 // PushFront adds an element to the beginning of the ISkipList. PushFront runs
 // in constant time.
 func (l *ISkipList) PushFront(elem ElemType) {
+	checkCapacity(l, 1)
+
+	if tracing(l) {
+		trace(l, "PushFront", elem)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
 	insertAtBeginning(l, elem)
 	l.length++
+	noteInsert(l, 0, elem)
+	enforceMaxLength(l, 0)
 }
 
 // PopFront removes the first element of the list and returns it. The second
@@ -848,39 +1138,74 @@ func (l *ISkipList) PopFront() (r ElemType, ok bool) {
 	if l.length == 0 {
 		return
 	}
+	if tracing(l) {
+		trace(l, "PopFront")
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
 	ok = true
-	r = l.Remove(0)
+	r = removeIndex(l, 0)
 	return
 }
 
+// PeekFront returns the first element of the list without removing it. The
+// second return value is false iff the list is empty. PeekFront runs in
+// constant time.
+func (l *ISkipList) PeekFront() (r ElemType, ok bool) {
+	if l.length == 0 {
+		return
+	}
+	return first(l), true
+}
+
 // PushBack adds an element to the end of the ISkipList. PushFront should be
 // preferred where applicable.
 func (l *ISkipList) PushBack(elem ElemType) {
+	checkCapacity(l, 1)
+
+	if tracing(l) {
+		trace(l, "PushBack", elem)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
 	index := l.length
 
 	if index == 0 {
 		insertAtBeginning(l, elem)
 		l.length++
+		noteInsert(l, 0, elem)
+		enforceMaxLength(l, 0)
 		return
 	}
 
 	l.length++
+	noteModified(l)
 
 	prevs := make([]*listNode, l.nLevels)
 	prevIndices := make([]int, l.nLevels)
 
 	var node *listNode
+	obs := searchObserver(l)
 	if l.cache != nil && l.cache.isValid() && len(l.cache.prevs) > 0 && l.cache.index <= index-1 {
+		if searchStatsEnabled || obs != nil {
+			countCacheLookup(obs, true)
+		}
 		p := l.cache.prevs[0]
 		pi := l.cache.prevIndices[0]
 
-		node = getToWithPrevIndices(p, index-1-pi, prevs, prevIndices)
+		node = getToWithPrevIndices(p, index-1-pi, prevs, prevIndices, obs)
 
 		for j := range prevIndices {
 			prevIndices[j] += pi
 		}
 	} else {
-		node = getToWithPrevIndices(l.root, index-1, prevs, prevIndices)
+		if searchStatsEnabled || obs != nil {
+			countCacheLookup(obs, false)
+		}
+		node = getToWithPrevIndices(l.root, index-1, prevs, prevIndices, obs)
 	}
 
 	if index-1 >= minIndexToCache {
@@ -907,7 +1232,11 @@ func (l *ISkipList) PushBack(elem ElemType) {
 
 	for ; prevsI >= 0; prevsI-- {
 		prevs[prevsI].elem = distToElem(elemToDist(prevs[prevsI].elem) + 1)
+		prevs[prevsI].aug = nil
 	}
+
+	noteInsert(l, index, elem)
+	enforceMaxLength(l, index)
 }
 
 // PopBack removes the last element of the list and returns it. The second
@@ -917,18 +1246,45 @@ func (l *ISkipList) PopBack() (r ElemType, ok bool) {
 	if l.length == 0 {
 		return
 	}
+	if tracing(l) {
+		trace(l, "PopBack")
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
 	ok = true
-	r = l.Remove(l.length - 1)
+	r = removeIndex(l, l.length-1)
 	return
 }
 
+// PeekBack returns the last element of the list without removing it. The
+// second return value is false iff the list is empty.
+func (l *ISkipList) PeekBack() (r ElemType, ok bool) {
+	if l.length == 0 {
+		return
+	}
+	return retrieve(l, l.length-1).elem, true
+}
+
 // Insert inserts an element before the element at the specified index, or at
 // the end of the list if the index is equal to the length of the ISkipList.
 func (l *ISkipList) Insert(index int, elem ElemType) {
 	if index < 0 || index > l.length {
 		panic("Index out of range in call to 'Insert'")
 	}
+	checkCapacity(l, 1)
 
+	if tracing(l) {
+		trace(l, "Insert", index, elem)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	insert(l, index, elem)
+}
+
+func insert(l *ISkipList, index int, elem ElemType) {
 	if l.cache != nil && l.cache.index >= index {
 		l.cache.invalidate()
 	}
@@ -936,26 +1292,36 @@ func (l *ISkipList) Insert(index int, elem ElemType) {
 	if index == 0 {
 		insertAtBeginning(l, elem)
 		l.length++
+		noteInsert(l, 0, elem)
+		enforceMaxLength(l, 0)
 		return
 	}
 
 	l.length++
+	noteModified(l)
 
 	prevs := make([]*listNode, l.nLevels)
 	prevIndices := make([]int, l.nLevels)
 
 	var node *listNode
+	obs := searchObserver(l)
 	if l.cache != nil && l.cache.isValid() && len(l.cache.prevs) > 0 && l.cache.index <= index-1 {
+		if searchStatsEnabled || obs != nil {
+			countCacheLookup(obs, true)
+		}
 		p := l.cache.prevs[0]
 		pi := l.cache.prevIndices[0]
 
-		node = getToWithPrevIndices(p, index-1-pi, prevs, prevIndices)
+		node = getToWithPrevIndices(p, index-1-pi, prevs, prevIndices, obs)
 
 		for j := range prevIndices {
 			prevIndices[j] += pi
 		}
 	} else {
-		node = getToWithPrevIndices(l.root, index-1, prevs, prevIndices)
+		if searchStatsEnabled || obs != nil {
+			countCacheLookup(obs, false)
+		}
+		node = getToWithPrevIndices(l.root, index-1, prevs, prevIndices, obs)
 	}
 
 	if index-1 >= minIndexToCache {
@@ -982,7 +1348,11 @@ func (l *ISkipList) Insert(index int, elem ElemType) {
 
 	for ; prevsI >= 0; prevsI-- {
 		prevs[prevsI].elem = distToElem(elemToDist(prevs[prevsI].elem) + 1)
+		prevs[prevsI].aug = nil
 	}
+
+	noteInsert(l, index, elem)
+	enforceMaxLength(l, index)
 }
 
 // Swap swaps the values of the elements at the specified indices.
@@ -994,6 +1364,17 @@ func (l *ISkipList) Swap(index1, index2 int) {
 		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", index2, l))
 	}
 
+	if tracing(l) {
+		trace(l, "Swap", index1, index2)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	swap(l, index1, index2)
+}
+
+func swap(l *ISkipList, index1, index2 int) {
 	if index1 == index2 {
 		return
 	}
@@ -1003,7 +1384,7 @@ func (l *ISkipList) Swap(index1, index2 int) {
 
 	prevs := make([]*listNode, l.nLevels)
 	prevIndices := make([]int, l.nLevels)
-	node1 := getToWithPrevIndices(l.root, index1, prevs, prevIndices)
+	node1 := getToWithPrevIndices(l.root, index1, prevs, prevIndices, searchObserver(l))
 	if index1 >= minIndexToCache {
 		copyToCache(l, index1, prevs, prevIndices)
 	}
@@ -1014,8 +1395,9 @@ func (l *ISkipList) Swap(index1, index2 int) {
 		p = prevs[0]
 		pi = prevIndices[0]
 	}
-	node2 := getTo(p, index2-pi)
+	node2 := getTo(p, index2-pi, searchObserver(l))
 	node1.elem, node2.elem = node2.elem, node1.elem
+	noteSwap(l, index1, index2)
 }
 
 func debugPrintList(node *listNode, pointerDigits int) string {
@@ -1097,3 +1479,10 @@ func DebugPrintISkipList(l *ISkipList, pointerDigits int) string {
 
 	return s.String()
 }
+
+// GoString returns the level-by-level representation of the ISkipList given
+// by DebugPrintISkipList (without pointers), so that the structure of an
+// ISkipList is shown when it is formatted with the %#v verb.
+func (l *ISkipList) GoString() string {
+	return DebugPrintISkipList(l, 0)
+}
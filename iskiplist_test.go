@@ -1,10 +1,23 @@
 package iskiplist
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+	"unsafe"
 
-	"github.com/addrummond/iskiplist/sliceutils"
+	"github.com/addrummond/iskiplist/v2/pcg"
+	"github.com/addrummond/iskiplist/v2/sliceutils"
 )
 
 const (
@@ -268,12 +281,16 @@ func TestRandomOpSequences(t *testing.T) {
 	const niters = 20
 
 	var sl ISkipList
-	sl.Seed(randSeed1, randSeed2)
 	for i := 0; i < niters; i++ {
 		t.Logf("----- Generating random sequence of %v operations -----\n", nops)
 		ops := sliceutils.GenOps(nops, 0)
 		sl.Clear()
+		// Reseed for each sequence so that a failing sequence can be replayed
+		// (and shrunk) in isolation.
+		seed2 := uint64(randSeed2 + i)
+		sl.Seed(randSeed1, seed2)
 		a := make([]ElemType, 0)
+		failed := false
 		for _, o := range ops {
 			t.Logf("%s\n", sliceutils.PrintOp(&o))
 			sliceutils.ApplyOpToSlice(&o, &a)
@@ -285,6 +302,7 @@ func TestRandomOpSequences(t *testing.T) {
 
 			if len(a) != sl.Length() {
 				t.Errorf("ISkipList has wrong length (%v instead of %v)\n", sl.Length(), len(a))
+				failed = true
 			}
 
 			// Equality check by looping over indices.
@@ -294,10 +312,15 @@ func TestRandomOpSequences(t *testing.T) {
 				t.Logf("Checking %v\n", i)
 				if v != e {
 					t.Errorf("Expected value %v at index %v, got %v instead (index loop).\n", v, i, e)
+					failed = true
 				}
 			}
 		}
 
+		if failed {
+			reportShrunkOpSequence(t, ops, randSeed1, seed2)
+		}
+
 		// Equality check using ForAllI
 		t.Logf("Testing result via ForAllI()...")
 		sl.ForAllI(func(i int, v *ElemType) {
@@ -318,6 +341,40 @@ func TestRandomOpSequences(t *testing.T) {
 	}
 }
 
+func FuzzISkipListOps(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0, 0, 0, 1, 0, 0, 0, 2, 0, 1, 0, 3, 2, 0, 1, 0, 1, 1, 0, 0})
+	// Build a list of 250 elements, read near its start (populating the index
+	// cache), and then truncate it, dropping levels.
+	var truncateSeed []byte
+	for i := 0; i < 250; i++ {
+		truncateSeed = append(truncateSeed, 0, byte(i), 0, byte(i))
+	}
+	truncateSeed = append(truncateSeed, 4, 10, 0, 0, 3, 12, 0, 0, 4, 11, 0, 0)
+	f.Add(truncateSeed)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ops := sliceutils.DecodeOps(data, 0)
+		var sl ISkipList
+		sl.Seed(randSeed1, randSeed2)
+		a := make([]ElemType, 0)
+		for i, o := range ops {
+			sliceutils.ApplyOpToSlice(&o, &a)
+			applyOpToISkipList(&o, &sl)
+			if err := sl.Validate(); err != nil {
+				t.Fatalf("Invalid ISkipList after op %v (%s): %v\n\n%s", i, sliceutils.PrintOp(&o), err, sliceutils.GoCode(ops[:i+1], "sl"))
+			}
+			if sl.Length() != len(a) {
+				t.Fatalf("ISkipList has wrong length (%v instead of %v)", sl.Length(), len(a))
+			}
+		}
+		sl.ForAllI(func(i int, v *ElemType) {
+			if *v != a[i] {
+				t.Fatalf("Expected value %v at index %v, got %v instead\n\n%s", a[i], i, *v, sliceutils.GoCode(ops, "sl"))
+			}
+		})
+	})
+}
+
 func benchmarkRandomOpSequenceWithISKipList(ops []sliceutils.Op, sl *ISkipList, l int) {
 	for _, o := range ops {
 		applyOpToISkipList(&o, sl)
@@ -423,6 +480,17 @@ func BenchmarkCreationMethods(b *testing.B) {
 				}
 			}
 		})
+
+		b.Run(fmt.Sprintf("Creating ISkipList of length %v using FromSlice", i), func(b *testing.B) {
+			a := make([]ElemType, i, i)
+			for k := 0; k < len(a); k++ {
+				a[k] = k
+			}
+			b.ResetTimer()
+			for j := 0; j < b.N; j++ {
+				FromSlice(a)
+			}
+		})
 	}
 }
 
@@ -434,5 +502,4262 @@ func applyOpToISkipList(op *sliceutils.Op, sl *ISkipList) {
 		sl.Remove(op.Index1)
 	case sliceutils.OpSwap:
 		sl.Swap(op.Index1, op.Index2)
+	case sliceutils.OpTruncate:
+		sl.Truncate(op.Index1)
+	case sliceutils.OpAt:
+		sl.At(op.Index1)
+	}
+}
+
+// opSequenceFails returns true iff applying the given ops to a freshly seeded
+// ISkipList gives different results to applying them to a slice (or if the
+// ISkipList panics).
+func opSequenceFails(ops []sliceutils.Op, seed1, seed2 uint64) (failed bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			failed = true
+		}
+	}()
+
+	var sl ISkipList
+	sl.Seed(seed1, seed2)
+	a := make([]ElemType, 0)
+	for _, o := range ops {
+		sliceutils.ApplyOpToSlice(&o, &a)
+		applyOpToISkipList(&o, &sl)
+		if sl.Length() != len(a) {
+			return true
+		}
+		for i, v := range a {
+			if sl.At(i) != v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reportShrunkOpSequence shrinks a failing op sequence and logs it as Go code
+// that can be pasted into a regression test.
+func reportShrunkOpSequence(t *testing.T, ops []sliceutils.Op, seed1, seed2 uint64) {
+	if !opSequenceFails(ops, seed1, seed2) {
+		t.Logf("Failure could not be reproduced on a fresh ISkipList; not shrinking\n")
+		return
+	}
+	shrunk := sliceutils.Shrink(ops, 0, func(ops []sliceutils.Op) bool {
+		return opSequenceFails(ops, seed1, seed2)
+	})
+	t.Errorf(
+		"Op sequence shrunk from %v to %v ops:\n\nvar sl ISkipList\nsl.Seed(%v, %v)\n%s",
+		len(ops), len(shrunk), seed1, seed2, sliceutils.GoCode(shrunk, "sl"),
+	)
+}
+
+func TestValueIndex(t *testing.T) {
+	const nops = 1000
+
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 50; i++ {
+		sl.PushBack(distToElem(i))
+	}
+	sl.EnableValueIndex()
+
+	check := func() {
+		first := make(map[ElemType]int)
+		sl.ForAllI(func(i int, v *ElemType) {
+			if _, ok := first[*v]; !ok {
+				first[*v] = i
+			}
+		})
+		for v, i := range first {
+			if j, ok := sl.PositionOf(v); !ok || i != j {
+				t.Errorf("PositionOf(%v) returned (%v, %v), expected %v\n", v, j, ok, i)
+			}
+		}
+	}
+
+	ops := sliceutils.GenOps(nops, sl.Length())
+	for i, o := range ops {
+		applyOpToISkipList(&o, &sl)
+		if o.Kind == sliceutils.OpInsert && i%2 == 0 {
+			sl.Set(o.Index1, distToElem(1000+i))
+		}
+		check()
+	}
+
+	sl.Truncate(sl.Length() / 2)
+	check()
+	if _, ok := sl.PositionOf(distToElem(-1)); ok {
+		t.Errorf("PositionOf found a value not in the list\n")
+	}
+}
+
+func TestMaxLength(t *testing.T) {
+	const max = 100
+
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 150; i++ {
+		sl.PushBack(distToElem(i))
+	}
+	sl.SetMaxLength(max)
+	if sl.Length() != max || sl.At(0) != distToElem(50) {
+		t.Errorf("Unexpected result of SetMaxLength: length %v, first elem %v\n", sl.Length(), sl.At(0))
+	}
+
+	for i := 150; i < 1000; i++ {
+		sl.PushBack(distToElem(i))
+		if sl.Length() != max || sl.At(0) != distToElem(i-max+1) || sl.At(max-1) != distToElem(i) {
+			t.Errorf("Unexpected window after PushBack(%v)\n", i)
+		}
+	}
+
+	sl.PushFront(distToElem(-1))
+	if sl.Length() != max || sl.At(0) != distToElem(-1) || sl.At(max-1) != distToElem(998) {
+		t.Errorf("Unexpected window after PushFront\n")
+	}
+
+	sl.Insert(50, distToElem(-2))
+	if sl.Length() != max || sl.At(0) != distToElem(900) || sl.At(49) != distToElem(-2) {
+		t.Errorf("Unexpected window after Insert\n")
+	}
+
+	sl.SetMaxLength(0)
+	sl.PushBack(distToElem(1000))
+	if sl.Length() != max+1 {
+		t.Errorf("Unexpected length after removing bound\n")
+	}
+}
+
+func TestTimeSeries(t *testing.T) {
+	var ts TimeSeries
+	ts.Seed(randSeed1, randSeed2)
+
+	var times []int
+	for i, o := range sliceutils.GenOps(1000, 0) {
+		tm := elemToDist(o.Elem)
+		j := ts.Insert(tm, distToElem(i))
+		if j > 0 && times[j-1] > tm || j < len(times) && times[j] <= tm {
+			t.Fatalf("Insert(%v) placed element at unexpected index %v\n", tm, j)
+		}
+		times = append(times, 0)
+		copy(times[j+1:], times[j:])
+		times[j] = tm
+	}
+
+	for t1 := -1; t1 < 102; t1 += 3 {
+		for t2 := t1; t2 < 102; t2 += 5 {
+			from, to := ts.RangeByTime(t1, t2)
+			for i, tm := range times {
+				inRange := tm >= t1 && tm < t2
+				if inRange != (i >= from && i < to) {
+					t.Fatalf("RangeByTime(%v, %v) = (%v, %v) is wrong at index %v\n", t1, t2, from, to, i)
+				}
+			}
+		}
+	}
+
+	n := ts.RemoveBefore(50)
+	if tm, _ := ts.At(0); tm < 50 || ts.Length() != len(times)-n || (n > 0 && times[n-1] >= 50) {
+		t.Errorf("Unexpected result from RemoveBefore\n")
+	}
+	for i := 0; i < ts.Length(); i++ {
+		if tm, _ := ts.At(i); tm != times[n+i] {
+			t.Fatalf("Expected timestamp %v at index %v after RemoveBefore, got %v\n", times[n+i], i, tm)
+		}
+	}
+	if err := ts.times.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.values.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if n := ts.RemoveBefore(50); n != 0 {
+		t.Errorf("Expected second RemoveBefore(50) to remove nothing, removed %v\n", n)
+	}
+	if n, l := ts.RemoveBefore(1000), ts.Length(); l != 0 || n == 0 {
+		t.Errorf("Expected RemoveBefore(1000) to empty the TimeSeries\n")
+	}
+}
+
+func TestSummaryStats(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 10; i++ {
+		sl.PushBack(distToElem(i))
+	}
+	sl.EnableSummaryStats([]ElemType{distToElem(25), distToElem(50), distToElem(75)})
+
+	for _, o := range sliceutils.GenOps(1000, sl.Length()) {
+		applyOpToISkipList(&o, &sl)
+		if o.Kind == sliceutils.OpInsert && o.Index1%3 == 0 {
+			sl.Update(o.Index1, func(e ElemType) ElemType { return distToElem(elemToDist(e) * 2) })
+		}
+
+		var sum int64
+		var sumSq float64
+		hist := make([]int, 4)
+		sl.ForAll(func(e *ElemType) {
+			d := elemToDist(*e)
+			sum += int64(d)
+			sumSq += float64(d) * float64(d)
+			if d >= 75 {
+				hist[3]++
+			} else {
+				hist[d/25]++
+			}
+		})
+		if sl.Sum() != sum {
+			t.Fatalf("Sum() = %v, expected %v\n", sl.Sum(), sum)
+		}
+		if sl.Length() > 0 {
+			mean, std := sl.MeanStd()
+			n := float64(sl.Length())
+			emean := float64(sum) / n
+			estd := math.Sqrt(math.Max(0, sumSq/n-emean*emean))
+			if math.Abs(mean-emean) > 1e-9 || math.Abs(std-estd) > 1e-6 {
+				t.Fatalf("MeanStd() = (%v, %v), expected (%v, %v)\n", mean, std, emean, estd)
+			}
+		}
+		for i, c := range sl.Histogram() {
+			if hist[i] != c {
+				t.Fatalf("Histogram() = %v, expected %v\n", sl.Histogram(), hist)
+			}
+		}
+	}
+
+	sl.Clear()
+	if sl.Sum() != 0 || !math.IsNaN(func() float64 { m, _ := sl.MeanStd(); return m }()) {
+		t.Errorf("Unexpected stats after Clear\n")
+	}
+}
+
+func TestMeanStdLargeValues(t *testing.T) {
+	// With values this large, a floating point sum of squares loses the
+	// variance entirely to rounding error.
+	const base = 1 << 40
+	var sl ISkipList
+	sl.EnableSummaryStats(nil)
+	for i := 0; i < 1000; i++ {
+		sl.PushBack(distToElem(base + i%2))
+	}
+	for i := 0; i < 10000; i++ {
+		j := i % sl.Length()
+		e := sl.At(j)
+		sl.Set(j, distToElem(base<<(i%8)))
+		sl.Set(j, e)
+	}
+	mean, std := sl.MeanStd()
+	if mean != base+0.5 || std != 0.5 {
+		t.Errorf("MeanStd() = (%v, %v), expected (%v, 0.5)\n", mean, std, base+0.5)
+	}
+	if a := testing.AllocsPerRun(100, func() { sl.MeanStd() }); a != 0 {
+		t.Errorf("MeanStd() made %v allocations\n", a)
+	}
+
+	// Here n * sumSq overflows 128 bits.
+	const huge = 1 << 62
+	sl.Clear()
+	for i := 0; i < 8; i++ {
+		sl.PushBack(distToElem(huge * (1 - 2*(i%2))))
+	}
+	mean, std = sl.MeanStd()
+	if mean != 0 || std != huge {
+		t.Errorf("MeanStd() = (%v, %v), expected (0, %v)\n", mean, std, float64(huge))
+	}
+
+	// The 128-bit computation agrees with the big.Int computation.
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	for i := 0; i < 1000; i++ {
+		s := summaryStats{sum: int64(rand.Random())<<20 - 1<<40, sumSqHi: uint64(rand.Random()), sumSqLo: uint64(rand.Random())<<32 | uint64(rand.Random())}
+		n := uint64(rand.Random())
+		if v, w := s.scaledVariance(n), s.scaledVarianceBig(n); v != w {
+			t.Fatalf("scaledVariance(%v) = %v for %+v, expected %v\n", n, v, s, w)
+		}
+	}
+}
+
+func TestPCG64(t *testing.T) {
+	var sl1, sl2 ISkipList
+	sl1.SeedPCG64(1, 2, 3, 4)
+	sl2.SeedFrom(&sl1)
+	a := make([]ElemType, 0)
+	for _, o := range sliceutils.GenOps(1000, 0) {
+		sliceutils.ApplyOpToSlice(&o, &a)
+		applyOpToISkipList(&o, &sl1)
+		applyOpToISkipList(&o, &sl2)
+	}
+	for i, v := range a {
+		if sl1.At(i) != v {
+			t.Errorf("Expected value %v at index %v, got %v\n", v, i, sl1.At(i))
+		}
+	}
+	if DebugPrintISkipList(&sl1, 0) != DebugPrintISkipList(&sl2, 0) {
+		t.Errorf("Lists with the same PCG64 seed have different structures\n")
+	}
+}
+
+func TestSeedStream(t *testing.T) {
+	var sl0, sl1, sl ISkipList
+	sl0.SeedStream(randSeed1, randSeed2, 0)
+	sl1.SeedStream(randSeed1, randSeed2, 1)
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 1000; i++ {
+		sl0.Insert(i/2, distToElem(i))
+		sl1.Insert(i/2, distToElem(i))
+		sl.Insert(i/2, distToElem(i))
+	}
+	if DebugPrintISkipList(&sl0, 0) != DebugPrintISkipList(&sl, 0) {
+		t.Errorf("Stream 0 differs from Seed()\n")
+	}
+	if DebugPrintISkipList(&sl0, 0) == DebugPrintISkipList(&sl1, 0) {
+		t.Errorf("Streams 0 and 1 produced the same structure\n")
+	}
+
+	p0 := pcg.NthStream(randSeed1, randSeed2, 3)
+	p1 := pcg.NthStream(randSeed1, randSeed2, 4).Retreat(pcg.StreamLength)
+	for i := 0; i < 10; i++ {
+		if p0.Random() != p1.Random() {
+			t.Errorf("NthStream(4) is not StreamLength steps ahead of NthStream(3)\n")
+		}
+	}
+}
+
+// cyclicLevelSource returns the values in a slice in turn.
+type cyclicLevelSource struct {
+	values []uint32
+	i      int
+}
+
+func (s *cyclicLevelSource) Random() uint32 {
+	r := s.values[s.i%len(s.values)]
+	s.i++
+	return r
+}
+
+func TestLevelSource(t *testing.T) {
+	// A source that always returns 0 never promotes any node, so the result
+	// should be a single level.
+	var flat ISkipList
+	flat.SetLevelSource(&cyclicLevelSource{values: []uint32{0}})
+	for i := 0; i < 100; i++ {
+		flat.PushBack(distToElem(i))
+		flat.Insert(i/2, distToElem(i))
+	}
+	if flat.nLevels != 0 {
+		t.Errorf("Expected a single level, got %v\n", flat.nLevels+1)
+	}
+
+	// A source that returns the maximum value every other time produces lots
+	// of tall nodes. Check that the list still behaves correctly.
+	var tall ISkipList
+	tall.SetLevelSource(&cyclicLevelSource{values: []uint32{^uint32(0), 0, ^uint32(0), 1 << 31, 0}})
+	a := make([]ElemType, 0)
+	for _, o := range sliceutils.GenOps(1000, 0) {
+		sliceutils.ApplyOpToSlice(&o, &a)
+		applyOpToISkipList(&o, &tall)
+	}
+	for i, v := range a {
+		if tall.At(i) != v {
+			t.Errorf("Expected value %v at index %v, got %v\n", v, i, tall.At(i))
+		}
+	}
+}
+
+func TestReseedInterval(t *testing.T) {
+	var sl1, sl2 ISkipList
+	sl1.Seed(randSeed1, randSeed2)
+	sl2.Seed(randSeed1, randSeed2)
+	sl2.SetReseedInterval(10)
+	a := make([]ElemType, 0)
+	for _, o := range sliceutils.GenOps(1000, 0) {
+		sliceutils.ApplyOpToSlice(&o, &a)
+		applyOpToISkipList(&o, &sl1)
+		applyOpToISkipList(&o, &sl2)
+	}
+	// The ops may leave the lists almost empty, so make sure that there's
+	// some structure to compare.
+	for i := 0; i < 200; i++ {
+		a = append(a, distToElem(i))
+		sl1.PushBack(distToElem(i))
+		sl2.PushBack(distToElem(i))
+	}
+	for i, v := range a {
+		if sl2.At(i) != v {
+			t.Errorf("Expected value %v at index %v, got %v\n", v, i, sl2.At(i))
+		}
+	}
+	if DebugPrintISkipList(&sl1, 0) == DebugPrintISkipList(&sl2, 0) {
+		t.Errorf("Reseeding had no effect on structure\n")
+	}
+}
+
+func TestAutoSeedAtSameAddress(t *testing.T) {
+	// Simulate repeated allocation of an ISkipList at the same address, as can
+	// happen with pooled allocation.
+	var sl ISkipList
+	structures := make(map[string]bool)
+	for i := 0; i < 10; i++ {
+		sl = ISkipList{}
+		for j := 0; j < 100; j++ {
+			sl.PushBack(distToElem(j))
+		}
+		structures[DebugPrintISkipList(&sl, 0)] = true
+	}
+	if len(structures) < 9 {
+		t.Errorf("Only %v distinct structures from 10 automatically seeded lists\n", len(structures))
+	}
+
+	SetAutoSeedFromCryptoRand(true)
+	defer SetAutoSeedFromCryptoRand(false)
+	sl = ISkipList{}
+	for j := 0; j < 100; j++ {
+		sl.PushBack(distToElem(j))
+	}
+	if sl.Length() != 100 {
+		t.Errorf("Unexpected length %v\n", sl.Length())
+	}
+}
+
+func TestStructurallyEqual(t *testing.T) {
+	var sl1, sl2, sl3 ISkipList
+	sl1.Seed(randSeed1, randSeed2)
+	sl2.Seed(randSeed1, randSeed2)
+	sl3.Seed(randSeed1+2, randSeed2)
+	for _, o := range sliceutils.GenOps(500, 0) {
+		applyOpToISkipList(&o, &sl1)
+		applyOpToISkipList(&o, &sl2)
+		applyOpToISkipList(&o, &sl3)
+	}
+	// The ops may leave the lists almost empty, so make sure that there's
+	// some structure to compare.
+	for i := 0; i < 200; i++ {
+		sl1.PushBack(distToElem(i))
+		sl2.PushBack(distToElem(i))
+		sl3.PushBack(distToElem(i))
+	}
+
+	if !StructurallyEqual(&sl1, &sl2) {
+		t.Errorf("Identically seeded lists are not structurally equal\n")
+	}
+	if !StructurallyEqual(&sl1, sl1.Copy()) {
+		t.Errorf("Copy is not structurally equal to the original\n")
+	}
+	if StructurallyEqual(&sl1, &sl3) {
+		t.Errorf("Differently seeded lists are structurally equal\n")
+	}
+	sl2.Set(0, sl2.At(0)+1)
+	if StructurallyEqual(&sl1, &sl2) {
+		t.Errorf("Lists with different elements are structurally equal\n")
+	}
+}
+
+type recordingTracer struct {
+	calls []string
+}
+
+func (r *recordingTracer) Trace(method string, args []interface{}) {
+	r.calls = append(r.calls, fmt.Sprintf("%s%v", method, args))
+}
+
+func TestTracer(t *testing.T) {
+	var sl ISkipList
+	var tr recordingTracer
+	sl.SetTracer(&tr)
+	sl.Seed(randSeed1, randSeed2)
+	sl.SetMaxLength(3)
+	for i := 0; i < 4; i++ {
+		sl.PushBack(i)
+	}
+	sl.Insert(1, 10)
+	sl.Swap(0, 2)
+	sl.Set(0, 20)
+	sl.PopFront()
+	sl.PopBack()
+	sl.Truncate(0)
+	sl.SetTracer(nil)
+	sl.PushBack(1)
+
+	expected := []string{
+		"Seed[12345 67891]",
+		"SetMaxLength[3]",
+		"PushBack[0]",
+		"PushBack[1]",
+		"PushBack[2]",
+		"PushBack[3]",
+		"Insert[1 10]",
+		"Swap[0 2]",
+		"Set[0 20]",
+		"PopFront[]",
+		"PopBack[]",
+		"Truncate[0]",
+	}
+	if fmt.Sprint(tr.calls) != fmt.Sprint(expected) {
+		t.Errorf("Expected trace %v, got %v\n", expected, tr.calls)
+	}
+}
+
+func TestSearchStats(t *testing.T) {
+	if !SearchStatsEnabled() {
+		t.Skip("Build with -tags iskiplistsearchstats to test search stats")
+	}
+
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 1000; i++ {
+		sl.PushBack(i)
+	}
+
+	ResetSearchStats()
+	for i := 0; i < 1000; i++ {
+		sl.At(i)
+	}
+	sequential := SearchStats()
+	if sequential.Searches == 0 || sequential.CacheHits == 0 {
+		t.Errorf("Expected searches and cache hits to be recorded, got %+v\n", sequential)
+	}
+
+	ResetSearchStats()
+	for i := 999; i >= 0; i-- {
+		sl.At(i)
+	}
+	reverse := SearchStats()
+	t.Logf("Sequential: %+v (%v hops per search)\n", sequential, sequential.HopsPerSearch())
+	t.Logf("Reverse: %+v (%v hops per search)\n", reverse, reverse.HopsPerSearch())
+	if reverse.CacheHits >= sequential.CacheHits {
+		t.Errorf("Expected fewer cache hits for reverse access pattern\n")
+	}
+
+	// Telemetry counts the same costs for a single ISkipList.
+	sl.EnableTelemetry()
+	ResetSearchStats()
+	for i := 0; i < 1000; i += 7 {
+		sl.Insert(i, i)
+		sl.At(i / 2)
+	}
+	if s, tel := SearchStats(), sl.Telemetry(); s != tel.SearchCost {
+		t.Errorf("Search stats %+v differ from telemetry %+v\n", s, tel.SearchCost)
+	}
+}
+
+func TestDepthReport(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	if h := sl.LevelHistogram(); len(h) != 0 {
+		t.Errorf("Expected empty histogram, got %v\n", h)
+	}
+
+	for i := 0; i < 10000; i++ {
+		sl.Insert(i/2, i)
+	}
+	h := sl.LevelHistogram()
+	if h[0] != sl.Length() {
+		t.Errorf("Expected %v nodes on densest level, got %v\n", sl.Length(), h[0])
+	}
+	for i := 1; i < len(h); i++ {
+		if h[i] > h[i-1] {
+			t.Errorf("Level %v has more nodes than level %v: %v\n", i, i-1, h)
+		}
+	}
+
+	r := sl.DepthReport()
+	t.Logf("%v", r)
+	if r.Suspicious {
+		t.Errorf("Unexpected suspicious depth report:\n%v", r)
+	}
+
+	// Force every node onto as many levels as possible.
+	var bad ISkipList
+	bad.SetLevelSource(&cyclicLevelSource{values: []uint32{^uint32(0)}})
+	for i := 0; i < 100; i++ {
+		bad.PushBack(i)
+	}
+	if r := bad.DepthReport(); !r.Suspicious {
+		t.Errorf("Expected suspicious depth report:\n%v", r)
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	var sl ISkipList
+	var buf bytes.Buffer
+	if err := sl.StartRecording(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	ops := sliceutils.GenOps(1000, 0)
+	for i, o := range ops {
+		applyOpToISkipList(&o, &sl)
+		switch {
+		case i == 500:
+			sl.Reseed()
+		case i%100 == 0 && sl.Length() > 0:
+			sl.Update(sl.Length()/2, func(e ElemType) ElemType { return e + 1 })
+		case i%150 == 0:
+			sl.PushFront(7)
+		}
+	}
+	if err := sl.StopRecording(); err != nil {
+		t.Fatal(err)
+	}
+	recorded := sl.Copy()
+	sl.PushBack(1) // not recorded
+
+	path := filepath.Join(t.TempDir(), "recording")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("Recorded %v ops in %v bytes\n", len(ops), buf.Len())
+
+	replayed, err := ReplayFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !StructurallyEqual(recorded, replayed) {
+		t.Errorf("Replayed ISkipList differs from original:\n%v\n%v\n", DebugPrintISkipList(recorded, 3), DebugPrintISkipList(replayed, 3))
+	}
+
+	// Recording a non-empty ISkipList preserves its elements.
+	buf.Reset()
+	if err := sl.StartRecording(&buf); err != nil {
+		t.Fatal(err)
+	}
+	sl.Insert(sl.Length()/2, 12345)
+	sl.StopRecording()
+	replayed, err = Replay(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(toSlice(&sl)) != fmt.Sprint(toSlice(replayed)) {
+		t.Errorf("Replayed ISkipList has different elements from original\n")
+	}
+
+	if _, err := Replay(bytes.NewReader([]byte("nope"))); err == nil {
+		t.Errorf("Expected error replaying invalid recording\n")
+	}
+
+	var custom ISkipList
+	custom.SetLevelSource(&cyclicLevelSource{values: []uint32{0}})
+	if err := custom.StartRecording(&buf); err == nil {
+		t.Errorf("Expected error recording ISkipList with custom LevelSource\n")
+	}
+}
+
+func TestRecordAndReplayBulkOps(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	var buf bytes.Buffer
+	if err := sl.StartRecording(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// Some of these operations rebuild the ISkipList, so they come first in
+	// order not to mask differences in the structure left by the others.
+	for i := 0; i < 100; i++ {
+		sl.PushBack(distToElem(i % 7))
+	}
+	sl.RemoveValues([]ElemType{1, 2})
+	sl.Dedup()
+	sl.DeltaInPlace()
+	sl.SplitAt(sl.Length() / 2)
+	sl.Rebalance()
+
+	for i := 0; i < 10; i++ {
+		sl.ApplyOps(sliceutils.GenOps(50, sl.Length()))
+		sl.AppendSlice([]ElemType{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+		sl.PrependSlice([]ElemType{11, 12, 13})
+		sl.InsertSlice(sl.Length()/2, []ElemType{14, 14, 14, 15})
+		sl.SetRange(3, []ElemType{16, 17})
+		sl.RotateRange(5, sl.Length()-5, 7)
+		sl.MoveElement(2, sl.Length()-3)
+		sl.SwapRanges(0, sl.Length()/2, 4)
+		sl.ExtractRange(2, 5)
+	}
+	sl.PushBack(18)
+	if err := sl.StopRecording(); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed, err := Replay(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !StructurallyEqual(&sl, replayed) {
+		t.Errorf("Replayed ISkipList differs from original:\n%v\n%v\n", DebugPrintISkipList(&sl, 3), DebugPrintISkipList(replayed, 3))
+	}
+
+	// Operations that take a function or another ISkipList are recorded as
+	// their result.
+	buf.Reset()
+	if err := sl.StartRecording(&buf); err != nil {
+		t.Fatal(err)
+	}
+	sl.RemoveIf(func(e ElemType) bool { return e%2 == 0 })
+	sl.Sort(func(a, b ElemType) bool { return a < b })
+	sl.Append(FromSlice([]ElemType{19, 20, 21}))
+	for i := 0; i < 100; i++ {
+		sl.Insert(i, distToElem(i))
+	}
+	sl.StopRecording()
+	replayed, err = Replay(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(toSlice(&sl)) != fmt.Sprint(toSlice(replayed)) {
+		t.Errorf("Replayed ISkipList has different elements from original\n")
+	}
+}
+
+func TestReplayMalformed(t *testing.T) {
+	// A recording of an ISkipList with the specified maximum length and
+	// elements 1..length, followed by the specified operations.
+	recording := func(maxLength, length int64, ops ...[]byte) []byte {
+		b := []byte(recordingMagic)
+		b = append(b, recordingVersion)
+		b = binary.AppendUvarint(b, 1)
+		b = binary.AppendUvarint(b, 1)
+		b = binary.AppendVarint(b, maxLength)
+		b = binary.AppendVarint(b, length)
+		for i := int64(1); i <= length; i++ {
+			b = binary.AppendVarint(b, int64(elemToDist(ElemType(i))))
+		}
+		for _, o := range ops {
+			b = append(b, o...)
+		}
+		return b
+	}
+	op := func(code byte, args ...int64) []byte {
+		b := []byte{code}
+		for _, a := range args {
+			b = binary.AppendVarint(b, a)
+		}
+		return b
+	}
+
+	tests := []struct {
+		name   string
+		rec    []byte
+		length int // length of the ISkipList returned with the error, or -1
+	}{
+		{"negative max length in header", recording(-1, 0), -1},
+		{"negative length in header", recording(0, -1), -1},
+		{"negative SetMaxLength", recording(0, 3, op(recSetMaxLength, -2)), 3},
+		{"Set out of bounds", recording(0, 3, op(recSet, 3, 0)), 3},
+		{"Remove out of bounds", recording(0, 3, op(recRemove, -1)), 3},
+		{"Insert out of bounds", recording(0, 3, op(recPushBack, 4), op(recInsert, 5, 0)), 4},
+		{"Swap out of bounds", recording(0, 3, op(recSwap, 0, 3)), 3},
+		{"Truncate out of bounds", recording(0, 3, op(recTruncate, 4)), 3},
+		{"SetRange out of bounds", recording(0, 3, op(recSetRange, 2, 2, 0, 0)), 3},
+		{"negative slice length", recording(0, 3, op(recAppendSlice, -1)), 3},
+		{"reversed RotateRange", recording(0, 3, op(recRotateRange, 2, 1, 1)), 3},
+		{"overlapping SwapRanges", recording(0, 3, op(recSwapRanges, 0, 1, 2)), 3},
+		{"ApplyOps out of bounds", recording(0, 3, op(recApplyOps, 2, sliceutils.OpRemove, 0, sliceutils.OpSwap, 0, 2)), 3},
+		{"unrecognized ApplyOps kind", recording(0, 3, op(recApplyOps, 1, 99, 0)), 3},
+	}
+	for _, tc := range tests {
+		l, err := Replay(bytes.NewReader(tc.rec))
+		if err == nil {
+			t.Errorf("%v: expected error\n", tc.name)
+			continue
+		}
+		if tc.length == -1 {
+			if l != nil {
+				t.Errorf("%v: expected no ISkipList to be returned\n", tc.name)
+			}
+		} else if l == nil || l.Length() != tc.length {
+			t.Errorf("%v: expected ISkipList of length %v to be returned, got %+v\n", tc.name, tc.length, l)
+		}
+	}
+}
+
+func toSlice(l *ISkipList) []ElemType {
+	s := make([]ElemType, l.Length())
+	l.CopyToSlice(s)
+	return s
+}
+
+func TestDebugAssertions(t *testing.T) {
+	if !DebugAssertionsEnabled() {
+		t.Skip("Build with -tags iskiplistdebug to test internal assertions")
+	}
+
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 100; i++ {
+		sl.PushBack(i)
+	}
+	// Corrupt the distance stored in the root node.
+	sl.root.elem++
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Expected corrupted ISkipList to trigger an assertion\n")
+		}
+	}()
+	sl.PushBack(100)
+}
+
+func TestPeek(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	if _, ok := sl.PeekFront(); ok {
+		t.Errorf("Expected PeekFront to fail on empty ISkipList\n")
+	}
+	if _, ok := sl.PeekBack(); ok {
+		t.Errorf("Expected PeekBack to fail on empty ISkipList\n")
+	}
+
+	for i := 0; i < 100; i++ {
+		sl.PushBack(i)
+		if e, ok := sl.PeekFront(); !ok || e != 0 {
+			t.Errorf("Expected PeekFront to return 0, got %v %v\n", e, ok)
+		}
+		if e, ok := sl.PeekBack(); !ok || e != i {
+			t.Errorf("Expected PeekBack to return %v, got %v %v\n", i, e, ok)
+		}
+	}
+	if sl.Length() != 100 {
+		t.Errorf("Peeking changed length of ISkipList\n")
+	}
+}
+
+func TestStackAndQueue(t *testing.T) {
+	var s Stack
+	var q Queue
+	if _, ok := s.Pop(); ok {
+		t.Errorf("Expected Pop to fail on empty Stack\n")
+	}
+	if _, ok := q.Dequeue(); ok {
+		t.Errorf("Expected Dequeue to fail on empty Queue\n")
+	}
+
+	for i := 0; i < 100; i++ {
+		s.Push(i)
+		q.Enqueue(i)
+	}
+	if s.Length() != 100 || q.Length() != 100 {
+		t.Errorf("Unexpected lengths %v %v\n", s.Length(), q.Length())
+	}
+	if e, _ := s.Peek(); e != 99 {
+		t.Errorf("Expected top of Stack to be 99, got %v\n", e)
+	}
+	if e, _ := q.Peek(); e != 0 {
+		t.Errorf("Expected front of Queue to be 0, got %v\n", e)
+	}
+	for i := 0; i < 100; i++ {
+		if e, ok := s.Pop(); !ok || e != 99-i {
+			t.Errorf("Expected Pop to return %v, got %v %v\n", 99-i, e, ok)
+		}
+		if e, ok := q.Dequeue(); !ok || e != i {
+			t.Errorf("Expected Dequeue to return %v, got %v %v\n", i, e, ok)
+		}
+	}
+	if _, ok := s.Peek(); ok {
+		t.Errorf("Expected Peek to fail on empty Stack\n")
+	}
+}
+
+func TestSplitAndConcat(t *testing.T) {
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	for iter := 0; iter < 200; iter++ {
+		n := int(rand.Bounded(300))
+		index := int(rand.Bounded(uint32(n + 1)))
+
+		var sl ISkipList
+		sl.Seed(randSeed1, randSeed2+uint64(iter))
+		for i := 0; i < n; i++ {
+			sl.Insert(int(rand.Bounded(uint32(i+1))), i)
+		}
+		before := toSlice(&sl)
+
+		tail := splitAt(&sl, index)
+		if err := sl.Validate(); err != nil {
+			t.Fatalf("Invalid head after splitting %v at %v: %v\n", n, index, err)
+		}
+		if err := tail.Validate(); err != nil {
+			t.Fatalf("Invalid tail after splitting %v at %v: %v\n", n, index, err)
+		}
+		if fmt.Sprint(toSlice(&sl)) != fmt.Sprint(before[:index]) || fmt.Sprint(toSlice(tail)) != fmt.Sprint(before[index:]) {
+			t.Fatalf("Unexpected elements after splitting %v at %v\n", n, index)
+		}
+
+		concat(&sl, tail, &sl)
+		if err := sl.Validate(); err != nil {
+			t.Fatalf("Invalid ISkipList after concatenation: %v\n", err)
+		}
+		if tail.Length() != 0 {
+			t.Errorf("Concatenation did not empty other ISkipList\n")
+		}
+		if fmt.Sprint(toSlice(&sl)) != fmt.Sprint(before) {
+			t.Fatalf("Unexpected elements after concatenation\n")
+		}
+	}
+}
+
+func TestExtend(t *testing.T) {
+	var a, b ISkipList
+	a.Seed(randSeed1, randSeed2)
+	b.Seed(randSeed1, randSeed2+1)
+	a.EnableValueIndex()
+	for i := 0; i < 10; i++ {
+		a.PushBack(i)
+	}
+	for i := 10; i < 1000; i++ {
+		b.PushBack(i)
+	}
+
+	a.Extend(&b)
+	if err := a.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if b.Length() != 990 {
+		t.Errorf("Extend modified its argument\n")
+	}
+	for i := 0; i < 1000; i++ {
+		if a.At(i) != i {
+			t.Errorf("Expected %v at index %v, got %v\n", i, i, a.At(i))
+		}
+	}
+	if i, ok := a.PositionOf(500); !ok || i != 500 {
+		t.Errorf("Value index not updated by Extend\n")
+	}
+
+	a.Extend(&a)
+	if a.Length() != 2000 || a.At(1999) != 999 {
+		t.Errorf("Extending an ISkipList with itself failed\n")
+	}
+
+	a.SetMaxLength(100)
+	a.Extend(&b)
+	if a.Length() != 100 || a.At(99) != 999 {
+		t.Errorf("Extend did not respect maximum length\n")
+	}
+}
+
+// checkLevelStructure checks that the number of nodes on each level of an
+// ISkipList is consistent with the ISkipList having been built by PushBack().
+func checkLevelStructure(t *testing.T, what string, sl *ISkipList) {
+	t.Helper()
+	r := sl.DepthReport()
+	minLevels := int(math.Log(float64(sl.Length()))) - 2
+	if len(r.Levels) < minLevels || r.Suspicious {
+		t.Errorf("Unexpected level structure after %v:\n%v", what, r)
+	}
+}
+
+func TestSmallSplicesKeepLevels(t *testing.T) {
+	// Building an ISkipList by many small splices should give the same level
+	// structure as building it by PushBack().
+	const n = 20000
+	tests := []struct {
+		name string
+		add  func(sl *ISkipList, i int)
+	}{
+		{"Extend", func(sl *ISkipList, i int) {
+			var other ISkipList
+			other.PushBack(i)
+			sl.Extend(&other)
+		}},
+		{"PrependList", func(sl *ISkipList, i int) {
+			var other ISkipList
+			other.PushBack(i)
+			sl.PrependList(&other)
+		}},
+		{"AppendSlice", func(sl *ISkipList, i int) {
+			sl.AppendSlice([]ElemType{i})
+		}},
+		{"InsertSlice", func(sl *ISkipList, i int) {
+			sl.InsertSlice(i/2, []ElemType{i})
+		}},
+		{"PrependSlice", func(sl *ISkipList, i int) {
+			sl.PrependSlice([]ElemType{i})
+		}},
+		{"Append", func(sl *ISkipList, i int) {
+			var other ISkipList
+			other.PushBack(i)
+			sl.Append(&other)
+		}},
+		{"InsertListAt", func(sl *ISkipList, i int) {
+			var other ISkipList
+			other.PushBack(i)
+			sl.InsertListAt(i/2, &other)
+		}},
+		{"ApplyOps", func(sl *ISkipList, i int) {
+			// Runs of two insertions, each of which is coalesced.
+			j := sl.Length() / 2
+			sl.ApplyOps([]sliceutils.Op{
+				{Kind: sliceutils.OpInsert, Index1: j, Elem: i},
+				{Kind: sliceutils.OpInsert, Index1: j + 1, Elem: i},
+				{Kind: sliceutils.OpInsert, Index1: 0, Elem: i},
+				{Kind: sliceutils.OpInsert, Index1: 0, Elem: i},
+			})
+		}},
+	}
+	for _, tc := range tests {
+		var sl ISkipList
+		sl.Seed(randSeed1, randSeed2)
+		for i := 0; i < n; i++ {
+			tc.add(&sl, i)
+		}
+		if err := sl.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		checkLevelStructure(t, tc.name, &sl)
+	}
+}
+
+func TestSplitJoinKeepsLevels(t *testing.T) {
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 60000; i++ {
+		sl.PushBack(i)
+	}
+	n := sl.Length()
+	for i := 0; i < 20000; i++ {
+		a, b := int(rand.Bounded(uint32(n))), int(rand.Bounded(uint32(n)))
+		if i%2 == 0 {
+			if a > b {
+				a, b = b, a
+			}
+			sl.RotateRange(a, b, int(rand.Bounded(10)))
+		} else {
+			sl.MoveElement(a, b)
+		}
+	}
+	if err := sl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	checkLevelStructure(t, "RotateRange and MoveElement", &sl)
+}
+
+func TestPrependList(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	sl.EnableSummaryStats(nil)
+
+	// Build a list from single-element chunks in reverse order. This should
+	// not give a degenerate structure.
+	for i := 999; i >= 0; i-- {
+		var chunk ISkipList
+		chunk.PushBack(i)
+		sl.PrependList(&chunk)
+	}
+	if err := sl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		if sl.At(i) != i {
+			t.Errorf("Expected %v at index %v, got %v\n", i, i, sl.At(i))
+		}
+	}
+	if sl.Sum() != 999*1000/2 {
+		t.Errorf("Summary stats not updated by PrependList\n")
+	}
+	if r := sl.DepthReport(); r.Suspicious {
+		t.Errorf("Building by PrependList gave suspicious structure:\n%v", r)
+	}
+
+	sl.PrependList(&sl)
+	if sl.Length() != 2000 || sl.At(0) != 0 || sl.At(1000) != 0 || sl.At(1999) != 999 {
+		t.Errorf("Prepending an ISkipList to itself failed\n")
+	}
+}
+
+func TestValues(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 1000; i++ {
+		sl.PushBack(i)
+	}
+
+	i := 100
+	for v := range sl.Values(100, 900) {
+		if v != i {
+			t.Errorf("Expected %v, got %v\n", i, v)
+		}
+		i++
+	}
+	if i != 900 {
+		t.Errorf("Expected iteration to stop at 900, stopped at %v\n", i)
+	}
+
+	for v := range sl.Values(500, 1000) {
+		if v == 510 {
+			break
+		}
+	}
+	for range sl.Values(10, 5) {
+		t.Errorf("Expected empty range to yield nothing\n")
+	}
+
+	allocs := func(from, to int) float64 {
+		return testing.AllocsPerRun(10, func() {
+			for v := range sl.Values(from, to) {
+				_ = v
+			}
+		})
+	}
+	if a1, a2 := allocs(500, 501), allocs(500, 1000); a1 != a2 {
+		t.Errorf("Allocations depend on length of range (%v vs %v)\n", a1, a2)
+	}
+}
+
+func TestAllAndRange(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 100; i++ {
+		sl.PushBack(distToElem(i * 2))
+	}
+
+	n := 0
+	for v := range sl.All() {
+		if v != distToElem(n*2) {
+			t.Errorf("Expected %v, got %v\n", n*2, v)
+		}
+		n++
+	}
+	if n != 100 {
+		t.Errorf("Expected All to yield 100 elements, got %v\n", n)
+	}
+
+	n = 0
+	for i, v := range sl.AllI() {
+		if i != n || v != distToElem(i*2) {
+			t.Errorf("Unexpected pair (%v, %v) from AllI\n", i, v)
+		}
+		n++
+	}
+	if n != 100 {
+		t.Errorf("Expected AllI to yield 100 elements, got %v\n", n)
+	}
+
+	n = 0
+	for i, v := range sl.Range(40, 60) {
+		if i != 40+n || v != distToElem(i*2) {
+			t.Errorf("Unexpected pair (%v, %v) from Range\n", i, v)
+		}
+		if i == 49 {
+			break
+		}
+		n++
+	}
+	if n != 9 {
+		t.Errorf("Expected break from Range at index 49, stopped after %v elements\n", n)
+	}
+
+	var empty ISkipList
+	for range empty.All() {
+		t.Errorf("Expected empty ISkipList to yield nothing\n")
+	}
+}
+
+func TestExtractIf(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	sl.EnableValueIndex()
+	for i := 0; i < 1000; i++ {
+		sl.PushBack(i)
+	}
+
+	none := sl.ExtractIf(func(e ElemType) bool { return e < 0 })
+	if none.Length() != 0 || sl.Length() != 1000 {
+		t.Errorf("Expected nothing to be extracted\n")
+	}
+
+	odd := sl.ExtractIf(func(e ElemType) bool { return e%2 == 1 })
+	if err := sl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := odd.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if sl.Length() != 500 || odd.Length() != 500 {
+		t.Fatalf("Unexpected lengths %v %v\n", sl.Length(), odd.Length())
+	}
+	for i := 0; i < 500; i++ {
+		if sl.At(i) != 2*i || odd.At(i) != 2*i+1 {
+			t.Errorf("Unexpected elements at index %v: %v %v\n", i, sl.At(i), odd.At(i))
+		}
+	}
+	if i, ok := sl.PositionOf(600); !ok || i != 300 {
+		t.Errorf("Value index not updated by ExtractIf\n")
+	}
+	if _, ok := sl.PositionOf(601); ok {
+		t.Errorf("Value index not updated by ExtractIf\n")
+	}
+	if r := odd.DepthReport(); r.Suspicious {
+		t.Errorf("Extracted ISkipList has suspicious structure:\n%v", r)
+	}
+
+	all := sl.ExtractIf(func(e ElemType) bool { return true })
+	if sl.Length() != 0 || all.Length() != 500 {
+		t.Errorf("Unexpected lengths %v %v\n", sl.Length(), all.Length())
+	}
+	if err := sl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	sl.PushBack(1)
+	if sl.At(0) != 1 {
+		t.Errorf("ISkipList unusable after extracting all elements\n")
+	}
+}
+
+func TestSplitAtFunc(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 1000; i++ {
+		sl.PushBack(i)
+	}
+
+	head, tail := sl.SplitAtFunc(func(e ElemType) bool { return e >= 600 })
+	if head != &sl {
+		t.Errorf("Expected first return value to be the original ISkipList\n")
+	}
+	if err := tail.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if head.Length() != 600 || tail.Length() != 400 || tail.At(0) != 600 || head.At(599) != 599 {
+		t.Errorf("Unexpected split: lengths %v %v\n", head.Length(), tail.Length())
+	}
+
+	_, tail = sl.SplitAtFunc(func(e ElemType) bool { return e < 0 })
+	if sl.Length() != 600 || tail.Length() != 0 {
+		t.Errorf("Expected no split when nothing matches\n")
+	}
+
+	_, tail = sl.SplitAtFunc(func(e ElemType) bool { return true })
+	if sl.Length() != 0 || tail.Length() != 600 {
+		t.Errorf("Expected everything to be split off when first element matches\n")
+	}
+}
+
+func TestInsertListAt(t *testing.T) {
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	sl.EnableValueIndex()
+	var a []ElemType
+	next := 0
+	for iter := 0; iter < 100; iter++ {
+		var other ISkipList
+		n := int(rand.Bounded(50))
+		var chunk []ElemType
+		for i := 0; i < n; i++ {
+			other.PushBack(next)
+			chunk = append(chunk, next)
+			next++
+		}
+		index := int(rand.Bounded(uint32(len(a) + 1)))
+		sl.InsertListAt(index, &other)
+		a = append(a[:index], append(chunk, a[index:]...)...)
+
+		if other.Length() != 0 {
+			t.Fatalf("InsertListAt did not consume its argument\n")
+		}
+		if err := sl.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(toSlice(&sl)) != fmt.Sprint(a) {
+			t.Fatalf("Unexpected elements after inserting %v elements at %v\n", n, index)
+		}
+	}
+	if r := sl.DepthReport(); r.Suspicious {
+		t.Errorf("Suspicious structure after repeated InsertListAt:\n%v", r)
+	}
+}
+
+func TestCrossSwap(t *testing.T) {
+	var a, b ISkipList
+	a.Seed(randSeed1, randSeed2)
+	b.Seed(randSeed1, randSeed2+1)
+	b.EnableValueIndex()
+	for i := 0; i < 100; i++ {
+		a.PushBack(i)
+		b.PushBack(1000 + i)
+	}
+
+	a.CrossSwap(10, &b, 20)
+	if a.At(10) != 1020 || b.At(20) != 10 {
+		t.Errorf("CrossSwap failed: %v %v\n", a.At(10), b.At(20))
+	}
+	if i, ok := b.PositionOf(10); !ok || i != 20 {
+		t.Errorf("Value index not updated by CrossSwap\n")
+	}
+
+	a.CrossSwapRange(50, &b, 0, 10)
+	for k := 0; k < 10; k++ {
+		if a.At(50+k) != 1000+k || b.At(k) != 50+k {
+			t.Errorf("CrossSwapRange failed at offset %v: %v %v\n", k, a.At(50+k), b.At(k))
+		}
+	}
+	if err := b.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	a.EnableIDs()
+	id0, id90 := a.IDAt(0), a.IDAt(90)
+	a.CrossSwapRange(0, &a, 90, 5)
+	if a.At(0) != 90 || a.At(94) != 4 {
+		t.Errorf("CrossSwapRange within one ISkipList failed\n")
+	}
+	// As for Swap(), IDs follow their elements.
+	if i, ok := a.IndexOfID(id0); !ok || i != 90 {
+		t.Errorf("ID of element formerly at 0 has index %v, expected 90\n", i)
+	}
+	if i, ok := a.IndexOfID(id90); !ok || i != 0 {
+		t.Errorf("ID of element formerly at 90 has index %v, expected 0\n", i)
+	}
+	if err := a.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected panic for overlapping ranges\n")
+		}
+	}()
+	a.CrossSwapRange(0, &a, 3, 5)
+}
+
+func TestEqualRange(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	// 0, 0, 0, 2, 2, 2, 4, 4, 4, ...
+	for i := 0; i < 300; i++ {
+		sl.PushBack((i / 3) * 2)
+	}
+	less := func(a, b ElemType) bool { return a < b }
+
+	for v := -1; v <= 201; v++ {
+		first, last := sl.EqualRange(v, less)
+		expectedFirst := sort.Search(sl.Length(), func(i int) bool { return sl.At(i) >= v })
+		expectedLast := sort.Search(sl.Length(), func(i int) bool { return sl.At(i) > v })
+		if first != expectedFirst || last != expectedLast {
+			t.Errorf("EqualRange(%v) = (%v, %v), expected (%v, %v)\n", v, first, last, expectedFirst, expectedLast)
+		}
+	}
+
+	var empty ISkipList
+	if first, last := empty.EqualRange(1, less); first != 0 || last != 0 {
+		t.Errorf("Expected (0, 0) for empty ISkipList, got (%v, %v)\n", first, last)
+	}
+}
+
+func TestRotateRange(t *testing.T) {
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	sl.EnableValueIndex()
+	a := make([]ElemType, 200)
+	for i := range a {
+		a[i] = i
+		sl.PushBack(i)
+	}
+
+	for iter := 0; iter < 100; iter++ {
+		from := int(rand.Bounded(uint32(len(a) + 1)))
+		to := from + int(rand.Bounded(uint32(len(a)-from+1)))
+		k := int(rand.Bounded(1000)) - 500
+
+		sl.RotateRange(from, to, k)
+
+		if n := to - from; n > 0 {
+			r := ((k % n) + n) % n
+			rotated := append(append([]ElemType{}, a[from+r:to]...), a[from:from+r]...)
+			copy(a[from:to], rotated)
+		}
+
+		if err := sl.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(toSlice(&sl)) != fmt.Sprint(a) {
+			t.Fatalf("Unexpected elements after RotateRange(%v, %v, %v)\n", from, to, k)
+		}
+	}
+}
+
+func TestFillFunc(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	sl.EnableSummaryStats(nil)
+	for i := 0; i < 100; i++ {
+		sl.PushBack(0)
+	}
+
+	sl.FillFunc(10, 20, func(i int) ElemType { return i * 2 })
+	for i := 0; i < 100; i++ {
+		expected := 0
+		if i >= 10 && i < 20 {
+			expected = i * 2
+		}
+		if sl.At(i) != expected {
+			t.Errorf("Expected %v at index %v, got %v\n", expected, i, sl.At(i))
+		}
+	}
+	if sl.Sum() != 290 {
+		t.Errorf("Summary stats not updated by FillFunc (sum %v)\n", sl.Sum())
+	}
+
+	sl.FillFunc(20, 10, func(i int) ElemType { panic("unexpected call") })
+}
+
+func TestScan(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 1; i <= 1000; i++ {
+		sl.PushBack(i)
+	}
+
+	sums := sl.Scan(0, nil)
+	if err := sums.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if sums.Length() != 1000 {
+		t.Fatalf("Expected length 1000, got %v\n", sums.Length())
+	}
+	for i := 0; i < 1000; i++ {
+		if sums.At(i) != (i+1)*(i+2)/2 {
+			t.Errorf("Expected %v at index %v, got %v\n", (i+1)*(i+2)/2, i, sums.At(i))
+		}
+	}
+	if r := sums.DepthReport(); r.Suspicious {
+		t.Errorf("Suspicious structure:\n%v", r)
+	}
+
+	max := sl.Scan(500, func(acc, v ElemType) ElemType {
+		if v > acc {
+			return v
+		}
+		return acc
+	})
+	if max.At(0) != 500 || max.At(499) != 500 || max.At(500) != 501 {
+		t.Errorf("Unexpected running maximum\n")
+	}
+
+	var empty ISkipList
+	if empty.Scan(0, nil).Length() != 0 {
+		t.Errorf("Expected empty result\n")
+	}
+}
+
+func TestDelta(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 500; i++ {
+		sl.PushBack(i * i)
+	}
+
+	d := sl.Delta()
+	if err := d.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 500; i++ {
+		expected := i*i - (i-1)*(i-1)
+		if i == 0 {
+			expected = 0
+		}
+		if d.At(i) != expected {
+			t.Errorf("Expected %v at index %v, got %v\n", expected, i, d.At(i))
+		}
+	}
+
+	roundTrip := d.Scan(0, nil)
+	if fmt.Sprint(toSlice(roundTrip)) != fmt.Sprint(toSlice(&sl)) {
+		t.Errorf("Scan is not the inverse of Delta\n")
+	}
+
+	sl.EnableValueIndex()
+	sl.DeltaInPlace()
+	if fmt.Sprint(toSlice(&sl)) != fmt.Sprint(toSlice(d)) {
+		t.Errorf("DeltaInPlace gave different result from Delta\n")
+	}
+	if err := sl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMapToValues(t *testing.T) {
+	backing := []string{"a", "b", "c", "d"}
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for _, i := range []int{3, 1, 1, 0, 2} {
+		sl.PushBack(i)
+	}
+
+	if vals := MapToValues(&sl, backing); fmt.Sprint(vals) != "[d b b a c]" {
+		t.Errorf("Unexpected values %v\n", vals)
+	}
+	if vals := MapRangeToValues(&sl, 1, 4, backing); fmt.Sprint(vals) != "[b b a]" {
+		t.Errorf("Unexpected values %v\n", vals)
+	}
+	if vals := MapRangeToValues(&sl, 2, 2, backing); len(vals) != 0 {
+		t.Errorf("Expected empty result, got %v\n", vals)
+	}
+}
+
+func TestStore(t *testing.T) {
+	var s Store[string]
+	s.Seed(randSeed1, randSeed2)
+	for i := 0; i < 10; i++ {
+		s.PushBack(fmt.Sprint(i))
+	}
+	s.PushFront("first")
+	s.Insert(5, "middle")
+
+	if s.Length() != 12 || s.At(0) != "first" || s.At(5) != "middle" || s.At(11) != "9" {
+		t.Errorf("Unexpected contents %v\n", s.Values())
+	}
+
+	if v := s.Remove(5); v != "middle" {
+		t.Errorf("Expected to remove 'middle', got %v\n", v)
+	}
+	s.Remove(0)
+	backingLen := len(s.backing)
+	s.PushBack("a")
+	s.PushBack("b")
+	if len(s.backing) != backingLen {
+		t.Errorf("Freed slots were not reused (backing length %v, expected %v)\n", len(s.backing), backingLen)
+	}
+	s.PushBack("c")
+	if len(s.backing) != backingLen+1 {
+		t.Errorf("Expected backing slice to grow\n")
+	}
+
+	s.Swap(0, 1)
+	s.Set(2, "two")
+	if fmt.Sprint(s.Values()) != "[1 0 two 3 4 5 6 7 8 9 a b c]" {
+		t.Errorf("Unexpected contents %v\n", s.Values())
+	}
+
+	n := 0
+	s.Iterate(func(i int, v string) bool {
+		n++
+		return i < 4
+	})
+	if n != 5 {
+		t.Errorf("Expected Iterate to stop after 5 elements, visited %v\n", n)
+	}
+
+	s.Clear()
+	if s.Length() != 0 || len(s.backing) != 0 {
+		t.Errorf("Clear did not empty Store\n")
+	}
+}
+
+func TestStoreEviction(t *testing.T) {
+	var s Store[string]
+	s.Seed(randSeed1, randSeed2)
+	for i := 0; i < 10; i++ {
+		s.PushBack(fmt.Sprint(i))
+	}
+	s.List().SetMaxLength(5)
+	if fmt.Sprint(s.Values()) != "[5 6 7 8 9]" {
+		t.Errorf("Unexpected contents %v\n", s.Values())
+	}
+
+	// The slots of the five elements evicted by SetMaxLength should be reused.
+	for i := 10; i < 15; i++ {
+		s.PushBack(fmt.Sprint(i))
+	}
+	if len(s.backing) != 10 {
+		t.Errorf("Evicted slots were not reused (backing length %v, expected 10)\n", len(s.backing))
+	}
+
+	// Each insertion now evicts an element, freeing a slot for the next one.
+	for i := 15; i < 100; i++ {
+		s.PushBack(fmt.Sprint(i))
+		s.PushFront(fmt.Sprint(-i))
+	}
+	if len(s.backing) != 10 {
+		t.Errorf("Evicted slots were not reused (backing length %v, expected 10)\n", len(s.backing))
+	}
+	if fmt.Sprint(s.Values()) != "[-99 11 12 13 14]" {
+		t.Errorf("Unexpected contents %v\n", s.Values())
+	}
+}
+
+func TestElemIDs(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 100; i++ {
+		sl.PushBack(distToElem(i))
+	}
+	sl.EnableIDs()
+
+	// All values are unique, so we can record the ID of each value.
+	ids := make(map[ElemType]ElemID)
+	sl.ForAllI(func(i int, e *ElemType) {
+		ids[*e] = sl.IDAt(i)
+	})
+	check := func() {
+		if err := sl.Validate(); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		sl.ForAllI(func(i int, e *ElemType) {
+			id, ok := ids[*e]
+			if !ok {
+				id = sl.IDAt(i)
+				ids[*e] = id
+			}
+			if sl.IDAt(i) != id {
+				t.Errorf("Element %v at index %v has ID %v, expected %v\n", *e, i, sl.IDAt(i), id)
+			}
+			if j, ok := sl.IndexOfID(id); !ok || j != i {
+				t.Errorf("IndexOfID(%v) returned (%v, %v), expected %v\n", id, j, ok, i)
+			}
+		})
+	}
+
+	removedID := sl.IDAt(10)
+	sl.Remove(10)
+	if _, ok := sl.IndexOfID(removedID); ok {
+		t.Errorf("IndexOfID found a removed element\n")
+	}
+	check()
+	sl.Insert(5, distToElem(1000))
+	sl.PushFront(distToElem(1001))
+	check()
+	sl.Swap(3, 70)
+	check()
+	sl.RotateRange(20, 60, 7)
+	check()
+	var other ISkipList
+	for i := 2000; i < 2010; i++ {
+		other.PushBack(distToElem(i))
+	}
+	sl.InsertListAt(30, &other)
+	check()
+	sl.ExtractIf(func(e ElemType) bool { return elemToDist(e)%3 == 0 })
+	check()
+	sl.Set(0, distToElem(3000))
+	ids[distToElem(3000)] = ids[distToElem(1001)]
+	check()
+
+	seen := make(map[ElemID]bool)
+	sl.ForAllI(func(i int, e *ElemType) {
+		if seen[sl.IDAt(i)] {
+			t.Errorf("Duplicate ID %v\n", sl.IDAt(i))
+		}
+		seen[sl.IDAt(i)] = true
+	})
+
+	sl.EnableValueIndex()
+	sl.DisableValueIndex()
+	check()
+	sl.DisableIDs()
+	if sl.HasIDs() {
+		t.Errorf("IDs still enabled after DisableIDs\n")
+	}
+}
+
+func TestApplyOps(t *testing.T) {
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+
+	for iter := 0; iter < 200; iter++ {
+		// Generate runs of each kind that ApplyOps coalesces, interspersed
+		// with random ops. The ops are generated against a slice so that
+		// they're all valid.
+		a := make([]ElemType, 0)
+		for i := 0; i < 50; i++ {
+			a = append(a, distToElem(i))
+		}
+		var ops []sliceutils.Op
+		add := func(o sliceutils.Op) {
+			sliceutils.ApplyOpToSlice(&o, &a)
+			ops = append(ops, o)
+		}
+		for len(ops) < 200 {
+			k := int(rand.Bounded(10)) + 1
+			switch rand.Bounded(5) {
+			case 0:
+				i := int(rand.Bounded(uint32(len(a) + 1)))
+				for j := 0; j < k; j++ {
+					add(sliceutils.Op{Kind: sliceutils.OpInsert, Index1: i + j, Elem: distToElem(len(ops))})
+				}
+			case 1:
+				i := int(rand.Bounded(uint32(len(a) + 1)))
+				for j := 0; j < k; j++ {
+					add(sliceutils.Op{Kind: sliceutils.OpInsert, Index1: i, Elem: distToElem(len(ops))})
+				}
+			case 2:
+				i := int(rand.Bounded(uint32(len(a) + 1)))
+				for j := 0; j < k && i < len(a); j++ {
+					add(sliceutils.Op{Kind: sliceutils.OpRemove, Index1: i})
+				}
+			case 3:
+				i := int(rand.Bounded(uint32(len(a) + 1)))
+				for j := 0; j < k && i-j-1 >= 0; j++ {
+					add(sliceutils.Op{Kind: sliceutils.OpRemove, Index1: i - j - 1})
+				}
+			case 4:
+				if len(a) > 0 {
+					add(sliceutils.Op{Kind: sliceutils.OpSwap, Index1: int(rand.Bounded(uint32(len(a)))), Index2: int(rand.Bounded(uint32(len(a))))})
+				}
+			}
+		}
+
+		var sl ISkipList
+		sl.Seed(randSeed1, uint64(randSeed2+iter))
+		for i := 0; i < 50; i++ {
+			sl.PushBack(distToElem(i))
+		}
+		if iter%2 == 0 {
+			sl.EnableValueIndex()
+		}
+		sl.ApplyOps(ops)
+
+		if err := sl.Validate(); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		if fmt.Sprint(toSlice(&sl)) != fmt.Sprint(a) {
+			t.Fatalf("ApplyOps gave %v, expected %v\n", toSlice(&sl), a)
+		}
+	}
+
+	// Invalid ops panic in the same way as the corresponding methods.
+	var sl ISkipList
+	sl.PushBack(1)
+	sl.PushBack(2)
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected ApplyOps to panic\n")
+			}
+		}()
+		sl.ApplyOps([]sliceutils.Op{{Kind: sliceutils.OpRemove, Index1: 0}, {Kind: sliceutils.OpRemove, Index1: 0}, {Kind: sliceutils.OpRemove, Index1: 0}})
+	}()
+	if sl.Length() != 0 {
+		t.Errorf("Expected valid ops preceding the invalid op to be applied\n")
+	}
+}
+
+func TestRemoveValues(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 100; i++ {
+		sl.PushBack(distToElem(i % 10))
+	}
+	sl.EnableIDs()
+
+	if n := sl.RemoveValues([]ElemType{3, 7, 42}); n != 20 {
+		t.Errorf("Expected RemoveValues to remove 20 elements, removed %v\n", n)
+	}
+	if n := sl.RemoveValues(nil); n != 0 {
+		t.Errorf("Expected RemoveValues(nil) to remove nothing, removed %v\n", n)
+	}
+	if err := sl.Validate(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if sl.Length() != 80 || fmt.Sprint(toSlice(&sl)[:8]) != "[0 1 2 4 5 6 8 9]" {
+		t.Errorf("Unexpected result of RemoveValues: %v\n", toSlice(&sl))
+	}
+
+	var other ISkipList
+	other.PushBack(0)
+	other.PushBack(9)
+	other.PushBack(0)
+	if n := sl.RemoveValuesIn(&other); n != 20 {
+		t.Errorf("Expected RemoveValuesIn to remove 20 elements, removed %v\n", n)
+	}
+	if other.Length() != 3 {
+		t.Errorf("RemoveValuesIn modified its argument\n")
+	}
+	if fmt.Sprint(toSlice(&sl)[:5]) != "[1 2 4 5 6]" {
+		t.Errorf("Unexpected result of RemoveValuesIn: %v\n", toSlice(&sl))
+	}
+
+	if n := sl.RemoveValuesIn(&sl); n != 60 || sl.Length() != 0 {
+		t.Errorf("Expected RemoveValuesIn to remove all elements, removed %v\n", n)
+	}
+	if err := sl.Validate(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+}
+
+func TestStartsEndsWithAndSubsequence(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 100; i++ {
+		sl.PushBack(distToElem(i))
+	}
+	fromSlice := func(s []ElemType) *ISkipList {
+		var o ISkipList
+		for _, v := range s {
+			o.PushBack(v)
+		}
+		return &o
+	}
+
+	tests := []struct {
+		s                                  []ElemType
+		startsWith, endsWith, containsSubs bool
+	}{
+		{nil, true, true, true},
+		{[]ElemType{0, 1, 2}, true, false, true},
+		{[]ElemType{0, 2}, false, false, true},
+		{[]ElemType{97, 98, 99}, false, true, true},
+		{[]ElemType{5, 50, 99}, false, false, true},
+		{[]ElemType{50, 5}, false, false, false},
+		{[]ElemType{99, 100}, false, false, false},
+		{toSlice(&sl), true, true, true},
+		{append(toSlice(&sl), 100), false, false, false},
+	}
+	for _, test := range tests {
+		o := fromSlice(test.s)
+		if sl.StartsWith(test.s) != test.startsWith || sl.StartsWithList(o) != test.startsWith {
+			t.Errorf("Unexpected StartsWith result for %v\n", test.s)
+		}
+		if sl.EndsWith(test.s) != test.endsWith || sl.EndsWithList(o) != test.endsWith {
+			t.Errorf("Unexpected EndsWith result for %v\n", test.s)
+		}
+		if sl.ContainsSubsequence(test.s) != test.containsSubs || sl.ContainsSubsequenceList(o) != test.containsSubs {
+			t.Errorf("Unexpected ContainsSubsequence result for %v\n", test.s)
+		}
+	}
+
+	var empty ISkipList
+	if !empty.StartsWith(nil) || empty.EndsWith([]ElemType{1}) || !empty.ContainsSubsequenceList(&empty) {
+		t.Errorf("Unexpected results for empty ISkipList\n")
+	}
+}
+
+func TestLCS(t *testing.T) {
+	lcsLength := func(x, y []ElemType) int {
+		dp := make([][]int, len(x)+1)
+		for i := range dp {
+			dp[i] = make([]int, len(y)+1)
+		}
+		for i := len(x) - 1; i >= 0; i-- {
+			for j := len(y) - 1; j >= 0; j-- {
+				if x[i] == y[j] {
+					dp[i][j] = dp[i+1][j+1] + 1
+				} else if dp[i+1][j] > dp[i][j+1] {
+					dp[i][j] = dp[i+1][j]
+				} else {
+					dp[i][j] = dp[i][j+1]
+				}
+			}
+		}
+		return dp[0][0]
+	}
+
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	for iter := 0; iter < 500; iter++ {
+		var a, b ISkipList
+		a.Seed(randSeed1, randSeed2)
+		b.Seed(randSeed1, randSeed2)
+		alphabet := rand.Bounded(6) + 1
+		for i := rand.Bounded(40); i > 0; i-- {
+			a.PushBack(distToElem(int(rand.Bounded(alphabet))))
+		}
+		for i := rand.Bounded(40); i > 0; i-- {
+			b.PushBack(distToElem(int(rand.Bounded(alphabet))))
+		}
+
+		lcs := LCS(&a, &b)
+		if err := lcs.Validate(); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		if expected := lcsLength(toSlice(&a), toSlice(&b)); lcs.Length() != expected {
+			t.Fatalf("LCS of %v and %v has length %v, expected %v\n", toSlice(&a), toSlice(&b), lcs.Length(), expected)
+		}
+		if !a.ContainsSubsequenceList(lcs) || !b.ContainsSubsequenceList(lcs) {
+			t.Fatalf("%v is not a common subsequence of %v and %v\n", toSlice(lcs), toSlice(&a), toSlice(&b))
+		}
+	}
+
+	var a ISkipList
+	for i := 0; i < 1000; i++ {
+		a.PushBack(distToElem(i))
+	}
+	b := a.Copy()
+	b.Remove(500)
+	b.Insert(10, -1)
+	if lcs := LCS(&a, b); lcs.Length() != 999 {
+		t.Errorf("Expected LCS of length 999, got %v\n", lcs.Length())
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	levenshtein := func(x, y []ElemType) int {
+		prev := make([]int, len(y)+1)
+		cur := make([]int, len(y)+1)
+		for j := range prev {
+			prev[j] = j
+		}
+		for i := 1; i <= len(x); i++ {
+			cur[0] = i
+			for j := 1; j <= len(y); j++ {
+				d := prev[j-1]
+				if x[i-1] != y[j-1] {
+					d++
+				}
+				if prev[j]+1 < d {
+					d = prev[j] + 1
+				}
+				if cur[j-1]+1 < d {
+					d = cur[j-1] + 1
+				}
+				cur[j] = d
+			}
+			prev, cur = cur, prev
+		}
+		return prev[len(y)]
+	}
+
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	for iter := 0; iter < 1000; iter++ {
+		var a, b ISkipList
+		alphabet := rand.Bounded(4) + 1
+		for i := rand.Bounded(30); i > 0; i-- {
+			a.PushBack(distToElem(int(rand.Bounded(alphabet))))
+		}
+		for i := rand.Bounded(30); i > 0; i-- {
+			b.PushBack(distToElem(int(rand.Bounded(alphabet))))
+		}
+
+		expected := levenshtein(toSlice(&a), toSlice(&b))
+		if d := EditDistance(&a, &b, -1); d != expected {
+			t.Fatalf("EditDistance(%v, %v) = %v, expected %v\n", toSlice(&a), toSlice(&b), d, expected)
+		}
+		maxDist := int(rand.Bounded(20))
+		capped := expected
+		if capped > maxDist {
+			capped = maxDist + 1
+		}
+		if d := EditDistance(&a, &b, maxDist); d != capped {
+			t.Fatalf("EditDistance(%v, %v, %v) = %v, expected %v\n", toSlice(&a), toSlice(&b), maxDist, d, capped)
+		}
+	}
+}
+
+func TestRangeEquals(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 100; i++ {
+		sl.PushBack(distToElem(i % 10))
+	}
+
+	if !sl.RangeEqualsSlice(13, []ElemType{3, 4, 5}) || !sl.RangeEqualsSlice(100, nil) {
+		t.Errorf("Expected RangeEqualsSlice to return true\n")
+	}
+	if sl.RangeEqualsSlice(13, []ElemType{3, 4, 6}) || sl.RangeEqualsSlice(98, []ElemType{8, 9, 0}) {
+		t.Errorf("Expected RangeEqualsSlice to return false\n")
+	}
+
+	if !sl.RangeEquals(5, &sl, 25, 70) || !sl.RangeEquals(0, &sl, 0, 100) || !sl.RangeEquals(100, &sl, 50, 0) {
+		t.Errorf("Expected RangeEquals to return true\n")
+	}
+	if sl.RangeEquals(5, &sl, 26, 10) || sl.RangeEquals(50, &sl, 0, 51) {
+		t.Errorf("Expected RangeEquals to return false\n")
+	}
+
+	var other ISkipList
+	for i := 0; i < 5; i++ {
+		other.PushBack(distToElem(i + 7))
+	}
+	if !sl.RangeEquals(17, &other, 0, 3) || sl.RangeEquals(17, &other, 0, 4) {
+		t.Errorf("Unexpected result of RangeEquals with another ISkipList\n")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected RangeEqualsSlice to panic on an out of bounds index\n")
+		}
+	}()
+	sl.RangeEqualsSlice(101, nil)
+}
+
+func TestRuns(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for _, v := range []ElemType{1, 1, 1, 2, 3, 3, 1, 1} {
+		sl.PushBack(v)
+	}
+
+	runs := sl.EncodeRuns()
+	if fmt.Sprint(runs) != "[{1 3} {2 1} {3 2} {1 2}]" {
+		t.Errorf("Unexpected runs %v\n", runs)
+	}
+
+	l := FromRuns(append(runs, Run{Value: 5, Count: 0}, Run{Value: 1, Count: 1}))
+	if err := l.Validate(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if fmt.Sprint(toSlice(l)) != "[1 1 1 2 3 3 1 1 1]" {
+		t.Errorf("Unexpected result of FromRuns: %v\n", toSlice(l))
+	}
+
+	var empty ISkipList
+	if len(empty.EncodeRuns()) != 0 || FromRuns(nil).Length() != 0 {
+		t.Errorf("Unexpected results for empty ISkipList\n")
+	}
+
+	big := FromRuns([]Run{{Value: 7, Count: 10000}})
+	if big.Length() != 10000 || len(big.EncodeRuns()) != 1 {
+		t.Errorf("Unexpected result of FromRuns for a long run\n")
+	}
+	if err := big.Validate(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+}
+
+func TestIndexOfSubsequence(t *testing.T) {
+	naive := func(a, p []ElemType) int {
+	outer:
+		for i := 0; i+len(p) <= len(a); i++ {
+			for j := range p {
+				if a[i+j] != p[j] {
+					continue outer
+				}
+			}
+			return i
+		}
+		return -1
+	}
+
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	for iter := 0; iter < 1000; iter++ {
+		var sl ISkipList
+		for i := rand.Bounded(60); i > 0; i-- {
+			sl.PushBack(distToElem(int(rand.Bounded(3))))
+		}
+		pattern := make([]ElemType, rand.Bounded(6))
+		for i := range pattern {
+			pattern[i] = distToElem(int(rand.Bounded(3)))
+		}
+		if i, expected := sl.IndexOfSubsequence(pattern), naive(toSlice(&sl), pattern); i != expected {
+			t.Fatalf("IndexOfSubsequence(%v) in %v returned %v, expected %v\n", pattern, toSlice(&sl), i, expected)
+		}
+	}
+}
+
+func TestReplaceSubsequence(t *testing.T) {
+	fromSlice := func(s []ElemType) *ISkipList {
+		var l ISkipList
+		l.Seed(randSeed1, randSeed2)
+		for _, v := range s {
+			l.PushBack(v)
+		}
+		return &l
+	}
+
+	tests := []struct {
+		list, pattern, repl []ElemType
+		maxN, n             int
+		expected            string
+	}{
+		{[]ElemType{1, 2, 3, 1, 2, 3}, []ElemType{1, 2}, []ElemType{9}, -1, 2, "[9 3 9 3]"},
+		{[]ElemType{1, 2, 3, 1, 2, 3}, []ElemType{1, 2}, []ElemType{9}, 1, 1, "[9 3 1 2 3]"},
+		{[]ElemType{1, 1, 1, 1, 1}, []ElemType{1, 1}, []ElemType{1}, -1, 2, "[1 1 1]"},
+		{[]ElemType{1, 2, 3}, []ElemType{2}, nil, -1, 1, "[1 3]"},
+		{[]ElemType{1, 2, 3}, []ElemType{2}, []ElemType{2, 2, 2}, -1, 1, "[1 2 2 2 3]"},
+		{[]ElemType{1, 2, 3}, []ElemType{4}, []ElemType{5}, -1, 0, "[1 2 3]"},
+		{[]ElemType{1, 2, 3}, nil, []ElemType{5}, -1, 0, "[1 2 3]"},
+		{[]ElemType{1, 2, 3}, []ElemType{1, 2, 3}, []ElemType{4, 5}, 0, 0, "[1 2 3]"},
+		{[]ElemType{1, 2, 3}, []ElemType{1, 2, 3}, []ElemType{4, 5}, -1, 1, "[4 5]"},
+	}
+	for _, test := range tests {
+		l := fromSlice(test.list)
+		l.EnableValueIndex()
+		n := l.ReplaceSubsequence(test.pattern, test.repl, test.maxN)
+		if err := l.Validate(); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		if n != test.n || fmt.Sprint(toSlice(l)) != test.expected {
+			t.Errorf("Replacing %v with %v in %v gave %v (%v replacements), expected %v (%v replacements)\n", test.pattern, test.repl, test.list, toSlice(l), n, test.expected, test.n)
+		}
+	}
+
+	l := fromSlice([]ElemType{1, 2, 1, 2, 1, 2})
+	l.SetMaxLength(7)
+	if n := l.ReplaceSubsequence([]ElemType{1}, []ElemType{3, 3}, -1); n != 3 {
+		t.Errorf("Expected 3 replacements with a maximum length, got %v\n", n)
+	}
+	if err := l.Validate(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+}
+
+func TestFrequencies(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 100; i++ {
+		sl.PushBack(distToElem(i % 7))
+	}
+	sl.PushBack(100)
+
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			sl.EnableValueIndex()
+		}
+		if n := sl.DistinctCount(); n != 8 {
+			t.Errorf("Expected 8 distinct values, got %v (indexed: %v)\n", n, indexed)
+		}
+		freqs := sl.Frequencies()
+		if len(freqs) != 8 || freqs[0] != 15 || freqs[6] != 14 || freqs[100] != 1 {
+			t.Errorf("Unexpected frequencies %v (indexed: %v)\n", freqs, indexed)
+		}
+	}
+
+	var empty ISkipList
+	if empty.DistinctCount() != 0 || len(empty.Frequencies()) != 0 {
+		t.Errorf("Unexpected results for empty ISkipList\n")
+	}
+}
+
+func TestMode(t *testing.T) {
+	tests := []struct {
+		elems []ElemType
+		mode  ElemType
+		count int
+	}{
+		{nil, 0, 0},
+		{[]ElemType{5}, 5, 1},
+		{[]ElemType{1, 2, 1, 3, 1}, 1, 3},
+		{[]ElemType{1, 2, 2, 1}, 1, 2},
+		{[]ElemType{3, 1, 2, 2, 1, 4}, 1, 2},
+		{[]ElemType{4, 4, 1, 2, 3, 4, 5, 6, 4, 7}, 4, 4},
+	}
+	for _, test := range tests {
+		var sl ISkipList
+		sl.Seed(randSeed1, randSeed2)
+		for _, e := range test.elems {
+			sl.PushBack(e)
+		}
+		if mode, count := sl.Mode(); mode != test.mode || count != test.count {
+			t.Errorf("Mode of %v returned (%v, %v), expected (%v, %v)\n", test.elems, mode, count, test.mode, test.count)
+		}
+	}
+}
+
+func TestMinMax(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	if _, _, ok := sl.MinMax(); ok {
+		t.Errorf("Expected MinMax of empty ISkipList to return false\n")
+	}
+
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	min, max := math.MaxInt64, math.MinInt64
+	for i := 0; i < 101; i++ {
+		v := int(rand.Bounded(10000)) - 5000
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+		sl.PushBack(distToElem(v))
+		if mn, mx, ok := sl.MinMax(); !ok || mn != distToElem(min) || mx != distToElem(max) {
+			t.Fatalf("MinMax returned (%v, %v, %v), expected (%v, %v, true)\n", mn, mx, ok, min, max)
+		}
+	}
+}
+
+func TestQuantiles(t *testing.T) {
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	qs := []float64{0.5, 0, 1, 0.99, 0.25, 0.9, 0.5, 0.01}
+	for iter := 0; iter < 200; iter++ {
+		var sl ISkipList
+		n := int(rand.Bounded(300)) + 1
+		for i := 0; i < n; i++ {
+			sl.PushBack(distToElem(int(rand.Bounded(uint32(iter + 1)))))
+		}
+		sorted := toSlice(&sl)
+		sort.Ints(sorted)
+
+		result := sl.Quantiles(qs, nil)
+		for i, q := range qs {
+			r := int(math.Ceil(q*float64(n))) - 1
+			if r < 0 {
+				r = 0
+			}
+			if result[i] != sorted[r] {
+				t.Fatalf("Quantile %v of %v is %v, expected %v\n", q, sorted, result[i], sorted[r])
+			}
+		}
+
+		desc := sl.Quantiles([]float64{0, 1}, func(a, b ElemType) bool { return a > b })
+		if desc[0] != sorted[n-1] || desc[1] != sorted[0] {
+			t.Fatalf("Unexpected quantiles with custom ordering: %v\n", desc)
+		}
+	}
+
+	var empty ISkipList
+	if empty.Quantiles(qs, nil) != nil {
+		t.Errorf("Expected nil quantiles for empty ISkipList\n")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected Quantiles to panic for a quantile out of range\n")
+		}
+	}()
+	empty.Quantiles([]float64{1.5}, nil)
+}
+
+func TestSplitInto(t *testing.T) {
+	for _, n := range []int{0, 1, 10, 100, 1001} {
+		for _, k := range []int{1, 2, 3, 7, 16} {
+			var sl ISkipList
+			sl.Seed(randSeed1, randSeed2)
+			for i := 0; i < n; i++ {
+				sl.PushBack(distToElem(i))
+			}
+
+			pieces := sl.SplitInto(k)
+			if len(pieces) != k || pieces[0] != &sl {
+				t.Fatalf("Unexpected pieces for n = %v, k = %v\n", n, k)
+			}
+			next := 0
+			for i, p := range pieces {
+				if err := p.Validate(); err != nil {
+					t.Fatalf("%v\n", err)
+				}
+				expected := n / k
+				if i < n%k {
+					expected++
+				}
+				if p.Length() != expected {
+					t.Errorf("Piece %v of %v has length %v for n = %v, expected %v\n", i, k, p.Length(), n, expected)
+				}
+				p.ForAll(func(e *ElemType) {
+					if *e != distToElem(next) {
+						t.Errorf("Expected element %v, got %v\n", next, *e)
+					}
+					next++
+				})
+			}
+			if next != n {
+				t.Errorf("Pieces contain %v elements, expected %v\n", next, n)
+			}
+		}
+	}
+}
+
+func TestScatter(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 10; i++ {
+		sl.PushBack(distToElem(i))
+	}
+
+	lists := sl.Scatter(3)
+	if len(lists) != 3 || fmt.Sprint(toSlice(lists[0]), toSlice(lists[1]), toSlice(lists[2])) != "[0 3 6 9] [1 4 7] [2 5 8]" {
+		t.Errorf("Unexpected result of Scatter\n")
+	}
+	if sl.Length() != 10 {
+		t.Errorf("Scatter modified the ISkipList\n")
+	}
+
+	lists = sl.ScatterFunc(2, func(i int, e ElemType) int { return elemToDist(e) / 5 })
+	if fmt.Sprint(toSlice(lists[0]), toSlice(lists[1])) != "[0 1 2 3 4] [5 6 7 8 9]" {
+		t.Errorf("Unexpected result of ScatterFunc\n")
+	}
+	for _, l := range lists {
+		if err := l.Validate(); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected ScatterFunc to panic for an out of range classification\n")
+		}
+	}()
+	sl.ScatterFunc(2, func(i int, e ElemType) int { return 2 })
+}
+
+func TestCapacity(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	sl.SetCapacity(5)
+	if sl.Capacity() != 5 {
+		t.Errorf("Expected capacity 5, got %v\n", sl.Capacity())
+	}
+	for i := 0; i < 5; i++ {
+		if err := sl.TryPushBack(distToElem(i)); err != nil {
+			t.Fatalf("Unexpected error %v\n", err)
+		}
+	}
+	if err := sl.TryPushBack(5); err != ErrCapacityExceeded {
+		t.Errorf("Expected ErrCapacityExceeded from TryPushBack, got %v\n", err)
+	}
+	if err := sl.TryPushFront(5); err != ErrCapacityExceeded {
+		t.Errorf("Expected ErrCapacityExceeded from TryPushFront, got %v\n", err)
+	}
+	if err := sl.TryInsert(2, 5); err != ErrCapacityExceeded {
+		t.Errorf("Expected ErrCapacityExceeded from TryInsert, got %v\n", err)
+	}
+
+	expectPanic := func(name string, f func()) {
+		defer func() {
+			if r := recover(); r != ErrCapacityExceeded {
+				t.Errorf("Expected %v to panic with ErrCapacityExceeded, got %v\n", name, r)
+			}
+		}()
+		f()
+	}
+	other := sl.Copy()
+	expectPanic("PushBack", func() { sl.PushBack(5) })
+	expectPanic("Insert", func() { sl.Insert(0, 5) })
+	expectPanic("Extend", func() { sl.Extend(other) })
+	expectPanic("InsertListAt", func() { sl.InsertListAt(1, other) })
+	expectPanic("ReplaceSubsequence", func() { sl.ReplaceSubsequence([]ElemType{1}, []ElemType{1, 1}, -1) })
+	if fmt.Sprint(toSlice(&sl)) != "[0 1 2 3 4]" || other.Length() != 5 {
+		t.Errorf("ISkipList modified by operation exceeding capacity: %v\n", toSlice(&sl))
+	}
+
+	// Operations that don't increase the length are fine.
+	sl.ReplaceSubsequence([]ElemType{1}, []ElemType{9}, -1)
+	sl.Remove(0)
+	sl.PushBack(5)
+
+	// Evictions are taken into account.
+	sl.SetMaxLength(5)
+	sl.PushBack(6)
+	sl.SetMaxLength(0)
+	expectPanic("PushBack", func() { sl.PushBack(7) })
+
+	sl.SetCapacity(0)
+	sl.PushBack(7)
+	if sl.Length() != 6 {
+		t.Errorf("Expected length 6 after removing capacity, got %v\n", sl.Length())
+	}
+}
+
+func TestReverseIteration(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 3, 4, 10, 99, 100, 101, 1000} {
+		var sl ISkipList
+		sl.Seed(randSeed1, randSeed2)
+		for i := 0; i < n; i++ {
+			sl.PushBack(distToElem(i))
+		}
+
+		expected := n - 1
+		sl.ForAllReverseI(func(i int, e *ElemType) {
+			if i != expected || *e != distToElem(i) {
+				t.Errorf("Unexpected pair (%v, %v), expected index %v\n", i, *e, expected)
+			}
+			expected--
+		})
+		if expected != -1 {
+			t.Errorf("ForAllReverseI stopped at %v for n = %v\n", expected, n)
+		}
+
+		if n < 10 {
+			continue
+		}
+		var visited []ElemType
+		sl.IterateRangeReverse(3, n-2, func(e *ElemType) bool {
+			visited = append(visited, *e)
+			return len(visited) < n/2
+		})
+		if len(visited) != n/2 || visited[0] != distToElem(n-3) || visited[len(visited)-1] != distToElem(n-2-n/2) {
+			t.Errorf("Unexpected elements visited by IterateRangeReverse for n = %v: %v\n", n, visited)
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	a := make([]ElemType, 0)
+	for i := 0; i < 200; i++ {
+		sl.PushBack(distToElem(i))
+		a = append(a, distToElem(i))
+	}
+	sl.EnableValueIndex()
+
+	c := sl.Cursor(0)
+	for iter := 0; iter < 5000; iter++ {
+		switch rand.Bounded(7) {
+		case 0:
+			c.Seek(int(rand.Bounded(uint32(len(a) + 1))))
+		case 1, 2:
+			c.Next()
+		case 3:
+			c.Prev()
+		case 4:
+			v := distToElem(1000 + iter)
+			a = append(a[:c.Index()], append([]ElemType{v}, a[c.Index():]...)...)
+			c.InsertBefore(v)
+		case 5:
+			if c.Valid() {
+				e := c.Remove()
+				if e != a[c.Index()] {
+					t.Fatalf("Cursor removed %v, expected %v\n", e, a[c.Index()])
+				}
+				a = append(a[:c.Index()], a[c.Index()+1:]...)
+			}
+		case 6:
+			if c.Valid() {
+				c.Set(distToElem(-iter))
+				a[c.Index()] = distToElem(-iter)
+			}
+		}
+
+		if c.Valid() != (c.Index() < len(a)) {
+			t.Fatalf("Cursor at index %v has Valid() = %v with length %v\n", c.Index(), c.Valid(), len(a))
+		}
+		if c.Valid() && c.Value() != a[c.Index()] {
+			t.Fatalf("Cursor at index %v has value %v, expected %v\n", c.Index(), c.Value(), a[c.Index()])
+		}
+	}
+
+	if err := sl.Validate(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if fmt.Sprint(toSlice(&sl)) != fmt.Sprint(a) {
+		t.Fatalf("ISkipList modified via cursor doesn't match expected contents\n")
+	}
+
+	c.Seek(0)
+	if c.Prev() || c.Index() != 0 {
+		t.Errorf("Expected Prev at index 0 to return false\n")
+	}
+	c.Seek(sl.Length())
+	if c.Next() || c.Valid() {
+		t.Errorf("Expected Next at end to return false\n")
+	}
+}
+
+func TestModificationDuringIteration(t *testing.T) {
+	if !DebugAssertionsEnabled() {
+		t.Skip("Modification detection requires the 'iskiplistdebug' build tag")
+	}
+
+	expectPanic := func(name string, f func()) {
+		t.Helper()
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("%v: expected panic on modification during iteration\n", name)
+			}
+		}()
+		f()
+	}
+
+	mk := func() *ISkipList {
+		var sl ISkipList
+		sl.Seed(randSeed1, randSeed2)
+		for i := 0; i < 20; i++ {
+			sl.PushBack(i)
+		}
+		return &sl
+	}
+
+	sl := mk()
+	expectPanic("Iterate", func() {
+		sl.Iterate(func(e *ElemType) bool { sl.PushBack(0); return true })
+	})
+	sl = mk()
+	expectPanic("IterateRangeI", func() {
+		sl.IterateRangeI(2, 10, func(i int, e *ElemType) bool { sl.Remove(0); return true })
+	})
+	sl = mk()
+	expectPanic("IterateReverse", func() {
+		sl.IterateReverse(func(e *ElemType) bool { sl.Insert(3, 0); return true })
+	})
+	sl = mk()
+	expectPanic("All", func() {
+		for range sl.All() {
+			sl.Truncate(5)
+		}
+	})
+
+	// Setting values is not a structural modification.
+	sl = mk()
+	sl.Iterate(func(e *ElemType) bool { *e = 0; return true })
+	sl.ForAllI(func(i int, e *ElemType) { sl.Set(i, i) })
+	// Modifying after iteration has halted is fine.
+	sl.Iterate(func(e *ElemType) bool { sl.PushBack(0); return false })
+}
+
+func TestInsertSlice(t *testing.T) {
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	sl.EnableValueIndex()
+	var a []ElemType
+	next := 0
+	for iter := 0; iter < 100; iter++ {
+		n := int(rand.Bounded(50))
+		chunk := make([]ElemType, n)
+		for i := range chunk {
+			chunk[i] = next
+			next++
+		}
+		index := int(rand.Bounded(uint32(len(a) + 1)))
+		sl.InsertSlice(index, chunk)
+		a = append(a[:index], append(append([]ElemType{}, chunk...), a[index:]...)...)
+
+		if err := sl.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(toSlice(&sl)) != fmt.Sprint(a) {
+			t.Fatalf("Unexpected elements after inserting %v elements at %v\n", n, index)
+		}
+	}
+	for i, v := range a {
+		if p, ok := sl.PositionOf(v); !ok || p != i {
+			t.Fatalf("Value index not updated by InsertSlice for %v\n", v)
+		}
+	}
+	if r := sl.DepthReport(); r.Suspicious {
+		t.Errorf("Suspicious structure after repeated InsertSlice:\n%v", r)
+	}
+}
+
+func TestExtractRange(t *testing.T) {
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	sl.EnableValueIndex()
+	var a []ElemType
+	for i := 0; i < 1000; i++ {
+		sl.PushBack(i)
+		a = append(a, i)
+	}
+	for len(a) > 0 {
+		from := int(rand.Bounded(uint32(len(a) + 1)))
+		to := from + int(rand.Bounded(uint32(len(a)-from+1)))
+		ex := sl.ExtractRange(from, to)
+		if fmt.Sprint(toSlice(ex)) != fmt.Sprint(a[from:to]) {
+			t.Fatalf("Unexpected extracted elements for [%v, %v)\n", from, to)
+		}
+		a = append(a[:from], a[to:]...)
+		if err := sl.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if err := ex.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(toSlice(&sl)) != fmt.Sprint(a) {
+			t.Fatalf("Unexpected remaining elements after extracting [%v, %v)\n", from, to)
+		}
+		for i, v := range a {
+			if p, ok := sl.PositionOf(v); !ok || p != i {
+				t.Fatalf("Value index not updated by ExtractRange for %v\n", v)
+			}
+		}
+	}
+
+	if ex := sl.ExtractRange(0, 0); ex.Length() != 0 {
+		t.Errorf("Expected empty ISkipList from ExtractRange on empty list\n")
+	}
+}
+
+func TestSplitAt(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 10, 100, 1000} {
+		for _, index := range []int{0, n / 3, n / 2, n} {
+			var sl ISkipList
+			sl.Seed(randSeed1, randSeed2)
+			sl.EnableValueIndex()
+			for i := 0; i < n; i++ {
+				sl.PushBack(i)
+			}
+			prefix, suffix := sl.SplitAt(index)
+			if prefix != &sl {
+				t.Fatalf("Expected SplitAt to return the original ISkipList as the prefix\n")
+			}
+			if err := prefix.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			if err := suffix.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			if prefix.Length() != index || suffix.Length() != n-index {
+				t.Fatalf("Unexpected lengths %v, %v after splitting %v elements at %v\n", prefix.Length(), suffix.Length(), n, index)
+			}
+			for i := 0; i < index; i++ {
+				if prefix.At(i) != i {
+					t.Fatalf("Unexpected element %v at %v of prefix\n", prefix.At(i), i)
+				}
+			}
+			for i := 0; i < n-index; i++ {
+				if suffix.At(i) != index+i {
+					t.Fatalf("Unexpected element %v at %v of suffix\n", suffix.At(i), i)
+				}
+			}
+			if _, ok := prefix.PositionOf(index); ok {
+				t.Fatalf("Value index not updated by SplitAt\n")
+			}
+		}
+	}
+}
+
+func TestAppend(t *testing.T) {
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	sl.EnableValueIndex()
+	var a []ElemType
+	next := 0
+	for iter := 0; iter < 100; iter++ {
+		var other ISkipList
+		other.Seed(randSeed1, uint64(iter))
+		other.EnableValueIndex()
+		n := int(rand.Bounded(50))
+		for i := 0; i < n; i++ {
+			other.PushBack(next)
+			a = append(a, next)
+			next++
+		}
+		sl.Append(&other)
+
+		if other.Length() != 0 {
+			t.Fatalf("Append did not consume its argument\n")
+		}
+		if err := other.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if err := sl.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(toSlice(&sl)) != fmt.Sprint(a) {
+			t.Fatalf("Unexpected elements after appending %v elements\n", n)
+		}
+	}
+	for i, v := range a {
+		if p, ok := sl.PositionOf(v); !ok || p != i {
+			t.Fatalf("Value index not updated by Append for %v\n", v)
+		}
+	}
+	if r := sl.DepthReport(); r.Suspicious {
+		t.Errorf("Suspicious structure after repeated Append:\n%v", r)
+	}
+}
+
+func TestFromSlice(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 3, 10, 100, 10000} {
+		a := make([]ElemType, n)
+		for i := range a {
+			a[i] = i * 3
+		}
+		sl := FromSlice(a)
+		if err := sl.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(toSlice(sl)) != fmt.Sprint(a) {
+			t.Fatalf("Unexpected elements in ISkipList built from slice of length %v\n", n)
+		}
+		// The new ISkipList can be modified in the usual way.
+		sl.Insert(n/2, -1)
+		sl.PushBack(-2)
+		if err := sl.Validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAppendSlice(t *testing.T) {
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	sl.EnableValueIndex()
+	var a []ElemType
+	next := 0
+	for iter := 0; iter < 100; iter++ {
+		n := int(rand.Bounded(50))
+		chunk := make([]ElemType, n)
+		for i := range chunk {
+			chunk[i] = next
+			next++
+		}
+		sl.AppendSlice(chunk)
+		a = append(a, chunk...)
+		// Interleave with ordinary insertions, as the cache must be kept
+		// consistent.
+		if len(a) > 0 {
+			index := int(rand.Bounded(uint32(len(a))))
+			sl.Insert(index, -iter)
+			a = append(a[:index], append([]ElemType{-iter}, a[index:]...)...)
+		}
+
+		if err := sl.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(toSlice(&sl)) != fmt.Sprint(a) {
+			t.Fatalf("Unexpected elements after appending %v elements\n", n)
+		}
+	}
+	if r := sl.DepthReport(); r.Suspicious {
+		t.Errorf("Suspicious structure after repeated AppendSlice:\n%v", r)
+	}
+
+	sl.Clear()
+	sl.SetMaxLength(10)
+	sl.AppendSlice([]ElemType{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12})
+	if fmt.Sprint(toSlice(&sl)) != "[3 4 5 6 7 8 9 10 11 12]" {
+		t.Errorf("Unexpected elements after AppendSlice with max length: %v\n", toSlice(&sl))
+	}
+}
+
+func TestPrependSlice(t *testing.T) {
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	sl.EnableValueIndex()
+	var a []ElemType
+	next := 0
+	for iter := 0; iter < 100; iter++ {
+		n := int(rand.Bounded(50))
+		chunk := make([]ElemType, n)
+		for i := range chunk {
+			chunk[i] = next
+			next++
+		}
+		sl.PrependSlice(chunk)
+		a = append(append([]ElemType{}, chunk...), a...)
+
+		if err := sl.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(toSlice(&sl)) != fmt.Sprint(a) {
+			t.Fatalf("Unexpected elements after prepending %v elements\n", n)
+		}
+	}
+	for i, v := range a {
+		if p, ok := sl.PositionOf(v); !ok || p != i {
+			t.Fatalf("Value index not updated by PrependSlice for %v\n", v)
+		}
+	}
+	if r := sl.DepthReport(); r.Suspicious {
+		t.Errorf("Suspicious structure after repeated PrependSlice:\n%v", r)
+	}
+}
+
+func TestSetRange(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	sl.EnableValueIndex()
+	for i := 0; i < 100; i++ {
+		sl.PushBack(i)
+	}
+
+	sl.SetRange(10, []ElemType{-1, -2, -3})
+	sl.SetRange(97, []ElemType{-4, -5, -6})
+	sl.SetRange(50, nil)
+	for i := 0; i < 100; i++ {
+		expected := i
+		switch {
+		case i >= 10 && i < 13:
+			expected = 9 - i
+		case i >= 97:
+			expected = 93 - i
+		}
+		if sl.At(i) != expected {
+			t.Errorf("Expected %v at %v after SetRange, got %v\n", expected, i, sl.At(i))
+		}
+	}
+	if i, ok := sl.PositionOf(-5); !ok || i != 98 {
+		t.Errorf("Value index not updated by SetRange\n")
+	}
+	if _, ok := sl.PositionOf(11); ok {
+		t.Errorf("Value index not updated by SetRange\n")
+	}
+	if err := sl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Expected SetRange past the end of the ISkipList to panic\n")
+		}
+	}()
+	sl.SetRange(98, []ElemType{1, 2, 3})
+}
+
+func TestSwapRanges(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	sl.EnableValueIndex()
+	sl.EnableIDs()
+	a := make([]ElemType, 100)
+	for i := range a {
+		a[i] = i
+		sl.PushBack(i)
+	}
+	ids := make([]ElemID, len(a))
+	for i := range ids {
+		ids[i] = sl.IDAt(i)
+	}
+
+	sl.SwapRanges(60, 5, 20)
+	for k := 0; k < 20; k++ {
+		a[5+k], a[60+k] = a[60+k], a[5+k]
+	}
+	if fmt.Sprint(toSlice(&sl)) != fmt.Sprint(a) {
+		t.Errorf("Unexpected elements after SwapRanges: %v\n", toSlice(&sl))
+	}
+	if i, ok := sl.PositionOf(60); !ok || i != 5 {
+		t.Errorf("Value index not updated by SwapRanges\n")
+	}
+	// IDs follow their elements (element v had ID ids[v]).
+	for i, v := range a {
+		if j, ok := sl.IndexOfID(ids[v]); !ok || j != i {
+			t.Errorf("ID of element %v has index %v, expected %v\n", v, j, i)
+		}
+	}
+	if err := sl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Expected SwapRanges with overlapping ranges to panic\n")
+		}
+	}()
+	sl.SwapRanges(0, 10, 11)
+}
+
+func TestMoveElement(t *testing.T) {
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	sl.EnableIDs()
+	a := make([]ElemType, 200)
+	ids := make([]ElemID, len(a))
+	for i := range a {
+		a[i] = i
+		sl.PushBack(i)
+		ids[i] = sl.IDAt(i)
+	}
+
+	for iter := 0; iter < 200; iter++ {
+		i := int(rand.Bounded(uint32(len(a))))
+		j := int(rand.Bounded(uint32(len(a))))
+		id := sl.IDAt(i)
+
+		sl.MoveElement(i, j)
+		v := a[i]
+		a = append(a[:i], a[i+1:]...)
+		a = append(a[:j], append([]ElemType{v}, a[j:]...)...)
+
+		if err := sl.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(toSlice(&sl)) != fmt.Sprint(a) {
+			t.Fatalf("Unexpected elements after moving %v to %v\n", i, j)
+		}
+		if sl.IDAt(j) != id {
+			t.Fatalf("Element moved from %v to %v did not keep its ID\n", i, j)
+		}
+	}
+	for i, v := range a {
+		if p, ok := sl.IndexOfID(ids[v]); !ok || p != i {
+			t.Fatalf("IDs not updated by MoveElement for %v\n", v)
+		}
+	}
+}
+
+func TestMoveRange(t *testing.T) {
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	var src, dst ISkipList
+	src.Seed(randSeed1, randSeed2)
+	dst.Seed(randSeed1, randSeed2+1)
+	src.EnableValueIndex()
+	dst.EnableValueIndex()
+	var a, b []ElemType
+	for i := 0; i < 500; i++ {
+		src.PushBack(i)
+		a = append(a, i)
+	}
+
+	for len(a) > 0 {
+		from := int(rand.Bounded(uint32(len(a) + 1)))
+		to := from + int(rand.Bounded(uint32(len(a)-from+1)))
+		dstIndex := int(rand.Bounded(uint32(len(b) + 1)))
+
+		src.MoveRange(&dst, from, to, dstIndex)
+		moved := append([]ElemType{}, a[from:to]...)
+		a = append(a[:from], a[to:]...)
+		b = append(b[:dstIndex], append(moved, b[dstIndex:]...)...)
+
+		if err := src.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if err := dst.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(toSlice(&src)) != fmt.Sprint(a) || fmt.Sprint(toSlice(&dst)) != fmt.Sprint(b) {
+			t.Fatalf("Unexpected elements after moving [%v, %v) to %v\n", from, to, dstIndex)
+		}
+	}
+	for i, v := range b {
+		if p, ok := dst.PositionOf(v); !ok || p != i {
+			t.Fatalf("Value index not updated by MoveRange for %v\n", v)
+		}
+	}
+}
+
+func TestIndexOfAndContains(t *testing.T) {
+	for _, indexed := range []bool{false, true} {
+		var sl ISkipList
+		sl.Seed(randSeed1, randSeed2)
+		if indexed {
+			sl.EnableValueIndex()
+		}
+		for i := 0; i < 100; i++ {
+			sl.PushBack(i % 10)
+		}
+
+		for v := 0; v < 10; v++ {
+			if i := sl.IndexOf(v); i != v {
+				t.Errorf("Expected IndexOf(%v) to be %v, got %v (indexed=%v)\n", v, v, i, indexed)
+			}
+			if i := sl.LastIndexOf(v); i != 90+v {
+				t.Errorf("Expected LastIndexOf(%v) to be %v, got %v (indexed=%v)\n", v, 90+v, i, indexed)
+			}
+			if !sl.Contains(v) {
+				t.Errorf("Expected Contains(%v) to be true (indexed=%v)\n", v, indexed)
+			}
+		}
+		if sl.IndexOf(10) != -1 || sl.LastIndexOf(10) != -1 || sl.Contains(10) {
+			t.Errorf("Expected absent value not to be found (indexed=%v)\n", indexed)
+		}
+	}
+}
+
+func TestFindIndexAndFindAll(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 100; i++ {
+		sl.PushBack(i)
+	}
+
+	isMultipleOf7 := func(e ElemType) bool { return e > 0 && e%7 == 0 }
+	if i := sl.FindIndex(isMultipleOf7); i != 7 {
+		t.Errorf("Expected FindIndex to return 7, got %v\n", i)
+	}
+	all := sl.FindAll(isMultipleOf7)
+	if len(all) != 14 || all[0] != 7 || all[13] != 98 {
+		t.Errorf("Unexpected result from FindAll: %v\n", all)
+	}
+
+	none := func(e ElemType) bool { return e < 0 }
+	if i := sl.FindIndex(none); i != -1 {
+		t.Errorf("Expected FindIndex to return -1, got %v\n", i)
+	}
+	if all := sl.FindAll(none); all != nil {
+		t.Errorf("Expected FindAll to return nil, got %v\n", all)
+	}
+}
+
+func TestRemoveIfAndRetainIf(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	sl.EnableValueIndex()
+	for i := 0; i < 1000; i++ {
+		sl.PushBack(i)
+	}
+
+	if n := sl.RemoveIf(func(e ElemType) bool { return e%3 == 0 }); n != 334 {
+		t.Errorf("Expected RemoveIf to remove 334 elements, removed %v\n", n)
+	}
+	if n := sl.RetainIf(func(e ElemType) bool { return e%2 == 0 }); n != 333 {
+		t.Errorf("Expected RetainIf to remove 333 elements, removed %v\n", n)
+	}
+	if n := sl.RemoveIf(func(e ElemType) bool { return e < 0 }); n != 0 {
+		t.Errorf("Expected RemoveIf to remove no elements, removed %v\n", n)
+	}
+	if err := sl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	i := 0
+	for v := 0; v < 1000; v++ {
+		if v%3 == 0 || v%2 != 0 {
+			continue
+		}
+		if sl.At(i) != v {
+			t.Fatalf("Expected %v at %v, got %v\n", v, i, sl.At(i))
+		}
+		if p, ok := sl.PositionOf(v); !ok || p != i {
+			t.Fatalf("Value index not updated for %v\n", v)
+		}
+		i++
+	}
+	if i != sl.Length() {
+		t.Errorf("Unexpected length %v, expected %v\n", sl.Length(), i)
+	}
+}
+
+func TestMap(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 1000; i++ {
+		sl.PushBack(i)
+	}
+
+	m := sl.Map(func(e ElemType) ElemType { return e * 2 })
+	if err := m.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if m.Length() != sl.Length() {
+		t.Fatalf("Unexpected length %v after Map\n", m.Length())
+	}
+	for i := 0; i < sl.Length(); i++ {
+		if sl.At(i) != i || m.At(i) != 2*i {
+			t.Fatalf("Unexpected elements %v, %v at %v after Map\n", sl.At(i), m.At(i), i)
+		}
+	}
+	if fmt.Sprint(sl.LevelHistogram()) != fmt.Sprint(m.LevelHistogram()) {
+		t.Errorf("Expected Map to preserve the level structure\n")
+	}
+
+	var empty ISkipList
+	if empty.Map(func(e ElemType) ElemType { return e }).Length() != 0 {
+		t.Errorf("Expected Map of empty ISkipList to be empty\n")
+	}
+}
+
+func TestSort(t *testing.T) {
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	less := func(a, b ElemType) bool { return a < b }
+	for _, n := range []int{0, 1, 2, 3, 7, 100, 1000, 5000} {
+		var sl ISkipList
+		sl.Seed(randSeed1, randSeed2)
+		sl.EnableIDs()
+		a := make([]ElemType, n)
+		for i := range a {
+			a[i] = int(rand.Bounded(uint32(n/2 + 1)))
+			sl.PushBack(a[i])
+		}
+		ids := make(map[ElemID]ElemType)
+		for i := 0; i < n; i++ {
+			ids[sl.IDAt(i)] = sl.At(i)
+		}
+
+		sl.Sort(less)
+		sort.Ints(a)
+
+		if err := sl.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(toSlice(&sl)) != fmt.Sprint(a) {
+			t.Fatalf("Unexpected elements after sorting %v elements\n", n)
+		}
+		for id, v := range ids {
+			if i, ok := sl.IndexOfID(id); !ok || sl.At(i) != v {
+				t.Fatalf("ID %v did not follow its element when sorting\n", id)
+			}
+		}
+		if r := sl.DepthReport(); r.Suspicious {
+			t.Errorf("Suspicious structure after Sort:\n%v", r)
+		}
+	}
+}
+
+func TestSortStableAndIsSorted(t *testing.T) {
+	// Sort by the high bits only, so that elements with equal high bits
+	// compare equal and stability can be checked using the low bits.
+	less := func(a, b ElemType) bool { return a>>16 < b>>16 }
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	a := make([]ElemType, 2000)
+	for i := range a {
+		a[i] = int(rand.Bounded(20))<<16 | i
+		sl.PushBack(a[i])
+	}
+
+	if sl.IsSorted(less) {
+		t.Errorf("Expected unsorted ISkipList not to be sorted\n")
+	}
+	sl.SortStable(less)
+	sort.SliceStable(a, func(i, j int) bool { return less(a[i], a[j]) })
+	if fmt.Sprint(toSlice(&sl)) != fmt.Sprint(a) {
+		t.Fatalf("SortStable did not preserve the order of equal elements\n")
+	}
+	if !sl.IsSorted(less) {
+		t.Errorf("Expected ISkipList to be sorted after SortStable\n")
+	}
+	if err := sl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	var empty ISkipList
+	if !empty.IsSorted(less) {
+		t.Errorf("Expected empty ISkipList to be sorted\n")
+	}
+}
+
+func TestSearchSorted(t *testing.T) {
+	less := func(a, b ElemType) bool { return a < b }
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 1000; i++ {
+		sl.PushBack(2 * (i / 3))
+	}
+	a := toSlice(&sl)
+
+	for v := -2; v < 700; v++ {
+		i, found := sl.SearchSorted(v, less)
+		expected := sort.SearchInts(a, v)
+		expectedFound := expected < len(a) && a[expected] == v
+		if i != expected || found != expectedFound {
+			t.Fatalf("Expected SearchSorted(%v) to return %v, %v; got %v, %v\n", v, expected, expectedFound, i, found)
+		}
+	}
+
+	var empty ISkipList
+	if i, found := empty.SearchSorted(1, less); i != 0 || found {
+		t.Errorf("Unexpected result from SearchSorted on empty ISkipList: %v, %v\n", i, found)
+	}
+}
+
+func TestDedup(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	sl.EnableValueIndex()
+	for _, v := range []ElemType{1, 1, 1, 2, 3, 3, 1, 4, 4, 4, 4, 5} {
+		sl.PushBack(v)
+	}
+	if n := sl.Dedup(); n != 6 {
+		t.Errorf("Expected Dedup to remove 6 elements, removed %v\n", n)
+	}
+	if fmt.Sprint(toSlice(&sl)) != "[1 2 3 1 4 5]" {
+		t.Errorf("Unexpected elements after Dedup: %v\n", toSlice(&sl))
+	}
+	if i, ok := sl.PositionOf(4); !ok || i != 4 {
+		t.Errorf("Value index not updated by Dedup\n")
+	}
+	if err := sl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each element is compared with the last element retained.
+	sl.Clear()
+	for _, v := range []ElemType{10, 11, 12, 13, 20, 21, 30} {
+		sl.PushBack(v)
+	}
+	near := func(a, b ElemType) bool { return b-a <= 2 }
+	if n := sl.DedupFunc(near); n != 3 {
+		t.Errorf("Expected DedupFunc to remove 3 elements, removed %v\n", n)
+	}
+	if fmt.Sprint(toSlice(&sl)) != "[10 13 20 30]" {
+		t.Errorf("Unexpected elements after DedupFunc: %v\n", toSlice(&sl))
+	}
+
+	var empty ISkipList
+	if n := empty.Dedup(); n != 0 {
+		t.Errorf("Expected Dedup on empty ISkipList to remove nothing\n")
+	}
+}
+
+func TestMinMaxIndex(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for _, v := range []ElemType{5, 3, 9, 1, 9, 1, 7} {
+		sl.PushBack(v)
+	}
+
+	if i := sl.MinIndex(nil); i != 3 {
+		t.Errorf("Expected MinIndex to return 3, got %v\n", i)
+	}
+	if i := sl.MaxIndex(nil); i != 2 {
+		t.Errorf("Expected MaxIndex to return 2, got %v\n", i)
+	}
+	if v, ok := sl.Min(nil); !ok || v != 1 {
+		t.Errorf("Expected Min to return 1, got %v\n", v)
+	}
+	if v, ok := sl.Max(nil); !ok || v != 9 {
+		t.Errorf("Expected Max to return 9, got %v\n", v)
+	}
+
+	// Order by distance from 6.
+	byDist := func(a, b ElemType) bool {
+		da, db := a-6, b-6
+		if da < 0 {
+			da = -da
+		}
+		if db < 0 {
+			db = -db
+		}
+		return da < db
+	}
+	if i := sl.MinIndex(byDist); i != 0 {
+		t.Errorf("Expected MinIndex with comparator to return 0, got %v\n", i)
+	}
+	if v, ok := sl.Max(byDist); !ok || v != 1 {
+		t.Errorf("Expected Max with comparator to return 1, got %v\n", v)
+	}
+	if i := sl.MaxIndex(byDist); i != 3 {
+		t.Errorf("Expected MaxIndex with comparator to return 3, got %v\n", i)
+	}
+
+	var empty ISkipList
+	if empty.MinIndex(nil) != -1 || empty.MaxIndex(nil) != -1 {
+		t.Errorf("Expected MinIndex and MaxIndex of empty ISkipList to be -1\n")
+	}
+	if _, ok := empty.Min(nil); ok {
+		t.Errorf("Expected Min of empty ISkipList to fail\n")
+	}
+}
+
+func TestSumRange(t *testing.T) {
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 100; i++ {
+		sl.PushBack(i)
+	}
+	sl.EnableRangeSums()
+
+	check := func(op string) {
+		t.Helper()
+		if err := sl.Validate(); err != nil {
+			t.Fatalf("After %v: %v", op, err)
+		}
+		a := toSlice(&sl)
+		for k := 0; k < 20; k++ {
+			from := int(rand.Bounded(uint32(len(a) + 1)))
+			to := from + int(rand.Bounded(uint32(len(a)-from+1)))
+			var expected int64
+			for _, v := range a[from:to] {
+				expected += int64(v)
+			}
+			if s := sl.SumRange(from, to); s != expected {
+				t.Fatalf("After %v: expected SumRange(%v, %v) to be %v, got %v\n", op, from, to, expected, s)
+			}
+		}
+	}
+
+	check("enabling")
+	for iter := 0; iter < 300; iter++ {
+		mutateRandomly(&sl, rand)
+		check(fmt.Sprintf("operation %v", iter))
+	}
+
+	sl.Clear()
+	sl.PushBack(5)
+	check("Clear")
+
+	sl.DisableRangeSums()
+	if sl.HasRangeSums() {
+		t.Errorf("Expected range sums to be disabled\n")
+	}
+}
+
+// Span aggregates are cached in the sparse nodes, so this checks that every
+// kind of structural change discards the aggregates that it affects. The
+// ISkipList is long enough to have several sparse levels.
+func TestSpanAggregatesStructuralChanges(t *testing.T) {
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 2000; i++ {
+		sl.PushBack(int(rand.Bounded(1000)))
+	}
+	sl.EnableRangeSums()
+	countMultiplesOf3 := Monoid{
+		Identity: 0,
+		Combine:  func(a, b ElemType) ElemType { return a + b },
+		Lift: func(e ElemType) ElemType {
+			if e%3 == 0 {
+				return 1
+			}
+			return 0
+		},
+	}
+	sl.EnableAggregate(countMultiplesOf3)
+	sl.EnableRangeMinMax()
+
+	for iter := 0; iter < 2000; iter++ {
+		n := sl.Length()
+		i := int(rand.Bounded(uint32(n)))
+		j := int(rand.Bounded(uint32(n)))
+		e := int(rand.Bounded(1000))
+		switch rand.Bounded(12) {
+		case 0:
+			sl.PushFront(e)
+		case 1:
+			sl.PopFront()
+		case 2:
+			sl.PushBack(e)
+		case 3:
+			sl.PopBack()
+		case 4:
+			sl.MoveElement(i, j)
+		case 5:
+			// A piece that is changed while detached must not bring stale
+			// sums back with it.
+			from, to := min(i, j), max(i, j)
+			piece := sl.ExtractRange(from, to)
+			if piece.Length() > 0 {
+				piece.Set(0, e)
+			}
+			sl.InsertListAt(from, piece)
+		case 6:
+			sl.PrependSlice([]ElemType{e, e + 1})
+		case 7:
+			sl.Truncate(n - 1)
+		case 8:
+			sl.SwapRanges(0, n/2, n/4)
+		case 9:
+			sl.Remove(i)
+		case 10:
+			sl.AppendSlice([]ElemType{e, e + 1})
+		default:
+			sl.Insert(i, e)
+		}
+
+		a := toSlice(&sl)
+		from := int(rand.Bounded(uint32(len(a) + 1)))
+		to := from + int(rand.Bounded(uint32(len(a)-from+1)))
+		var expected int64
+		expectedAgg := 0
+		for _, v := range a[from:to] {
+			expected += int64(v)
+			expectedAgg += countMultiplesOf3.lift(v)
+		}
+		if s := sl.SumRange(from, to); s != expected {
+			t.Fatalf("Iteration %v: expected SumRange(%v, %v) to be %v, got %v\n", iter, from, to, expected, s)
+		}
+		if agg := sl.AggregateRange(from, to); agg != expectedAgg {
+			t.Fatalf("Iteration %v: expected AggregateRange(%v, %v) to be %v, got %v\n", iter, from, to, expectedAgg, agg)
+		}
+		if from < to {
+			expectedLo, expectedHi := a[from], a[from]
+			for _, v := range a[from:to] {
+				expectedLo, expectedHi = min(expectedLo, v), max(expectedHi, v)
+			}
+			lo, _ := sl.RangeMin(from, to)
+			hi, _ := sl.RangeMax(from, to)
+			if lo != expectedLo || hi != expectedHi {
+				t.Fatalf("Iteration %v: expected minimum and maximum of [%v, %v) to be %v, %v; got %v, %v\n", iter, from, to, expectedLo, expectedHi, lo, hi)
+			}
+		}
+		if iter%100 == 0 {
+			if err := sl.Validate(); err != nil {
+				t.Fatalf("Iteration %v: %v", iter, err)
+			}
+		}
+	}
+}
+
+// mutateRandomly applies a randomly chosen operation to an ISkipList. The
+// operations cover the various ways in which optional features are notified
+// of changes.
+func mutateRandomly(sl *ISkipList, rand *pcg.Pcg32) {
+	n := sl.Length()
+	switch rand.Bounded(9) {
+	case 0:
+		sl.Insert(int(rand.Bounded(uint32(n+1))), int(rand.Bounded(1000))-500)
+	case 1:
+		if n > 0 {
+			sl.Remove(int(rand.Bounded(uint32(n))))
+		}
+	case 2:
+		if n > 0 {
+			sl.Set(int(rand.Bounded(uint32(n))), int(rand.Bounded(1000)))
+		}
+	case 3:
+		if n > 1 {
+			sl.Swap(int(rand.Bounded(uint32(n))), int(rand.Bounded(uint32(n))))
+		}
+	case 4:
+		from := int(rand.Bounded(uint32(n + 1)))
+		sl.RotateRange(from, n, int(rand.Bounded(10)))
+	case 5:
+		sl.AppendSlice([]ElemType{1, 2, 3})
+	case 6:
+		from := int(rand.Bounded(uint32(n + 1)))
+		sl.ExtractRange(from, from+int(rand.Bounded(uint32(n-from+1))))
+	case 7:
+		sl.Sort(func(a, b ElemType) bool { return a > b })
+	case 8:
+		sl.RemoveIf(func(e ElemType) bool { return e%7 == 0 })
+	}
+}
+
+func TestAggregateRange(t *testing.T) {
+	// 'first' is associative but not commutative, so it checks that
+	// aggregates are combined in order.
+	const none = -1 << 40
+	first := Monoid{
+		Identity: none,
+		Combine: func(a, b ElemType) ElemType {
+			if a != none {
+				return a
+			}
+			return b
+		},
+	}
+	countEven := Monoid{
+		Identity: 0,
+		Combine:  func(a, b ElemType) ElemType { return a + b },
+		Lift: func(e ElemType) ElemType {
+			if e%2 == 0 {
+				return 1
+			}
+			return 0
+		},
+	}
+
+	for _, m := range []Monoid{first, countEven} {
+		rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+		var sl ISkipList
+		sl.Seed(randSeed1, randSeed2)
+		for i := 0; i < 100; i++ {
+			sl.PushBack(i)
+		}
+		sl.EnableAggregate(m)
+
+		for iter := 0; iter < 300; iter++ {
+			mutateRandomly(&sl, rand)
+			if err := sl.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			a := toSlice(&sl)
+			for k := 0; k < 20; k++ {
+				from := int(rand.Bounded(uint32(len(a) + 1)))
+				to := from + int(rand.Bounded(uint32(len(a)-from+1)))
+				expected := m.Identity
+				for _, v := range a[from:to] {
+					expected = m.Combine(expected, m.lift(v))
+				}
+				if agg := sl.AggregateRange(from, to); agg != expected {
+					t.Fatalf("Expected AggregateRange(%v, %v) to be %v, got %v\n", from, to, expected, agg)
+				}
+			}
+		}
+
+		sl.DisableAggregate()
+		if sl.HasAggregate() {
+			t.Errorf("Expected aggregates to be disabled\n")
+		}
+	}
+}
+
+func TestRangeMinMax(t *testing.T) {
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 100; i++ {
+		sl.PushBack(int(rand.Bounded(1000)))
+	}
+	sl.EnableRangeMinMax()
+	sl.EnableRangeSums()
+
+	for iter := 0; iter < 300; iter++ {
+		mutateRandomly(&sl, rand)
+		if err := sl.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		a := toSlice(&sl)
+		for k := 0; k < 20; k++ {
+			from := int(rand.Bounded(uint32(len(a) + 1)))
+			to := from + int(rand.Bounded(uint32(len(a)-from+1)))
+			lo, okLo := sl.RangeMin(from, to)
+			hi, okHi := sl.RangeMax(from, to)
+			if from == to {
+				if okLo || okHi {
+					t.Fatalf("Expected empty range [%v, %v) to have no minimum or maximum\n", from, to)
+				}
+				continue
+			}
+			expectedLo, expectedHi := a[from], a[from]
+			for _, v := range a[from:to] {
+				expectedLo, expectedHi = min(expectedLo, v), max(expectedHi, v)
+			}
+			if !okLo || !okHi || lo != expectedLo || hi != expectedHi {
+				t.Fatalf("Expected minimum and maximum of [%v, %v) to be %v, %v; got %v, %v\n", from, to, expectedLo, expectedHi, lo, hi)
+			}
+		}
+	}
+
+	// Disabling one augmentation leaves the other intact.
+	sl.DisableRangeMinMax()
+	if sl.HasRangeMinMax() || !sl.HasRangeSums() {
+		t.Errorf("Unexpected augmentations enabled after DisableRangeMinMax\n")
+	}
+	if err := sl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandles(t *testing.T) {
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 100; i++ {
+		sl.PushBack(i)
+	}
+	sl.EnableHandles()
+
+	// Elements are unique, so a handle's element can be used to check that it
+	// still refers to the same element.
+	handles := make(map[ElemType]Handle)
+	for i := 0; i < sl.Length(); i++ {
+		handles[sl.At(i)] = sl.HandleAt(i)
+	}
+	next := 1000
+	for iter := 0; iter < 500; iter++ {
+		n := sl.Length()
+		switch rand.Bounded(6) {
+		case 0:
+			i := int(rand.Bounded(uint32(n + 1)))
+			sl.Insert(i, next)
+			handles[next] = sl.HandleAt(i)
+			next++
+		case 1:
+			if n > 0 {
+				sl.Remove(int(rand.Bounded(uint32(n))))
+			}
+		case 2:
+			from := int(rand.Bounded(uint32(n + 1)))
+			sl.RotateRange(from, n, int(rand.Bounded(10)))
+		case 3:
+			for v, h := range handles {
+				if e, ok := sl.RemoveHandle(h); ok {
+					if e != v {
+						t.Fatalf("RemoveHandle removed %v, expected %v\n", e, v)
+					}
+					break
+				}
+			}
+		case 4:
+			if n > 0 {
+				sl.Swap(int(rand.Bounded(uint32(n))), int(rand.Bounded(uint32(n))))
+			}
+		case 5:
+			if n >= 2 {
+				k := int(rand.Bounded(uint32(n/2))) + 1
+				sl.SwapRanges(0, n-k, k)
+			}
+		}
+
+		if err := sl.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		present := make(map[ElemType]int)
+		sl.ForAllI(func(i int, e *ElemType) { present[*e] = i })
+		for v, h := range handles {
+			i, ok := present[v]
+			if !ok {
+				i = -1
+			}
+			if j := sl.IndexOfHandle(h); j != i {
+				t.Fatalf("Expected handle for %v to have index %v, got %v\n", v, i, j)
+			}
+		}
+	}
+
+	var other ISkipList
+	other.PushBack(1)
+	other.EnableHandles()
+	if sl.IndexOfHandle(other.HandleAt(0)) != -1 {
+		t.Errorf("Expected handle from another ISkipList not to be found\n")
+	}
+	if sl.IndexOfHandle(Handle{}) != -1 {
+		t.Errorf("Expected zero Handle not to be found\n")
+	}
+	if _, ok := sl.RemoveHandle(Handle{}); ok {
+		t.Errorf("Expected RemoveHandle with zero Handle to fail\n")
+	}
+
+	sl.PushFront(-1)
+	h := sl.HandleAt(0)
+	sl.Clear()
+	if sl.IndexOfHandle(h) != -1 {
+		t.Errorf("Expected handle not to be found after Clear\n")
+	}
+}
+
+func TestRemoveLastElementWithSparseLevels(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 100; i++ {
+		sl.PushBack(i + 1)
+	}
+	// Truncate leaves the root column with sparse levels.
+	sl.Truncate(1)
+	if e := sl.Remove(0); e != 1 {
+		t.Errorf("Expected Remove to return 1, got %v\n", e)
+	}
+	if err := sl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndicesOf(t *testing.T) {
+	rand := pcg.NewPCG32().Seed(randSeed1, randSeed2)
+	for _, indexed := range []bool{false, true} {
+		var sl ISkipList
+		sl.Seed(randSeed1, randSeed2)
+		if indexed {
+			sl.EnableValueIndex()
+		}
+		for i := 0; i < 200; i++ {
+			sl.PushBack(int(rand.Bounded(10)))
+		}
+		for iter := 0; iter < 100; iter++ {
+			mutateRandomly(&sl, rand)
+		}
+
+		a := toSlice(&sl)
+		for v := -1; v < 10; v++ {
+			var expected []int
+			for i, e := range a {
+				if e == v {
+					expected = append(expected, i)
+				}
+			}
+			if got := sl.IndicesOf(v); fmt.Sprint(got) != fmt.Sprint(expected) {
+				t.Errorf("Expected IndicesOf(%v) to be %v, got %v (indexed=%v)\n", v, expected, got, indexed)
+			}
+		}
+		if sl.IndicesOf(-1000) != nil {
+			t.Errorf("Expected IndicesOf for absent value to be nil (indexed=%v)\n", indexed)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	var a, b ISkipList
+	a.Seed(randSeed1, randSeed2)
+	b.Seed(randSeed1+1, randSeed2+1)
+	if !a.Equal(&b) {
+		t.Errorf("Expected empty ISkipLists to be equal\n")
+	}
+	for i := 0; i < 1000; i++ {
+		a.PushBack(i)
+		b.PushFront(999 - i)
+	}
+	if !a.Equal(&b) || !b.Equal(&a) || !a.Equal(&a) {
+		t.Errorf("Expected ISkipLists with different structures but the same elements to be equal\n")
+	}
+	b.Set(500, -1)
+	if a.Equal(&b) {
+		t.Errorf("Expected ISkipLists with different elements not to be equal\n")
+	}
+	b.Set(500, 500)
+	b.PushBack(1000)
+	if a.Equal(&b) {
+		t.Errorf("Expected ISkipLists with different lengths not to be equal\n")
+	}
+}
+
+func TestHash64(t *testing.T) {
+	var a, b ISkipList
+	a.Seed(randSeed1, randSeed2)
+	b.Seed(randSeed1+1, randSeed2+1)
+	for i := 0; i < 1000; i++ {
+		a.PushBack(i * 7)
+		b.PushFront((999 - i) * 7)
+	}
+
+	if a.Hash64(0) != b.Hash64(0) {
+		t.Errorf("Expected equal ISkipLists to have the same hash\n")
+	}
+	if a.Hash64(0) == a.Hash64(1) {
+		t.Errorf("Expected different seeds to give different hashes\n")
+	}
+	h := a.Hash64(0)
+	a.Set(10, -1)
+	if a.Hash64(0) == h {
+		t.Errorf("Expected modification to change the hash\n")
+	}
+
+	// Check against the standard library's implementation of FNV-1a.
+	std := fnv.New64a()
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], 12345)
+	std.Write(buf[:])
+	a.ForAll(func(e *ElemType) {
+		binary.LittleEndian.PutUint64(buf[:], uint64(*e))
+		std.Write(buf[:])
+	})
+	if a.Hash64(12345) != std.Sum64() {
+		t.Errorf("Hash64 doesn't match FNV-1a: %x %x\n", a.Hash64(12345), std.Sum64())
+	}
+}
+
+func TestGoString(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 20; i++ {
+		sl.PushBack(i)
+	}
+	s := fmt.Sprintf("%#v", &sl)
+	if s != DebugPrintISkipList(&sl, 0) {
+		t.Errorf("Unexpected result of formatting with %%#v:\n%v", s)
+	}
+	if !strings.HasPrefix(s, "ISkipList of length 20 with ") {
+		t.Errorf("Unexpected result of GoString:\n%v", s)
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	rand := pcg.NewPCG32()
+	rand.Seed(randSeed1, randSeed2)
+	for _, n := range []int{0, 1, 2, 10, 1000} {
+		var sl ISkipList
+		sl.Seed(randSeed1, randSeed2)
+		for i := 0; i < n; i++ {
+			sl.PushBack(i * 7)
+		}
+		mutateRandomly(&sl, rand)
+
+		data, err := sl.MarshalBinary()
+		if err != nil {
+			t.Fatalf("Unexpected error from MarshalBinary: %v", err)
+		}
+		var restored ISkipList
+		restored.PushBack(99)
+		if err := restored.UnmarshalBinary(data); err != nil {
+			t.Fatalf("Unexpected error from UnmarshalBinary: %v", err)
+		}
+		// The distances stored in the last node on each sparse level are
+		// meaningless, so compare the serialized level structure rather than
+		// the output of DebugPrintISkipList.
+		redata, _ := restored.MarshalBinary()
+		if !bytes.Equal(data, redata) {
+			t.Errorf("Restored ISkipList differs from original:\n%v\n%v", DebugPrintISkipList(&sl, 0), DebugPrintISkipList(&restored, 0))
+		}
+		if !sl.Equal(&restored) {
+			t.Errorf("Restored ISkipList has different elements from original")
+		}
+
+		// Both lists should go on to behave identically.
+		for i := 0; i < 50; i++ {
+			sl.Insert(i%(sl.Length()+1), -i)
+			restored.Insert(i%(restored.Length()+1), -i)
+		}
+		again, _ := sl.MarshalBinary()
+		again2, _ := restored.MarshalBinary()
+		if !bytes.Equal(again, again2) {
+			t.Errorf("Restored ISkipList diverged from original after insertions")
+		}
+	}
+}
+
+func TestUnmarshalBinaryMalformed(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 100; i++ {
+		sl.PushBack(i)
+	}
+	data, _ := sl.MarshalBinary()
+
+	var target ISkipList
+	target.PushBack(1)
+	target.PushBack(2)
+	for i := 0; i < len(data); i++ {
+		if err := target.UnmarshalBinary(data[:i]); err == nil {
+			t.Errorf("Expected error unmarshaling data truncated to %v bytes", i)
+		}
+	}
+	if err := target.UnmarshalBinary(append(append([]byte{}, data...), 0)); err == nil {
+		t.Errorf("Expected error unmarshaling data with trailing byte")
+	}
+	corrupt := append([]byte{}, data...)
+	corrupt[0] = 'X'
+	if err := target.UnmarshalBinary(corrupt); err == nil {
+		t.Errorf("Expected error unmarshaling data with bad magic")
+	}
+	if s := toSlice(&target); len(s) != 2 || s[0] != 1 || s[1] != 2 {
+		t.Errorf("ISkipList modified by failed UnmarshalBinary: %v", toSlice(&target))
+	}
+}
+
+func TestGob(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 500; i++ {
+		sl.PushBack(i)
+	}
+
+	type wrapper struct {
+		Name string
+		List *ISkipList
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(wrapper{"foo", &sl}); err != nil {
+		t.Fatalf("Unexpected error encoding: %v", err)
+	}
+	var w wrapper
+	if err := gob.NewDecoder(&buf).Decode(&w); err != nil {
+		t.Fatalf("Unexpected error decoding: %v", err)
+	}
+	if w.Name != "foo" || !w.List.Equal(&sl) {
+		t.Errorf("Decoded value differs from encoded value")
+	}
+	data, _ := sl.MarshalBinary()
+	redata, _ := w.List.MarshalBinary()
+	if !bytes.Equal(data, redata) {
+		t.Errorf("Decoded ISkipList has different level structure")
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	rand := pcg.NewPCG32()
+	rand.Seed(randSeed1, randSeed2)
+
+	var buf bytes.Buffer
+	var lists []*ISkipList
+	for _, n := range []int{0, 1, 100, 5000} {
+		sl := new(ISkipList)
+		sl.Seed(randSeed1, uint64(n))
+		for i := 0; i < n; i++ {
+			sl.PushBack(i)
+		}
+		mutateRandomly(sl, rand)
+		if err := sl.Encode(&buf); err != nil {
+			t.Fatalf("Unexpected error from Encode: %v", err)
+		}
+		lists = append(lists, sl)
+	}
+
+	// bytes.Buffer implements io.ByteReader, so the lists can be decoded
+	// one after the other.
+	for _, sl := range lists {
+		d, err := Decode(&buf)
+		if err != nil {
+			t.Fatalf("Unexpected error from Decode: %v", err)
+		}
+		data, _ := sl.MarshalBinary()
+		ddata, _ := d.MarshalBinary()
+		if !bytes.Equal(data, ddata) || !sl.Equal(d) {
+			t.Errorf("Decoded ISkipList differs from original")
+		}
+	}
+	if _, err := Decode(&buf); err != io.EOF {
+		t.Errorf("Expected io.EOF at end of stream, got %v", err)
+	}
+
+	data, _ := lists[2].MarshalBinary()
+	if _, err := Decode(bytes.NewReader(data[:len(data)-1])); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected io.ErrUnexpectedEOF decoding truncated data, got %v", err)
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	for _, pcg64 := range []bool{false, true} {
+		var sl ISkipList
+		if pcg64 {
+			sl.SeedPCG64(1, 2, 3, 4)
+		} else {
+			sl.Seed(randSeed1, randSeed2)
+		}
+		sl.SetMaxLength(800)
+		for i := 0; i < 500; i++ {
+			sl.PushBack(i)
+		}
+		sl.At(300)
+
+		c, err := sl.Checkpoint()
+		if err != nil {
+			t.Fatalf("Unexpected error from Checkpoint: %v", err)
+		}
+
+		run := func(sl *ISkipList) ([]byte, []ElemType) {
+			rand := pcg.NewPCG32()
+			rand.Seed(randSeed1, randSeed2)
+			for i := 0; i < 200; i++ {
+				mutateRandomly(sl, rand)
+				sl.Insert(int(rand.Bounded(uint32(sl.Length()+1))), i)
+			}
+			data, _ := sl.MarshalBinary()
+			return data, toSlice(sl)
+		}
+
+		data1, elems1 := run(&sl)
+		sl.Restore(c)
+		if sl.Length() != 500 || sl.MaxLength() != 800 {
+			t.Fatalf("Restored ISkipList has length %v and max length %v", sl.Length(), sl.MaxLength())
+		}
+		data2, elems2 := run(&sl)
+		if !bytes.Equal(data1, data2) || fmt.Sprint(elems1) != fmt.Sprint(elems2) {
+			t.Errorf("ISkipList did not behave identically after being restored (pcg64=%v)", pcg64)
+		}
+
+		// The same checkpoint can be restored into a different ISkipList.
+		var other ISkipList
+		other.Restore(c)
+		data3, elems3 := run(&other)
+		if !bytes.Equal(data1, data3) || fmt.Sprint(elems1) != fmt.Sprint(elems3) {
+			t.Errorf("Other ISkipList did not behave identically after being restored (pcg64=%v)", pcg64)
+		}
+	}
+
+	var custom ISkipList
+	custom.SetLevelSource(&cyclicLevelSource{values: []uint32{0}})
+	if _, err := custom.Checkpoint(); err == nil {
+		t.Errorf("Expected error checkpointing ISkipList with a custom LevelSource")
+	}
+}
+
+func TestMarshalCompact(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 10000; i++ {
+		sl.PushBack(i * 3)
+	}
+	sl.Set(5000, -7)
+
+	data := sl.MarshalCompact()
+	full, _ := sl.MarshalBinary()
+	if len(data) >= len(full)/2 {
+		t.Errorf("Compact serialization of %v bytes is not much smaller than full serialization of %v bytes", len(data), len(full))
+	}
+
+	var a, b ISkipList
+	b.PushBack(1)
+	if err := a.UnmarshalCompact(data); err != nil {
+		t.Fatalf("Unexpected error from UnmarshalCompact: %v", err)
+	}
+	if err := b.UnmarshalCompact(data); err != nil {
+		t.Fatalf("Unexpected error from UnmarshalCompact: %v", err)
+	}
+	if !a.Equal(&sl) {
+		t.Errorf("Restored ISkipList has different elements from original")
+	}
+	adata, _ := a.MarshalBinary()
+	bdata, _ := b.MarshalBinary()
+	if !bytes.Equal(adata, bdata) {
+		t.Errorf("Loading the same compact serialization gave different structures")
+	}
+
+	for i := 0; i < len(data); i++ {
+		if err := a.UnmarshalCompact(data[:i]); err == nil {
+			t.Errorf("Expected error unmarshaling data truncated to %v bytes", i)
+		}
+	}
+	if !a.Equal(&sl) {
+		t.Errorf("ISkipList modified by failed UnmarshalCompact")
+	}
+
+	var empty ISkipList
+	if err := a.UnmarshalCompact(empty.MarshalCompact()); err != nil || a.Length() != 0 {
+		t.Errorf("Failed to round trip empty ISkipList: %v", err)
+	}
+}
+
+func TestWriteDot(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 50; i++ {
+		sl.PushBack(i * 10)
+	}
+
+	var sb strings.Builder
+	if err := sl.WriteDot(&sb); err != nil {
+		t.Fatalf("Unexpected error from WriteDot: %v", err)
+	}
+	dot := sb.String()
+	if !strings.HasPrefix(dot, "digraph ISkipList {\n") || !strings.HasSuffix(dot, "}\n") {
+		t.Errorf("Unexpected DOT output:\n%v", dot)
+	}
+	for i := 0; i < 50; i++ {
+		if !strings.Contains(dot, fmt.Sprintf("n0_%v [label=\"%v: %v\"];", i, i, i*10)) {
+			t.Errorf("DOT output missing node for element %v", i)
+		}
+	}
+	nodes, decls := 0, 0
+	for _, c := range sl.LevelHistogram() {
+		nodes += c
+	}
+	for _, line := range strings.Split(dot, "\n") {
+		if strings.HasPrefix(line, "\tn") && line[2] >= '0' && line[2] <= '9' && !strings.Contains(line, "->") {
+			decls++
+		}
+	}
+	if decls != nodes {
+		t.Errorf("DOT output declares %v nodes, expected %v", decls, nodes)
+	}
+
+	var empty ISkipList
+	sb.Reset()
+	if err := empty.WriteDot(&sb); err != nil || !strings.Contains(sb.String(), "length 0") {
+		t.Errorf("Unexpected DOT output for empty ISkipList: %v\n%v", err, sb.String())
+	}
+}
+
+func TestStats(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 10000; i++ {
+		sl.PushBack(i)
+	}
+
+	s := sl.Stats()
+	if s.Depth.Length != 10000 || len(s.AverageSpan) != len(s.Depth.Levels) {
+		t.Fatalf("Unexpected stats:\n%v", s)
+	}
+	if s.AverageSpan[0] != 1 {
+		t.Errorf("Average span on densest level is %v", s.AverageSpan[0])
+	}
+	if s.AverageSpan[1] < 2 || s.AverageSpan[1] > 3.5 {
+		t.Errorf("Average span on level 1 is %v, expected about e", s.AverageSpan[1])
+	}
+	sl.At(5000)
+	if s = sl.Stats(); s.CacheIndex != 5000 {
+		t.Errorf("Expected cached index 5000, got %v", s.CacheIndex)
+	}
+	if !strings.Contains(s.String(), "cache: index 5000") {
+		t.Errorf("Unexpected formatting of stats:\n%v", s)
+	}
+
+	var empty ISkipList
+	if s := empty.Stats(); s.Depth.Length != 0 || len(s.AverageSpan) != 0 || s.CacheIndex != -1 {
+		t.Errorf("Unexpected stats for empty ISkipList:\n%v", s)
+	}
+}
+
+func TestNodeCountAndMemoryFootprint(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	empty := sl.MemoryFootprint()
+	if sl.NodeCount() != 0 {
+		t.Errorf("Empty ISkipList has %v nodes", sl.NodeCount())
+	}
+
+	for i := 0; i < 10000; i++ {
+		sl.PushBack(i)
+	}
+	expected := 0
+	for _, c := range sl.LevelHistogram() {
+		expected += c
+	}
+	if n := sl.NodeCount(); n != expected {
+		t.Errorf("NodeCount returned %v, expected %v", n, expected)
+	}
+	// On average there are about 1.58 nodes per element.
+	if n := sl.NodeCount(); n < 15000 || n > 16600 {
+		t.Errorf("Implausible node count %v", n)
+	}
+	if m := sl.MemoryFootprint(); m < empty+sl.NodeCount()*int(unsafe.Sizeof(listNode{})) {
+		t.Errorf("MemoryFootprint returned %v, which doesn't account for all nodes", m)
+	}
+}
+
+func TestTelemetry(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 10000; i++ {
+		sl.PushBack(i)
+	}
+	if sl.HasTelemetry() || sl.Telemetry().Searches != 0 {
+		t.Errorf("Telemetry unexpectedly enabled")
+	}
+
+	sl.EnableTelemetry()
+	// Sequential access should mostly start from the cache.
+	for i := 100; i < 1100; i++ {
+		sl.At(i)
+	}
+	tel := sl.Telemetry()
+	if tel.Searches != 1000 {
+		t.Errorf("Expected 1000 searches, got %v", tel.Searches)
+	}
+	if tel.CacheHits+tel.CacheMisses != 1000 || tel.CacheHitRate() < 0.99 {
+		t.Errorf("Unexpected cache hits %v and misses %v", tel.CacheHits, tel.CacheMisses)
+	}
+	var total uint64
+	for _, c := range tel.PathLengths {
+		total += c
+	}
+	if total != tel.Searches {
+		t.Errorf("Path length histogram counts %v searches, expected %v", total, tel.Searches)
+	}
+	if tel.LongestPath == 0 || tel.HopsPerSearch() > float64(tel.LongestPath) {
+		t.Errorf("Implausible longest path %v (mean %v)", tel.LongestPath, tel.HopsPerSearch())
+	}
+
+	// Backward access can't use the cache.
+	sl.ResetTelemetry()
+	for i := 5000; i > 4000; i-- {
+		sl.At(i)
+	}
+	tel = sl.Telemetry()
+	if tel.Searches != 1000 || tel.CacheMisses < 999 {
+		t.Errorf("Unexpected telemetry for backward access: %+v", tel)
+	}
+
+	sl.DisableTelemetry()
+	sl.At(20)
+	if sl.HasTelemetry() || sl.Telemetry().Searches != 0 {
+		t.Errorf("Telemetry recorded after being disabled")
+	}
+}
+
+type countingMetricsSink struct {
+	inserts, removes int
+	searches         SearchCost
+}
+
+func (m *countingMetricsSink) OnInsert(n int)        { m.inserts += n }
+func (m *countingMetricsSink) OnRemove(n int)        { m.removes += n }
+func (m *countingMetricsSink) OnSearch(c SearchCost) { m.searches.add(&c) }
+
+func TestMetricsSink(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	var m countingMetricsSink
+	sl.SetMetricsSink(&m)
+	sl.EnableTelemetry()
+
+	for i := 0; i < 1000; i++ {
+		sl.Insert(i/2, i)
+	}
+	sl.AppendSlice([]ElemType{1, 2, 3})
+	sl.Remove(500)
+	sl.Truncate(900)
+	sl.RemoveIf(func(e ElemType) bool { return e%10 == 0 })
+	sl.Sort(func(a, b ElemType) bool { return a < b })
+	sl.At(450)
+	if m.inserts != 1003 {
+		t.Errorf("Expected 1003 insertions, got %v", m.inserts)
+	}
+	if m.removes != 1003-sl.Length() {
+		t.Errorf("Expected %v removals, got %v", 1003-sl.Length(), m.removes)
+	}
+	if m.searches.Searches == 0 || m.searches.HopsPerSearch() == 0 {
+		t.Errorf("No searches reported")
+	}
+	if tel := sl.Telemetry(); m.searches != tel.SearchCost {
+		t.Errorf("Reported search costs %+v differ from telemetry %+v", m.searches, tel.SearchCost)
+	}
+
+	sl.Clear()
+	if m.removes != 1003 {
+		t.Errorf("Expected 1003 removals after Clear, got %v", m.removes)
+	}
+
+	sl.SetMetricsSink(nil)
+	sl.PushBack(1)
+	if m.inserts != 1003 {
+		t.Errorf("Insertion reported after MetricsSink removed")
+	}
+}
+
+func TestDebugDump(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 200; i++ {
+		sl.PushBack(i * 2)
+	}
+
+	dump := sl.DebugDump()
+	hist := sl.LevelHistogram()
+	if len(dump) != len(hist) {
+		t.Fatalf("Dump has %v levels, expected %v", len(dump), len(hist))
+	}
+	byID := make(map[int]NodeDump)
+	for h, level := range dump {
+		if level.Level != h || len(level.Nodes) != hist[h] {
+			t.Errorf("Level %v of dump has %v nodes, expected %v", h, len(level.Nodes), hist[h])
+		}
+		for _, nd := range level.Nodes {
+			byID[nd.ID] = nd
+			if nd.Elem != nd.Index*2 {
+				t.Errorf("Node %v has element %v, expected %v", nd.ID, nd.Elem, nd.Index*2)
+			}
+		}
+	}
+	for _, level := range dump {
+		for _, nd := range level.Nodes {
+			if nd.Next != -1 && byID[nd.Next].Index != nd.Index+nd.Span {
+				t.Errorf("Node %v has span %v but next node has index %v", nd.ID, nd.Span, byID[nd.Next].Index)
+			}
+			if (nd.Down == -1) != (level.Level == 0) || (nd.Down != -1 && byID[nd.Down].Index != nd.Index) {
+				t.Errorf("Node %v has wrong node below", nd.ID)
+			}
+		}
+	}
+
+	js, err := json.Marshal(dump)
+	if err != nil {
+		t.Fatalf("Unexpected error marshaling dump: %v", err)
+	}
+	cp := sl.Copy()
+	js2, _ := json.Marshal(cp.DebugDump())
+	if !bytes.Equal(js, js2) {
+		t.Errorf("Dumps of ISkipLists with the same structure differ")
+	}
+
+	var empty ISkipList
+	if js, _ := json.Marshal(empty.DebugDump()); string(js) != "[]" {
+		t.Errorf("Unexpected dump of empty ISkipList: %s", js)
+	}
+}
+
+func TestRebalance(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 20000; i++ {
+		sl.PushBack(i)
+	}
+	sl.EnableValueIndex()
+	// Removing elements never reduces the number of levels.
+	for sl.Length() > 50 {
+		sl.Remove(sl.Length() / 2)
+	}
+	before := toSlice(&sl)
+	levelsBefore := len(sl.LevelHistogram())
+
+	sl.Rebalance()
+	if err := sl.Validate(); err != nil {
+		t.Fatalf("Invalid ISkipList after Rebalance: %v", err)
+	}
+	if fmt.Sprint(toSlice(&sl)) != fmt.Sprint(before) {
+		t.Errorf("Rebalance changed elements")
+	}
+	if levels := len(sl.LevelHistogram()); levels >= levelsBefore {
+		t.Errorf("Rebalance didn't reduce the number of levels (%v before, %v after)", levelsBefore, levels)
+	}
+	if sl.DepthReport().Suspicious {
+		t.Errorf("Suspicious level structure after Rebalance:\n%v", sl.DepthReport())
+	}
+	if i := sl.IndexOf(before[30]); i != 30 {
+		t.Errorf("Value index gave %v after Rebalance, expected 30", i)
+	}
+
+	var empty ISkipList
+	empty.Rebalance()
+	if empty.Length() != 0 {
+		t.Errorf("Rebalance modified empty ISkipList")
+	}
+}
+
+func TestTruncateShrinkInvalidatesCache(t *testing.T) {
+	var sl ISkipList
+	sl.Seed(randSeed1, randSeed2)
+	for i := 0; i < 2000; i++ {
+		sl.PushBack(i)
+	}
+	sl.At(10)
+	ops := make([]sliceutils.Op, 1988)
+	for i := range ops {
+		ops[i] = sliceutils.Op{Kind: sliceutils.OpRemove, Index1: 12}
+	}
+	sl.ApplyOps(ops)
+	if e := sl.At(11); e != 11 {
+		t.Errorf("Expected 11, got %v", e)
+	}
+	if err := sl.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The same applies to a direct call to Truncate.
+	var sl2 ISkipList
+	sl2.Seed(randSeed1, randSeed2)
+	for i := 0; i < 2000; i++ {
+		sl2.PushBack(i)
+	}
+	sl2.At(10)
+	sl2.Truncate(12)
+	if err := sl2.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if e := sl2.At(11); e != 11 {
+		t.Errorf("Expected 11, got %v", e)
 	}
 }
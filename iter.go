@@ -0,0 +1,88 @@
+package iskiplist
+
+import (
+	"fmt"
+	"iter"
+)
+
+// Values returns an iterator over the values of the elements in a range of the
+// ISkipList, for use with range-over-func loops:
+//
+//	for v := range l.Values(from, to) {
+//		...
+//	}
+//
+// The bounds are checked as for IterateRange() when Values is called. The
+// elements are yielded directly from the densest level of the ISkipList, so
+// (unlike copying the range to a slice with CopyRangeToSlice()) the amount of
+// memory allocated doesn't depend on the length of the range. Elements must
+// not be added to or removed from the ISkipList during iteration (see
+// IterateRange()).
+func (l *ISkipList) Values(from, to int) iter.Seq[ElemType] {
+	if from < 0 || from > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", from, l))
+	}
+	if to < 0 || to > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", to, l))
+	}
+
+	return func(yield func(ElemType) bool) {
+		if to <= from {
+			return
+		}
+		// getTo rather than retrieve, as the latter allocates.
+		mods := l.mods.get()
+		node := getTo(l.root, from, searchObserver(l))
+		for i := from; i < to; i++ {
+			if !yield(node.elem) {
+				return
+			}
+			checkNotModified(l, mods)
+			node = node.next
+		}
+	}
+}
+
+// Range returns an iterator over the indices and values of the elements in a
+// range of the ISkipList, for use with range-over-func loops:
+//
+//	for i, v := range l.Range(from, to) {
+//		...
+//	}
+//
+// It is otherwise the same as Values().
+func (l *ISkipList) Range(from, to int) iter.Seq2[int, ElemType] {
+	if from < 0 || from > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", from, l))
+	}
+	if to < 0 || to > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", to, l))
+	}
+
+	return func(yield func(int, ElemType) bool) {
+		if to <= from {
+			return
+		}
+		mods := l.mods.get()
+		node := getTo(l.root, from, searchObserver(l))
+		for i := from; i < to; i++ {
+			if !yield(i, node.elem) {
+				return
+			}
+			checkNotModified(l, mods)
+			node = node.next
+		}
+	}
+}
+
+// All(), which returns an iterator over the values of all the elements of the
+// ISkipList, is a shorthand for l.Values(0, l.Length()).
+func (l *ISkipList) All() iter.Seq[ElemType] {
+	return l.Values(0, l.length)
+}
+
+// AllI(), which returns an iterator over the indices and values of all the
+// elements of the ISkipList, is a shorthand for l.Range(0, l.Length()).
+func (l *ISkipList) AllI() iter.Seq2[int, ElemType] {
+	return l.Range(0, l.length)
+}
@@ -0,0 +1,365 @@
+package iskiplist
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Serialized ISkipLists start with this magic string followed by a format
+// version byte. The rest of the format is as follows (all integers are
+// varints, signed for elements and unsigned otherwise):
+//
+//	length
+//	number of levels - 1
+//	PCG32 state and increment
+//	for each element: height (one byte), element
+//
+// The height of an element is the number of sparse levels on which it
+// appears. The first element appears on every level.
+const marshalMagic = "ISKL"
+const marshalVersion = 1
+
+// MarshalBinary implements encoding.BinaryMarshaler. It serializes the
+// elements of the ISkipList together with its level structure and the state
+// of its built-in PCG32 generator, so that UnmarshalBinary() restores an
+// identical ISkipList that goes on to behave identically. Optional features
+// such as the value index are not serialized, and nor is the state of any
+// other generator (see SeedPCG64() and SetLevelSource()). The result is
+// typically a little more than one byte per element plus the size of the
+// varint encoding of the element. MarshalBinary runs in O(n) time and never
+// returns an error. To serialize a large ISkipList without holding the
+// serialization in memory, use Encode().
+func (l *ISkipList) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 0, len(marshalMagic)+1+4*binary.MaxVarintLen64+2*l.length)
+	data = appendHeader(data, l)
+	walkHeights(l, func(e ElemType, height uint8) {
+		data = append(data, height)
+		data = binary.AppendVarint(data, int64(elemToDist(e)))
+	})
+	return data, nil
+}
+
+// Encode writes the same serialization of the ISkipList as MarshalBinary() to
+// w. The elements are streamed directly from the ISkipList, so the memory
+// used by Encode doesn't depend on the length of the ISkipList. Writes to w
+// are buffered. The serialization can be read using Decode().
+func (l *ISkipList) Encode(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	var buf [1 + binary.MaxVarintLen64]byte
+	bw.Write(appendHeader(buf[:0], l))
+	walkHeights(l, func(e ElemType, height uint8) {
+		buf[0] = height
+		n := binary.PutVarint(buf[1:], int64(elemToDist(e)))
+		// Any error is sticky and is returned by Flush.
+		bw.Write(buf[:1+n])
+	})
+	return bw.Flush()
+}
+
+func appendHeader(data []byte, l *ISkipList) []byte {
+	data = append(data, marshalMagic...)
+	data = append(data, marshalVersion)
+	data = binary.AppendUvarint(data, uint64(l.length))
+	data = binary.AppendUvarint(data, uint64(l.nLevels))
+	state, increment := l.rand.State()
+	data = binary.AppendUvarint(data, state)
+	return binary.AppendUvarint(data, increment)
+}
+
+// walkHeights calls f with each element of the ISkipList in order, together
+// with the number of sparse levels on which it appears. It keeps a cursor on
+// each sparse level, so that the levels are walked in a single pass.
+func walkHeights(l *ISkipList, f func(e ElemType, height uint8)) {
+	if l.root == nil {
+		return
+	}
+
+	// The next node (and its index) on each sparse level, indexed by height
+	// above the densest level.
+	cursors := make([]*listNode, l.nLevels+1)
+	indices := make([]int, l.nLevels+1)
+	n := l.root
+	for h := l.nLevels; h > 0; h-- {
+		cursors[h] = n
+		n = n.nextLevel
+	}
+
+	i := 0
+	for ; n != nil; n = n.next {
+		// The sparse levels are nested, so the node appears on every level
+		// up to the first on which it doesn't appear.
+		h := 0
+		for h+1 < len(cursors) && cursors[h+1] != nil && indices[h+1] == i {
+			h++
+			if c := cursors[h]; c.next != nil {
+				indices[h] += elemToDist(c.elem)
+				cursors[h] = c.next
+			} else {
+				cursors[h] = nil
+			}
+		}
+		f(n.elem, uint8(h))
+		i++
+	}
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. It replaces the
+// contents of the ISkipList with those serialized by MarshalBinary(),
+// restoring the level structure and the state of the built-in PCG32 generator
+// without drawing any random numbers. Optional features enabled for the
+// ISkipList remain enabled and are updated to reflect the new contents. An
+// error is returned (and the ISkipList is not modified) if the data are
+// malformed. UnmarshalBinary runs in O(n) time.
+func (l *ISkipList) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	d, err := decode(r)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+	if r.Len() != 0 {
+		return errors.New("malformed serialized ISkipList: trailing data")
+	}
+
+	if tracing(l) {
+		trace(l, "UnmarshalBinary", d.length)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	clearList(l)
+	l.root = d.root
+	l.nLevels = d.nLevels
+	l.length = d.length
+	l.rand = d.rand
+	noteInsertRange(l, 0, l.length)
+	return nil
+}
+
+// Decode reads an ISkipList serialized by Encode() or MarshalBinary() from r
+// and returns it. The elements are added to the ISkipList as they are read,
+// so no intermediate slice is materialized. If r implements io.ByteReader
+// then Decode reads exactly the bytes of the serialization, so that several
+// ISkipLists can be read from the same stream; otherwise r is buffered and
+// Decode may read beyond the end of the serialization. Decode returns io.EOF
+// if r is empty, and io.ErrUnexpectedEOF if the serialization is truncated.
+func Decode(r io.Reader) (*ISkipList, error) {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return decode(br)
+}
+
+func decode(r io.ByteReader) (*ISkipList, error) {
+	var header [len(marshalMagic) + 1]byte
+	for i := range header {
+		b, err := r.ReadByte()
+		if err == io.EOF && i > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		header[i] = b
+	}
+	if string(header[:len(marshalMagic)]) != marshalMagic {
+		return nil, errors.New("not a serialized ISkipList")
+	}
+	if v := header[len(marshalMagic)]; v != marshalVersion {
+		return nil, fmt.Errorf("unsupported ISkipList serialization version %v", v)
+	}
+
+	var err error
+	uvarint := func() uint64 {
+		if err != nil {
+			return 0
+		}
+		var v uint64
+		v, err = binary.ReadUvarint(r)
+		return v
+	}
+	unexpectedEOF := func() (*ISkipList, error) {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	length := uvarint()
+	nLevels := uvarint()
+	state := uvarint()
+	increment := uvarint()
+	if err != nil {
+		return unexpectedEOF()
+	}
+	if length > math.MaxInt || (length > 0 && nLevels >= maxLevels) || (length == 0 && nLevels != 0) {
+		return nil, fmt.Errorf("malformed serialized ISkipList: invalid length %v or number of levels %v", length, nLevels+1)
+	}
+
+	l := new(ISkipList)
+	l.rand.SetState(state, increment)
+	if length == 0 {
+		return l, nil
+	}
+
+	var chain chainBuilder
+	levels := newLevelBuilder(int(nLevels))
+	for i := 0; i < int(length); i++ {
+		var h byte
+		h, err = r.ReadByte()
+		if err != nil {
+			return unexpectedEOF()
+		}
+		if uint64(h) > nLevels || (i == 0 && uint64(h) != nLevels) {
+			return nil, fmt.Errorf("malformed serialized ISkipList: invalid height %v for element at index %v", h, i)
+		}
+		var e int64
+		e, err = binary.ReadVarint(r)
+		if err != nil {
+			return unexpectedEOF()
+		}
+		chain.add(distToElem(int(e)))
+		levels.add(chain.last, int(h))
+	}
+	levels.finish(l)
+	l.length = int(length)
+	return l, nil
+}
+
+// GobEncode implements gob.GobEncoder, so that ISkipLists can be sent over
+// RPC or cached on disk using encoding/gob. The encoding is that of
+// MarshalBinary().
+func (l *ISkipList) GobEncode() ([]byte, error) {
+	return l.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder. See UnmarshalBinary().
+func (l *ISkipList) GobDecode(data []byte) error {
+	return l.UnmarshalBinary(data)
+}
+
+// Compact serializations start with this magic string followed by a format
+// version byte, the length, and the PCG32 state and increment (as unsigned
+// varints). Each element is then stored as a signed varint giving the
+// difference between it and the preceding element (or zero, for the first
+// element).
+const compactMagic = "ISKC"
+const compactVersion = 1
+
+// MarshalCompact returns a compact serialization of the ISkipList that, unlike
+// MarshalBinary(), omits the level structure and stores each element as the
+// varint-encoded difference from the preceding element. When the ISkipList
+// holds increasing indices into a backing slice, most elements take a single
+// byte. UnmarshalCompact() rebuilds the level structure using the saved state
+// of the built-in PCG32 generator, so loading the same serialization always
+// produces the same structure (although not the structure of the original
+// ISkipList). If the generator has not yet been seeded, the loaded ISkipList
+// is seeded automatically and its structure is not deterministic. Optional
+// features and other generators (see SeedPCG64() and SetLevelSource()) are
+// not serialized. MarshalCompact runs in O(n) time.
+func (l *ISkipList) MarshalCompact() []byte {
+	data := make([]byte, 0, len(compactMagic)+1+3*binary.MaxVarintLen64+l.length)
+	data = append(data, compactMagic...)
+	data = append(data, compactVersion)
+	data = binary.AppendUvarint(data, uint64(l.length))
+	state, increment := l.rand.State()
+	data = binary.AppendUvarint(data, state)
+	data = binary.AppendUvarint(data, increment)
+	prev := int64(0)
+	for node := densest(l); node != nil; node = node.next {
+		e := int64(elemToDist(node.elem))
+		data = binary.AppendVarint(data, e-prev)
+		prev = e
+	}
+	return data
+}
+
+// UnmarshalCompact replaces the contents of the ISkipList with those
+// serialized by MarshalCompact(), rebuilding the level structure as described
+// there. The ISkipList's own generator is not used, and its state is replaced
+// by the state following the rebuild. Optional features enabled for the
+// ISkipList remain enabled and are updated to reflect the new contents. An
+// error is returned (and the ISkipList is not modified) if the data are
+// malformed. UnmarshalCompact runs in O(n) time.
+func (l *ISkipList) UnmarshalCompact(data []byte) error {
+	r := bytes.NewReader(data)
+	var header [len(compactMagic) + 1]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	if string(header[:len(compactMagic)]) != compactMagic {
+		return errors.New("not a compact serialized ISkipList")
+	}
+	if v := header[len(compactMagic)]; v != compactVersion {
+		return fmt.Errorf("unsupported compact ISkipList serialization version %v", v)
+	}
+
+	var err error
+	uvarint := func() uint64 {
+		if err != nil {
+			return 0
+		}
+		var v uint64
+		v, err = binary.ReadUvarint(r)
+		return v
+	}
+	unexpectedEOF := func() error {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	length := uvarint()
+	state := uvarint()
+	increment := uvarint()
+	if err != nil {
+		return unexpectedEOF()
+	}
+	// Each element takes at least one byte.
+	if length > uint64(r.Len()) {
+		return fmt.Errorf("malformed compact serialized ISkipList: length %v exceeds available data", length)
+	}
+
+	var chain chainBuilder
+	prev := int64(0)
+	for i := uint64(0); i < length; i++ {
+		var d int64
+		d, err = binary.ReadVarint(r)
+		if err != nil {
+			return unexpectedEOF()
+		}
+		prev += d
+		chain.add(distToElem(int(prev)))
+	}
+	if r.Len() != 0 {
+		return errors.New("malformed compact serialized ISkipList: trailing data")
+	}
+
+	if tracing(l) {
+		trace(l, "UnmarshalCompact", chain.n)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	var tmp ISkipList
+	tmp.rand.SetState(state, increment)
+	rebuild(&tmp, chain.first, chain.n, &tmp)
+	clearList(l)
+	l.root = tmp.root
+	l.nLevels = tmp.nLevels
+	l.length = tmp.length
+	l.rand = tmp.rand
+	noteInsertRange(l, 0, l.length)
+	return nil
+}
@@ -0,0 +1,40 @@
+package iskiplist
+
+// A MetricsSink is notified of elements being added to and removed from an
+// ISkipList, and of searches through it. It provides a way to feed the
+// behavior of an ISkipList into an existing metrics pipeline (e.g.
+// Prometheus or expvar) without this package depending on a metrics library.
+// See SetMetricsSink().
+//
+// The methods of a MetricsSink are called synchronously while the ISkipList
+// is being modified or searched, so they should be cheap (e.g. incrementing a
+// counter or observing a histogram), and they must not access the ISkipList.
+type MetricsSink interface {
+	// OnInsert is called when n elements have been added to the ISkipList.
+	// Bulk operations may report their elements one at a time.
+	OnInsert(n int)
+	// OnRemove is called when n elements have been removed from the
+	// ISkipList, including elements evicted to enforce a maximum length.
+	OnRemove(n int)
+	// OnSearch is called following each search through the ISkipList for the
+	// node at a given index, and following each lookup in the index cache,
+	// with the resulting increments to the counts described by SearchCost.
+	// Summing the SearchCosts reported for an ISkipList gives the counts that
+	// Telemetry() would report for it.
+	OnSearch(c SearchCost)
+}
+
+// SetMetricsSink sets a MetricsSink that is notified of elements being added
+// to and removed from the ISkipList, and of searches through it. Elements
+// moved within the ISkipList (e.g. by Sort() or MoveElement()) and elements
+// replaced by Set() are not reported. Passing nil removes the MetricsSink.
+// Copies of an ISkipList do not inherit the MetricsSink.
+func (l *ISkipList) SetMetricsSink(m MetricsSink) {
+	if m == nil {
+		if l.ext != nil {
+			l.ext.metrics = nil
+		}
+		return
+	}
+	getExt(l).metrics = m
+}
@@ -0,0 +1,112 @@
+package iskiplist
+
+// kmpTable returns the Knuth-Morris-Pratt failure function for a pattern:
+// table[i] is the length of the longest proper prefix of pattern[:i+1] that is
+// also a suffix of it.
+func kmpTable(pattern []ElemType) []int {
+	table := make([]int, len(pattern))
+	k := 0
+	for i := 1; i < len(pattern); i++ {
+		for k > 0 && pattern[i] != pattern[k] {
+			k = table[k-1]
+		}
+		if pattern[i] == pattern[k] {
+			k++
+		}
+		table[i] = k
+	}
+	return table
+}
+
+// kmpFind returns the index of the first occurrence of a non-empty pattern
+// that starts at or after index 'from', where 'node' is the densest-level node
+// at index 'from', or -1 if there is no such occurrence.
+func kmpFind(from int, node *listNode, pattern []ElemType, table []int) int {
+	k := 0
+	for i := from; node != nil; i, node = i+1, node.next {
+		for k > 0 && node.elem != pattern[k] {
+			k = table[k-1]
+		}
+		if node.elem == pattern[k] {
+			k++
+		}
+		if k == len(pattern) {
+			return i - len(pattern) + 1
+		}
+	}
+	return -1
+}
+
+// IndexOfSubsequence returns the index of the first occurrence of the elements
+// of 'pattern' as a contiguous sequence of elements of the ISkipList, or -1 if
+// there is no such occurrence. An empty pattern occurs at index 0. The
+// ISkipList is scanned once using the Knuth-Morris-Pratt algorithm, so
+// IndexOfSubsequence runs in O(n + len(pattern)) time and doesn't copy the
+// ISkipList.
+func (l *ISkipList) IndexOfSubsequence(pattern []ElemType) int {
+	if len(pattern) == 0 {
+		return 0
+	}
+	if len(pattern) > l.length {
+		return -1
+	}
+	return kmpFind(0, densestAt(l, 0), pattern, kmpTable(pattern))
+}
+
+// ReplaceSubsequence replaces occurrences of the elements of 'pattern' as a
+// contiguous sequence of elements of the ISkipList with the elements of
+// 'repl', and returns the number of occurrences replaced. Occurrences are
+// found from left to right and do not overlap. The search resumes after each
+// replacement, so elements inserted by a replacement are never matched. If
+// maxN is non-negative, at most maxN occurrences are replaced. An empty
+// pattern matches nothing. Each replacement splices the ISkipList (see
+// InsertListAt()) rather than inserting and removing elements individually,
+// so ReplaceSubsequence runs in O(n + r(log n + len(repl))) time, where r is
+// the number of occurrences replaced.
+func (l *ISkipList) ReplaceSubsequence(pattern, repl []ElemType, maxN int) int {
+	if tracing(l) {
+		trace(l, "ReplaceSubsequence", pattern, repl, maxN)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	if len(pattern) == 0 || len(pattern) > l.length {
+		return 0
+	}
+
+	table := kmpTable(pattern)
+	replaced := 0
+	from := 0
+	for (maxN < 0 || replaced < maxN) && from+len(pattern) <= l.length {
+		i := kmpFind(from, densestAt(l, from), pattern, table)
+		if i == -1 {
+			break
+		}
+		checkCapacity(l, len(repl)-len(pattern))
+		removeRange(l, i, i+len(pattern))
+		replaced++
+
+		from = i
+		if len(repl) > 0 {
+			var b chainBuilder
+			for _, e := range repl {
+				b.add(e)
+			}
+			before := l.length
+			insertListAt(l, i, b.build(l))
+			from += len(repl)
+			// Account for any evictions made to enforce the maximum length.
+			if evicted := before + len(repl) - l.length; evicted > 0 && i != 0 {
+				from -= evicted
+			}
+			if from < 0 {
+				from = 0
+			}
+			if from > l.length {
+				from = l.length
+			}
+		}
+	}
+	return replaced
+}
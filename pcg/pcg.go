@@ -37,6 +37,20 @@ func (p *Pcg32) IsUninitialized() bool {
 	return p.state == 0
 }
 
+// State returns the internal state of the generator. Passing the result to
+// SetState() restores the generator to the same point in its sequence.
+// (Added by addrummond.)
+func (p *Pcg32) State() (state, increment uint64) {
+	return p.state, p.increment
+}
+
+// SetState sets the internal state of the generator to a value previously
+// returned by State(). (Added by addrummond.)
+func (p *Pcg32) SetState(state, increment uint64) {
+	p.state = state
+	p.increment = increment
+}
+
 func NewPCG32() *Pcg32 {
 	return &Pcg32{pcg32State, pcg32Increment}
 }
@@ -94,3 +108,49 @@ func (p *Pcg32) advanceLCG64(state, delta, curMult, curPlus uint64) uint64 {
 	}
 	return accMult*state + accPlus
 }
+
+// StreamLength is the number of values in each of the streams returned by
+// NthStream(). (Added by addrummond.)
+const StreamLength = 1 << 32
+
+// NthStream returns a generator for the nth of 2^32 non-overlapping streams of
+// StreamLength values, each of which is a contiguous segment of the sequence
+// produced by a generator seeded with (state, sequence). This allows (e.g.)
+// parallel test shards to derive reproducible, non-overlapping sequences from
+// a single master seed. It runs in O(log n) time. (Added by addrummond.)
+func NthStream(state, sequence uint64, n uint32) *Pcg32 {
+	return NewPCG32().Seed(state, sequence).Advance(uint64(n) * StreamLength)
+}
+
+// Random64 returns a uniformly distributed 64-bit value composed of two
+// successive 32-bit values. (Added by addrummond.)
+func (p *Pcg32) Random64() uint64 {
+	hi := uint64(p.Random())
+	return hi<<32 | uint64(p.Random())
+}
+
+// Float64 returns a uniformly distributed float64 in the half-open interval
+// [0.0, 1.0). (Added by addrummond.)
+func (p *Pcg32) Float64() float64 {
+	return float64(p.Random64()>>11) * (1.0 / (1 << 53))
+}
+
+// Int63n returns a uniformly distributed value in the half-open interval
+// [0, n), without modulo bias. It panics if n <= 0. (Added by addrummond.)
+func (p *Pcg32) Int63n(n int64) int64 {
+	return int63n(p.Random64, n)
+}
+
+func int63n(random64 func() uint64, n int64) int64 {
+	if n <= 0 {
+		panic("invalid argument to Int63n")
+	}
+	// Reject values in the incomplete final segment of the range.
+	max := int64((1 << 63) - 1 - (1<<63)%uint64(n))
+	for {
+		v := int64(random64() >> 1)
+		if v <= max {
+			return v % n
+		}
+	}
+}
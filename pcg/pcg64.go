@@ -0,0 +1,147 @@
+package pcg
+
+// PCG64 (XSL RR 128/64 variant) added by addrummond. This follows the
+// reference implementation in pcg_variants.h from the PCG C library
+// (http://www.pcg-random.org). The 128-bit LCG state gives a period of 2^128,
+// and each of the 2^127 streams is selected by the increment.
+
+import "math/bits"
+
+const (
+	pcg64StateHigh      = 0x979c9a98d8462005
+	pcg64StateLow       = 0x7d3e9cb6cfe0549b
+	pcg64IncrementHigh  = 0x0000000000000001
+	pcg64IncrementLow   = 0xda3e39cb94b95bdb
+	pcg64MultiplierHigh = 0x2360ed051fc65da4
+	pcg64MultiplierLow  = 0x4385df649fccf645
+)
+
+type Pcg64 struct {
+	stateHigh, stateLow         uint64
+	incrementHigh, incrementLow uint64
+}
+
+// IsUninitialized returns true iff the Pcg64 struct has not been initialized.
+func (p *Pcg64) IsUninitialized() bool {
+	// The increment must be odd, so it's zero only if uninitialized.
+	return p.incrementLow == 0
+}
+
+func NewPCG64() *Pcg64 {
+	return &Pcg64{pcg64StateHigh, pcg64StateLow, pcg64IncrementHigh, pcg64IncrementLow}
+}
+
+// Seed seeds the generator with a 128-bit initial state and a 128-bit stream
+// selector (of which the top bit is ignored).
+func (p *Pcg64) Seed(stateHigh, stateLow, sequenceHigh, sequenceLow uint64) *Pcg64 {
+	p.incrementHigh = (sequenceHigh << 1) | (sequenceLow >> 63)
+	p.incrementLow = (sequenceLow << 1) | 1
+	p.stateHigh, p.stateLow = 0, 0
+	p.step()
+	var carry uint64
+	p.stateLow, carry = bits.Add64(p.stateLow, stateLow, 0)
+	p.stateHigh, _ = bits.Add64(p.stateHigh, stateHigh, carry)
+	p.step()
+	return p
+}
+
+func mul128(aHigh, aLow, bHigh, bLow uint64) (uint64, uint64) {
+	hi, lo := bits.Mul64(aLow, bLow)
+	hi += aHigh*bLow + aLow*bHigh
+	return hi, lo
+}
+
+func add128(aHigh, aLow, bHigh, bLow uint64) (uint64, uint64) {
+	lo, carry := bits.Add64(aLow, bLow, 0)
+	hi, _ := bits.Add64(aHigh, bHigh, carry)
+	return hi, lo
+}
+
+func (p *Pcg64) step() {
+	p.stateHigh, p.stateLow = mul128(p.stateHigh, p.stateLow, pcg64MultiplierHigh, pcg64MultiplierLow)
+	p.stateHigh, p.stateLow = add128(p.stateHigh, p.stateLow, p.incrementHigh, p.incrementLow)
+}
+
+func (p *Pcg64) Random() uint64 {
+	p.step()
+	return bits.RotateLeft64(p.stateHigh^p.stateLow, -int(p.stateHigh>>58))
+}
+
+func (p *Pcg64) Bounded(bound uint64) uint64 {
+	if bound == 0 {
+		return 0
+	}
+	threshold := -bound % bound
+	for {
+		r := p.Random()
+		if r >= threshold {
+			return r % bound
+		}
+	}
+}
+
+func (p *Pcg64) Advance(delta uint64) *Pcg64 {
+	p.advanceWith(delta, pcg64MultiplierHigh, pcg64MultiplierLow, p.incrementHigh, p.incrementLow)
+	return p
+}
+
+// Retreat moves the generator back by delta steps. Since the period is 2^128,
+// this is implemented by advancing by 2^128 - delta.
+func (p *Pcg64) Retreat(delta uint64) *Pcg64 {
+	// For delta > 0, 2^128 - delta = (2^64 - 1) * 2^64 + (2^64 - delta). We
+	// first advance by 2^64 - 1 jumps of 2^64 steps each.
+	if delta == 0 {
+		return p
+	}
+	stepMultHigh, stepMultLow, stepPlusHigh, stepPlusLow := pcg64Jump64(p.incrementHigh, p.incrementLow)
+	p.advanceWith(^uint64(0), stepMultHigh, stepMultLow, stepPlusHigh, stepPlusLow)
+	return p.Advance(-delta)
+}
+
+// pcg64Jump64 returns the multiplier and increment of the LCG that advances
+// the state by 2^64 steps.
+func pcg64Jump64(incHigh, incLow uint64) (uint64, uint64, uint64, uint64) {
+	curMultHigh, curMultLow := uint64(pcg64MultiplierHigh), uint64(pcg64MultiplierLow)
+	curPlusHigh, curPlusLow := incHigh, incLow
+	for i := 0; i < 64; i++ {
+		h, l := add128(curMultHigh, curMultLow, 0, 1)
+		curPlusHigh, curPlusLow = mul128(h, l, curPlusHigh, curPlusLow)
+		curMultHigh, curMultLow = mul128(curMultHigh, curMultLow, curMultHigh, curMultLow)
+	}
+	return curMultHigh, curMultLow, curPlusHigh, curPlusLow
+}
+
+func (p *Pcg64) advanceWith(delta, curMultHigh, curMultLow, curPlusHigh, curPlusLow uint64) {
+	accMultHigh, accMultLow := uint64(0), uint64(1)
+	accPlusHigh, accPlusLow := uint64(0), uint64(0)
+	for delta > 0 {
+		if delta&1 != 0 {
+			accMultHigh, accMultLow = mul128(accMultHigh, accMultLow, curMultHigh, curMultLow)
+			accPlusHigh, accPlusLow = mul128(accPlusHigh, accPlusLow, curMultHigh, curMultLow)
+			accPlusHigh, accPlusLow = add128(accPlusHigh, accPlusLow, curPlusHigh, curPlusLow)
+		}
+		h, l := add128(curMultHigh, curMultLow, 0, 1)
+		curPlusHigh, curPlusLow = mul128(h, l, curPlusHigh, curPlusLow)
+		curMultHigh, curMultLow = mul128(curMultHigh, curMultLow, curMultHigh, curMultLow)
+		delta /= 2
+	}
+	p.stateHigh, p.stateLow = mul128(accMultHigh, accMultLow, p.stateHigh, p.stateLow)
+	p.stateHigh, p.stateLow = add128(p.stateHigh, p.stateLow, accPlusHigh, accPlusLow)
+}
+
+// Random64 is a synonym for Random, provided for consistency with Pcg32.
+func (p *Pcg64) Random64() uint64 {
+	return p.Random()
+}
+
+// Float64 returns a uniformly distributed float64 in the half-open interval
+// [0.0, 1.0).
+func (p *Pcg64) Float64() float64 {
+	return float64(p.Random()>>11) * (1.0 / (1 << 53))
+}
+
+// Int63n returns a uniformly distributed value in the half-open interval
+// [0, n), without modulo bias. It panics if n <= 0.
+func (p *Pcg64) Int63n(n int64) int64 {
+	return int63n(p.Random, n)
+}
@@ -0,0 +1,42 @@
+package pcg
+
+import "testing"
+
+func TestPcg64ReferenceOutput(t *testing.T) {
+	// First outputs of the reference pcg64 demo program (seeded with 42, 54).
+	expected := []uint64{0x86b1da1d72062b68, 0x1304aa46c9853d39, 0xa3670e9e0dd50358, 0xf9090e529a7dae00, 0xc85b9fd837996f2c, 0x606121f8e3919196}
+	p := NewPCG64().Seed(0, 42, 0, 54)
+	for i, e := range expected {
+		if r := p.Random(); r != e {
+			t.Errorf("Output %v is %x, expected %x\n", i, r, e)
+		}
+	}
+
+	p.Retreat(uint64(len(expected)))
+	p.Advance(2)
+	if r := p.Random(); r != expected[2] {
+		t.Errorf("Unexpected output %x following Retreat/Advance\n", r)
+	}
+}
+
+func TestFloat64AndInt63n(t *testing.T) {
+	p := NewPCG32().Seed(12345, 67891)
+	q := NewPCG64().Seed(1, 2, 3, 4)
+	var counts [10]int
+	for i := 0; i < 10000; i++ {
+		f1, f2 := p.Float64(), q.Float64()
+		if f1 < 0 || f1 >= 1 || f2 < 0 || f2 >= 1 {
+			t.Fatalf("Float64 out of range: %v %v\n", f1, f2)
+		}
+		n1, n2 := p.Int63n(10), q.Int63n(10)
+		if n1 < 0 || n1 >= 10 || n2 < 0 || n2 >= 10 {
+			t.Fatalf("Int63n out of range: %v %v\n", n1, n2)
+		}
+		counts[n1]++
+	}
+	for i, c := range counts {
+		if c < 800 || c > 1200 {
+			t.Errorf("Suspicious count %v for value %v\n", c, i)
+		}
+	}
+}
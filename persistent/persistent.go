@@ -0,0 +1,438 @@
+// Package persistent provides an immutable counterpart to ISkipList. Every
+// operation that would mutate an ISkipList instead returns a new List, leaving
+// the original unchanged. The new List shares all but O(log n) of its nodes
+// with the original, so keeping old versions around is cheap, and Lists can be
+// shared between goroutines without locking. Versioned is built on List.
+//
+// A List is a skip list whose nodes have the same layout as those of an
+// ISkipList: an element (or, on a sparse level, the number of elements that
+// the node spans), a 'next' pointer and a 'nextLevel' pointer. An ordinary
+// skip list can't be path copied, as updating a node's 'next' pointer requires
+// a copy of every node that points to it, and so on all the way back to the
+// start of the level. A List therefore cuts each level into runs. The nodes
+// below a sparse node that lie within its span form a run, and the last node
+// of a run has a nil 'next' pointer instead of pointing on to the first node
+// of the following run. Every node then has exactly one incoming pointer, and
+// an update need only copy the nodes from the start of each run on the path
+// down to the affected element. Runs have O(1) expected length, so this is
+// O(log n) nodes in all. As with ISkipList, the first node is present on every
+// level, and the levels of the other nodes are drawn from a PCG32 generator
+// whose state is carried along from each List to its successors.
+package persistent
+
+import (
+	"fmt"
+
+	"github.com/addrummond/iskiplist/v2"
+	"github.com/addrummond/iskiplist/v2/pcg"
+)
+
+// ElemType is the type of an element of a List.
+type ElemType = iskiplist.ElemType
+
+const (
+	defaultSeed1 = 0x853c49e6748fea9b
+	defaultSeed2 = 0xda3e39cb94b95bdb
+)
+
+// This is approximately (1/e)*UINT32_MAX, the same value that is used by
+// ISkipList.
+const pWithUint32Denom = 1580030168
+
+const maxLevels = 30
+
+// Nodes are never modified once they have been linked into a List.
+type node struct {
+	elem      ElemType // elem if on densest level; number of elements spanned otherwise
+	next      *node    // nil for the last node of a run
+	nextLevel *node    // the first node of the run below
+}
+
+// List is an immutable indexable sequence. The zero value is an empty List, and
+// a nil *List may be used wherever an empty List is expected.
+type List struct {
+	root    *node // the first node of the sparsest level
+	nLevels int   // the number of sparse levels
+	length  int
+	rand    pcg.Pcg32
+}
+
+// Empty returns an empty List whose random number generator is seeded with the
+// specified values.
+func Empty(seed1, seed2 uint64) *List {
+	var l List
+	l.rand.Seed(seed1|1, seed2)
+	return &l
+}
+
+// FromSlice returns a List containing the elements of a slice.
+func FromSlice(elems []ElemType) *List {
+	l := Empty(defaultSeed1, defaultSeed2)
+	for _, e := range elems {
+		l = l.PushBack(e)
+	}
+	return l
+}
+
+// successor returns an empty List whose generator state follows on from l's.
+func successor(l *List) *List {
+	var nl List
+	if l != nil {
+		nl.rand = l.rand
+	}
+	if nl.rand.IsUninitialized() {
+		nl.rand.Seed(defaultSeed1, defaultSeed2)
+	}
+	return &nl
+}
+
+// withStructure returns a successor of l with the specified structure,
+// removing any sparsest levels that have only one node.
+func withStructure(l *List, root *node, nLevels, length int) *List {
+	nl := successor(l)
+	for nLevels > 0 && root.next == nil {
+		root = root.nextLevel
+		nLevels--
+	}
+	nl.root, nl.nLevels, nl.length = root, nLevels, length
+	return nl
+}
+
+func randomLevels(l *List) int {
+	n := 0
+	for n < maxLevels-1 && l.rand.Random() < pWithUint32Denom {
+		n++
+	}
+	return n
+}
+
+// raise adds sparse levels above the root of a List of the specified length
+// until it has 'to' sparse levels.
+func raise(root *node, nLevels, length, to int) *node {
+	for ; nLevels < to; nLevels++ {
+		root = &node{elem: length, nextLevel: root}
+	}
+	return root
+}
+
+// copyRun copies the nodes of a run from 'first' up to but not including
+// 'stop' (which may be nil to copy the rest of the run). It returns the first
+// and last copies, or nil if there are none. The last copy still points to
+// 'stop'.
+func copyRun(first, stop *node) (head, last *node) {
+	for n := first; n != stop; n = n.next {
+		c := *n
+		if last == nil {
+			head = &c
+		} else {
+			last.next = &c
+		}
+		last = &c
+	}
+	return head, last
+}
+
+// nth returns the node at offset j of a run on the densest level.
+func nth(first *node, j int) *node {
+	for ; j > 0; j-- {
+		first = first.next
+	}
+	return first
+}
+
+// find returns the node of a run on a sparse level whose span contains offset
+// j of the run, together with the offset at which its span starts.
+func find(first *node, j int) (*node, int) {
+	n, pos := first, 0
+	for n.next != nil && pos+n.elem <= j {
+		pos += n.elem
+		n = n.next
+	}
+	return n, pos
+}
+
+func set(first *node, level, j int, v ElemType) *node {
+	if level == 0 {
+		target := nth(first, j)
+		head, last := copyRun(first, target.next)
+		last.elem = v
+		return head
+	}
+	c, pos := find(first, j)
+	head, last := copyRun(first, c.next)
+	last.nextLevel = set(c.nextLevel, level-1, j-pos, v)
+	return head
+}
+
+// insert returns a copy of a run with an element inserted at offset j, where
+// j >= 1. The new element appears on 'height' sparse levels. If it appears
+// on the level above too, the run is cut before the new element's node,
+// which is returned as the second result.
+func insert(first *node, level, j int, e ElemType, height int) (*node, *node) {
+	var head, last, n *node
+	if level == 0 {
+		p := nth(first, j-1)
+		head, last = copyRun(first, p.next)
+		n = &node{elem: e, next: p.next}
+		last.next = n
+	} else {
+		c, pos := find(first, j-1)
+		head, last = copyRun(first, c.next)
+		below, belowTail := insert(c.nextLevel, level-1, j-pos, e, height)
+		last.nextLevel = below
+		if height >= level {
+			n = &node{elem: pos + c.elem + 1 - j, next: c.next, nextLevel: belowTail}
+			last.elem = j - pos
+			last.next = n
+		} else {
+			last.elem++
+		}
+	}
+	if height > level {
+		last.next = nil
+		return head, n
+	}
+	return head, nil
+}
+
+// join links the run starting at b onto p, which is a fresh copy of the last
+// node of the preceding run on the same level. The first node of b keeps its
+// 'height' lowest sparse levels, and a height of -1 removes its element
+// altogether. On the levels above, its span is merged into p's, and the runs
+// below the two nodes are joined in turn.
+func join(p, b *node, level, height int) {
+	if level <= height {
+		p.next = b
+		return
+	}
+	p.next = b.next
+	if level == 0 {
+		return
+	}
+	p.elem += b.elem
+	if height < 0 {
+		p.elem--
+	}
+	head, last := copyRun(p.nextLevel, nil)
+	join(last, b.nextLevel, level-1, height)
+	p.nextLevel = head
+}
+
+// remove returns a copy of a run with the element at offset j removed, where
+// j >= 1.
+func remove(first *node, level, j int) *node {
+	if level == 0 {
+		p := nth(first, j-1)
+		head, last := copyRun(first, p.next)
+		last.next = p.next.next
+		return head
+	}
+	c, pos := find(first, j)
+	if pos == j {
+		// The removed element's node is on this level, so its span is merged
+		// into that of the preceding node.
+		head, last := copyRun(first, c)
+		join(last, c, level, -1)
+		return head
+	}
+	head, last := copyRun(first, c.next)
+	last.elem--
+	last.nextLevel = remove(c.nextLevel, level-1, j-pos)
+	return head
+}
+
+// split splits a run into copies of its first j nodes and the remainder,
+// where j >= 1 and j is less than the number of elements spanned by the run.
+func split(first *node, level, j int) (*node, *node) {
+	if level == 0 {
+		p := nth(first, j-1)
+		head, last := copyRun(first, p.next)
+		last.next = nil
+		return head, p.next
+	}
+	c, pos := find(first, j)
+	if pos == j {
+		head, last := copyRun(first, c)
+		last.next = nil
+		return head, c
+	}
+	head, last := copyRun(first, c.next)
+	left, right := split(c.nextLevel, level-1, j-pos)
+	last.elem = j - pos
+	last.nextLevel = left
+	last.next = nil
+	return head, &node{elem: pos + c.elem - j, next: c.next, nextLevel: right}
+}
+
+// Length returns the length of the List. It runs in constant time.
+func (l *List) Length() int {
+	if l == nil {
+		return 0
+	}
+	return l.length
+}
+
+func checkIndex(l *List, i int) {
+	if i < 0 || i >= l.Length() {
+		panic(fmt.Sprintf("Out of bounds index %v into List of length %v", i, l.Length()))
+	}
+}
+
+// At retrieves the element at the specified index.
+func (l *List) At(i int) ElemType {
+	checkIndex(l, i)
+	n := l.root
+	for level := l.nLevels; level > 0; level-- {
+		c, pos := find(n, i)
+		i -= pos
+		n = c.nextLevel
+	}
+	return nth(n, i).elem
+}
+
+// Set returns a List with the element at the specified index replaced.
+func (l *List) Set(i int, v ElemType) *List {
+	checkIndex(l, i)
+	return withStructure(l, set(l.root, l.nLevels, i, v), l.nLevels, l.length)
+}
+
+// Insert returns a List with an element inserted before the element at the
+// specified index, or at the end if the index is equal to the length of the
+// List.
+func (l *List) Insert(index int, elem ElemType) *List {
+	if index < 0 || index > l.Length() {
+		panic("Index out of range in call to 'Insert'")
+	}
+	if l.Length() == 0 {
+		return withStructure(l, &node{elem: elem}, 0, 1)
+	}
+	if index == 0 {
+		// The first node is present on every level. As in
+		// ISkipList.PushFront(), rather than giving the new element all these
+		// levels, we put it in place of the old first element, which is then
+		// reinserted with a random number of levels.
+		return l.Set(0, elem).Insert(1, l.At(0))
+	}
+
+	nl := successor(l)
+	height := randomLevels(nl)
+	nLevels := max(l.nLevels, height)
+	root := raise(l.root, l.nLevels, l.length, nLevels)
+	root, _ = insert(root, nLevels, index, elem, height)
+	nl.root, nl.nLevels, nl.length = root, nLevels, l.length+1
+	return nl
+}
+
+// Remove returns a List with the element at the specified index removed,
+// together with the value of the removed element.
+func (l *List) Remove(index int) (*List, ElemType) {
+	checkIndex(l, index)
+	e := l.At(index)
+	if l.length == 1 {
+		return successor(l), e
+	}
+	if index == 0 {
+		// The first node is present on every level, so the second element
+		// takes its place.
+		nl, _ := l.Set(0, l.At(1)).Remove(1)
+		return nl, e
+	}
+	return withStructure(l, remove(l.root, l.nLevels, index), l.nLevels, l.length-1), e
+}
+
+// PushFront returns a List with an element added at the beginning.
+func (l *List) PushFront(elem ElemType) *List {
+	return l.Insert(0, elem)
+}
+
+// PushBack returns a List with an element added at the end.
+func (l *List) PushBack(elem ElemType) *List {
+	return l.Insert(l.Length(), elem)
+}
+
+// Slice returns a List containing the elements in the range [from, to).
+func (l *List) Slice(from, to int) *List {
+	if from < 0 || from > l.Length() {
+		panic(fmt.Sprintf("Out of bounds index %v into List of length %v", from, l.Length()))
+	}
+	if to < 0 || to > l.Length() {
+		panic(fmt.Sprintf("Out of bounds index %v into List of length %v", to, l.Length()))
+	}
+	if to <= from {
+		return successor(l)
+	}
+	root := l.root
+	if to < l.length {
+		root, _ = split(root, l.nLevels, to)
+	}
+	if from > 0 {
+		_, root = split(root, l.nLevels, from)
+	}
+	return withStructure(l, root, l.nLevels, to-from)
+}
+
+// Concat returns a List containing the elements of l followed by the elements
+// of other.
+func (l *List) Concat(other *List) *List {
+	if other.Length() == 0 {
+		return withStructure(l, l.rootNode(), l.levels(), l.Length())
+	}
+	if l.Length() == 0 {
+		return withStructure(l, other.root, other.nLevels, other.length)
+	}
+
+	// As in ISkipList.Append(), the first node of 'other' is given a random
+	// number of levels, so that repeatedly concatenating short Lists doesn't
+	// give a degenerate structure.
+	nl := successor(l)
+	height := randomLevels(nl)
+	nLevels := max(l.nLevels, other.nLevels, height)
+	a := raise(l.root, l.nLevels, l.length, nLevels)
+	b := raise(other.root, other.nLevels, other.length, nLevels)
+	head, last := copyRun(a, nil)
+	join(last, b, nLevels, height)
+	return withStructure(nl, head, nLevels, l.length+other.length)
+}
+
+func (l *List) rootNode() *node {
+	if l == nil {
+		return nil
+	}
+	return l.root
+}
+
+func (l *List) levels() int {
+	if l == nil {
+		return 0
+	}
+	return l.nLevels
+}
+
+func iterate(first *node, level int, f func(ElemType) bool) bool {
+	for n := first; n != nil; n = n.next {
+		if level == 0 {
+			if !f(n.elem) {
+				return false
+			}
+		} else if !iterate(n.nextLevel, level-1, f) {
+			return false
+		}
+	}
+	return true
+}
+
+// Iterate passes each element of the List to the supplied function in order,
+// halting if the function returns false.
+func (l *List) Iterate(f func(ElemType) bool) {
+	iterate(l.rootNode(), l.levels(), f)
+}
+
+// ToSlice returns a slice containing the elements of the List.
+func (l *List) ToSlice() []ElemType {
+	r := make([]ElemType, 0, l.Length())
+	l.Iterate(func(e ElemType) bool {
+		r = append(r, e)
+		return true
+	})
+	return r
+}
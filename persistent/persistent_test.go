@@ -0,0 +1,232 @@
+package persistent
+
+import (
+	"math"
+	"testing"
+
+	"github.com/addrummond/iskiplist/v2/sliceutils"
+)
+
+const (
+	randSeed1 = 12345
+	randSeed2 = 67891
+)
+
+func equal(a, b []ElemType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// This test applies a random sequence of operations to a List, keeping every
+// intermediate version along with a slice copy of its expected contents. Old
+// versions must be unaffected by subsequent operations.
+func TestRandomOpSequences(t *testing.T) {
+	const nops = 1000
+
+	l := Empty(randSeed1, randSeed2)
+	var versions []*List
+	var expected [][]ElemType
+	a := make([]ElemType, 0)
+	for _, o := range sliceutils.GenOps(nops, 0) {
+		sliceutils.ApplyOpToSlice(&o, &a)
+		switch o.Kind {
+		case sliceutils.OpInsert:
+			l = l.Insert(o.Index1, o.Elem)
+		case sliceutils.OpRemove:
+			var e ElemType
+			l, e = l.Remove(o.Index1)
+			if e != expected[len(expected)-1][o.Index1] {
+				t.Errorf("Remove returned unexpected value %v\n", e)
+			}
+		case sliceutils.OpSwap:
+			v1, v2 := l.At(o.Index1), l.At(o.Index2)
+			l = l.Set(o.Index1, v2).Set(o.Index2, v1)
+		}
+
+		cp := make([]ElemType, len(a))
+		copy(cp, a)
+		versions = append(versions, l)
+		expected = append(expected, cp)
+	}
+
+	for i, v := range versions {
+		if !equal(v.ToSlice(), expected[i]) {
+			t.Fatalf("Version %v has unexpected contents\n", i)
+		}
+		for j, e := range expected[i] {
+			if v.At(j) != e {
+				t.Fatalf("Version %v has unexpected value at %v\n", i, j)
+			}
+		}
+	}
+}
+
+func TestSliceAndConcat(t *testing.T) {
+	a := make([]ElemType, 100)
+	for i := range a {
+		a[i] = i
+	}
+	l := FromSlice(a)
+	for i := 0; i <= 100; i += 7 {
+		for j := i; j <= 100; j += 11 {
+			if !equal(l.Slice(i, j).ToSlice(), a[i:j]) {
+				t.Errorf("Slice(%v, %v) has unexpected contents\n", i, j)
+			}
+			c := l.Slice(0, i).Concat(l.Slice(i, 100))
+			if !equal(c.ToSlice(), a) {
+				t.Errorf("Concat at %v has unexpected contents\n", i)
+			}
+		}
+	}
+
+	var empty *List
+	if empty.Length() != 0 || empty.PushBack(1).At(0) != 1 {
+		t.Errorf("Unexpected behavior of nil List\n")
+	}
+}
+
+// checkRuns checks that the nodes of a run each span the number of elements
+// in the run below them, and returns the number of elements in the run.
+func checkRuns(t *testing.T, first *node, level int, nodes map[*node]bool) int {
+	t.Helper()
+	total := 0
+	for n := first; n != nil; n = n.next {
+		if nodes[n] {
+			t.Fatalf("Node on level %v has more than one incoming pointer\n", level)
+		}
+		nodes[n] = true
+		if level == 0 {
+			if n.nextLevel != nil {
+				t.Fatalf("Node on densest level has a nextLevel pointer\n")
+			}
+			total++
+			continue
+		}
+		if n.nextLevel == nil {
+			t.Fatalf("Node on level %v has no run below it\n", level)
+		}
+		if below := checkRuns(t, n.nextLevel, level-1, nodes); below != n.elem {
+			t.Fatalf("Node on level %v spans %v elements, expected %v\n", level, n.elem, below)
+		}
+		total += n.elem
+	}
+	return total
+}
+
+// checkStructure checks the structure of a List and returns its nodes.
+func checkStructure(t *testing.T, l *List) map[*node]bool {
+	t.Helper()
+	nodes := make(map[*node]bool)
+	if l.root == nil {
+		if l.length != 0 || l.nLevels != 0 {
+			t.Fatalf("Empty List has length %v and %v levels\n", l.length, l.nLevels)
+		}
+		return nodes
+	}
+	if n := checkRuns(t, l.root, l.nLevels, nodes); n != l.length {
+		t.Fatalf("List has %v elements, expected %v\n", n, l.length)
+	}
+	if l.nLevels > 0 && l.root.next == nil {
+		t.Fatalf("Sparsest level has only one node\n")
+	}
+	return nodes
+}
+
+// Lists built up one element (or one short List) at a time should have the
+// same level structure as an ISkipList of the same length.
+func TestLevels(t *testing.T) {
+	const n = 20000
+	builds := []struct {
+		what string
+		add  func(l *List, i int) *List
+	}{
+		{"PushBack", func(l *List, i int) *List { return l.PushBack(ElemType(i)) }},
+		{"PushFront", func(l *List, i int) *List { return l.PushFront(ElemType(i)) }},
+		{"Insert", func(l *List, i int) *List { return l.Insert(i/2, ElemType(i)) }},
+		{"Concat", func(l *List, i int) *List { return l.Concat(Empty(uint64(i), randSeed2).PushBack(ElemType(i))) }},
+	}
+	for _, b := range builds {
+		l := Empty(randSeed1, randSeed2)
+		for i := 0; i < n; i++ {
+			l = b.add(l, i)
+		}
+		checkStructure(t, l)
+		if l.Length() != n || l.nLevels < int(math.Log(n))-2 {
+			t.Errorf("%v: List of length %v has %v sparse levels\n", b.what, l.Length(), l.nLevels)
+		}
+
+		// Removing elements keeps the structure intact.
+		for i := 0; i < n/2; i++ {
+			l, _ = l.Remove((i * 7919) % l.Length())
+		}
+		checkStructure(t, l)
+	}
+}
+
+// An update copies only the nodes on the path to the affected element.
+func TestPathCopying(t *testing.T) {
+	const n = 20000
+	l := Empty(randSeed1, randSeed2)
+	for i := 0; i < n; i++ {
+		l = l.PushBack(ElemType(i))
+	}
+	old := checkStructure(t, l)
+
+	updates := map[string]*List{
+		"Set":    l.Set(n/2, -1),
+		"Insert": l.Insert(n/3, -1),
+		"Remove": func() *List { r, _ := l.Remove(n / 4); return r }(),
+		"Slice":  l.Slice(n/5, n/2),
+		"Concat": l.Concat(FromSlice([]ElemType{1, 2, 3})),
+	}
+	for what, u := range updates {
+		copied := 0
+		for nd := range checkStructure(t, u) {
+			if !old[nd] {
+				copied++
+			}
+		}
+		if copied > 100 {
+			t.Errorf("%v copied %v nodes of a List of length %v\n", what, copied, n)
+		}
+	}
+	for i, e := range l.ToSlice() {
+		if e != ElemType(i) {
+			t.Fatalf("Original List was modified at index %v\n", i)
+		}
+	}
+}
+
+func TestVersioned(t *testing.T) {
+	var v Versioned
+	var ids []VersionID
+	for i := 0; i < 100; i++ {
+		v.PushBack(ElemType(i))
+		if i%10 == 0 {
+			v.Set(0, ElemType(-i))
+		}
+		ids = append(ids, v.SaveVersion())
+	}
+
+	for i, id := range ids {
+		l := v.AtVersion(id)
+		if l.Length() != i+1 || l.At(i) != ElemType(i) || l.At(0) != ElemType(-(i/10)*10) {
+			t.Errorf("Version %v has unexpected contents %v\n", id, l.ToSlice())
+		}
+	}
+
+	v.Restore(ids[5])
+	if v.Length() != 6 {
+		t.Errorf("Unexpected length %v after Restore\n", v.Length())
+	}
+	if v.AtVersion(ids[99]).Length() != 100 {
+		t.Errorf("Later version lost after Restore\n")
+	}
+}
@@ -0,0 +1,110 @@
+package persistent
+
+import "fmt"
+
+// VersionID identifies a version saved by Versioned.SaveVersion().
+type VersionID int
+
+// Versioned is a mutable sequence that can save snapshots of its contents.
+// It holds a current List, which is replaced on each mutation, together with
+// every List saved via SaveVersion(). Since successive Lists share structure,
+// the memory required to keep a version is proportional to the changes made
+// since the previous version rather than to its length. The zero value is an
+// empty Versioned with no saved versions.
+//
+// Unlike a List, a Versioned must not be mutated concurrently from multiple
+// goroutines. The Lists returned by Current() and AtVersion() can be freely
+// shared, however.
+type Versioned struct {
+	current  *List
+	versions []*List
+}
+
+// NewVersioned returns a Versioned whose current contents are the specified
+// List.
+func NewVersioned(l *List) *Versioned {
+	return &Versioned{current: l}
+}
+
+// Current returns the current contents of the Versioned.
+func (v *Versioned) Current() *List {
+	return v.current
+}
+
+// Length returns the length of the current contents.
+func (v *Versioned) Length() int {
+	return v.current.Length()
+}
+
+// At retrieves the element at the specified index of the current contents.
+func (v *Versioned) At(i int) ElemType {
+	return v.current.At(i)
+}
+
+// Set updates the element at the specified index of the current contents.
+func (v *Versioned) Set(i int, elem ElemType) {
+	v.current = v.current.Set(i, elem)
+}
+
+// Insert inserts an element into the current contents. See List.Insert().
+func (v *Versioned) Insert(index int, elem ElemType) {
+	v.current = v.current.Insert(index, elem)
+}
+
+// Remove removes an element from the current contents and returns its value.
+func (v *Versioned) Remove(index int) ElemType {
+	var e ElemType
+	v.current, e = v.current.Remove(index)
+	return e
+}
+
+// PushFront adds an element to the beginning of the current contents.
+func (v *Versioned) PushFront(elem ElemType) {
+	v.current = v.current.PushFront(elem)
+}
+
+// PushBack adds an element to the end of the current contents.
+func (v *Versioned) PushBack(elem ElemType) {
+	v.current = v.current.PushBack(elem)
+}
+
+// SaveVersion saves the current contents and returns an ID that can be passed
+// to AtVersion() to retrieve them. It runs in constant time.
+func (v *Versioned) SaveVersion() VersionID {
+	v.versions = append(v.versions, v.current)
+	return VersionID(len(v.versions) - 1)
+}
+
+func checkVersion(v *Versioned, id VersionID) {
+	if id < 0 || int(id) >= len(v.versions) || v.versions[id] == nil {
+		panic(fmt.Sprintf("Unknown or discarded version %v", id))
+	}
+}
+
+// AtVersion returns the contents saved by the call to SaveVersion() that
+// returned the specified ID. For example, v.AtVersion(id).At(i) retrieves the
+// element that was at index i when the version was saved.
+func (v *Versioned) AtVersion(id VersionID) *List {
+	checkVersion(v, id)
+	return v.versions[id]
+}
+
+// Restore makes the contents saved with the specified version ID current.
+// Versions saved subsequently remain available.
+func (v *Versioned) Restore(id VersionID) {
+	checkVersion(v, id)
+	v.current = v.versions[id]
+}
+
+// DiscardVersion releases a saved version so that any structure not shared
+// with other versions can be garbage collected. The ID may not be used again.
+func (v *Versioned) DiscardVersion(id VersionID) {
+	checkVersion(v, id)
+	v.versions[id] = nil
+}
+
+// NVersions returns the number of versions that have been saved, including
+// those that have since been discarded.
+func (v *Versioned) NVersions() int {
+	return len(v.versions)
+}
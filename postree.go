@@ -0,0 +1,224 @@
+package iskiplist
+
+import "github.com/addrummond/iskiplist/v2/pcg"
+
+// A posTree is an implicit treap (i.e. a treap keyed by position rather than
+// by value) that mirrors the sequence of elements in an ISkipList. Each node
+// has a parent pointer, so given a node we can work out its current position
+// in O(log n) by walking up to the root. This is something that the skip list
+// itself can't do, as skip list nodes have no links back up to the sparser
+// levels.
+//
+// A posTree is only maintained if an optional feature that requires it (e.g.
+// the value index or element IDs) is enabled.
+
+type posNode struct {
+	left, right, parent *posNode
+	size                int
+	priority            uint32
+	value               ElemType
+	id                  ElemID
+}
+
+type posTree struct {
+	root *posNode
+	rand pcg.Pcg32
+}
+
+const (
+	posTreeSeed1 = 0x9e3779b97f4a7c15
+	posTreeSeed2 = 0xbf58476d1ce4e5b9
+)
+
+func newPosTree() *posTree {
+	var t posTree
+	t.rand.Seed(posTreeSeed1, posTreeSeed2)
+	return &t
+}
+
+func posSize(n *posNode) int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func posFix(n *posNode) {
+	n.size = posSize(n.left) + posSize(n.right) + 1
+	if n.left != nil {
+		n.left.parent = n
+	}
+	if n.right != nil {
+		n.right.parent = n
+	}
+}
+
+// posSplit splits a tree into a tree containing the first k nodes and a tree
+// containing the rest.
+func posSplit(n *posNode, k int) (*posNode, *posNode) {
+	if n == nil {
+		return nil, nil
+	}
+	if posSize(n.left) >= k {
+		l, r := posSplit(n.left, k)
+		n.left = r
+		posFix(n)
+		if l != nil {
+			l.parent = nil
+		}
+		n.parent = nil
+		return l, n
+	}
+	l, r := posSplit(n.right, k-posSize(n.left)-1)
+	n.right = l
+	posFix(n)
+	if r != nil {
+		r.parent = nil
+	}
+	n.parent = nil
+	return n, r
+}
+
+func posMerge(a, b *posNode) *posNode {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if a.priority > b.priority {
+		a.right = posMerge(a.right, b)
+		posFix(a)
+		return a
+	}
+	b.left = posMerge(a, b.left)
+	posFix(b)
+	return b
+}
+
+func (t *posTree) length() int {
+	return posSize(t.root)
+}
+
+func (t *posTree) insert(index int, value ElemType) *posNode {
+	n := &posNode{
+		size:     1,
+		priority: t.rand.Random(),
+		value:    value,
+	}
+	l, r := posSplit(t.root, index)
+	t.root = posMerge(posMerge(l, n), r)
+	t.root.parent = nil
+	return n
+}
+
+func (t *posTree) remove(index int) *posNode {
+	l, r := posSplit(t.root, index)
+	m, r := posSplit(r, 1)
+	t.root = posMerge(l, r)
+	if t.root != nil {
+		t.root.parent = nil
+	}
+	return m
+}
+
+func (t *posTree) at(index int) *posNode {
+	n := t.root
+	for {
+		ls := posSize(n.left)
+		if index < ls {
+			n = n.left
+		} else if index == ls {
+			return n
+		} else {
+			index -= ls + 1
+			n = n.right
+		}
+	}
+}
+
+// indexOf returns the current position of a node in the tree.
+func (t *posTree) indexOf(n *posNode) int {
+	i := posSize(n.left)
+	for n.parent != nil {
+		if n.parent.right == n {
+			i += posSize(n.parent.left) + 1
+		}
+		n = n.parent
+	}
+	return i
+}
+
+// rotate rotates the nodes in [from, to) to the left by k positions, where
+// 0 < k < to-from.
+func (t *posTree) rotate(from, to, k int) {
+	l, r := posSplit(t.root, to)
+	l, m := posSplit(l, from)
+	a, b := posSplit(m, k)
+	t.root = posMerge(posMerge(l, posMerge(b, a)), r)
+	t.root.parent = nil
+}
+
+// swap exchanges the positions of the nodes at indices i and j.
+func (t *posTree) swap(i, j int) {
+	if i == j {
+		return
+	}
+	if i > j {
+		i, j = j, i
+	}
+	rest, c := posSplit(t.root, j+1)
+	rest, y := posSplit(rest, j)
+	a, rest := posSplit(rest, i)
+	x, b := posSplit(rest, 1)
+	t.root = posMerge(posMerge(posMerge(posMerge(a, y), b), x), c)
+	t.root.parent = nil
+}
+
+// nodes returns the nodes of the tree in order.
+func (t *posTree) nodes() []*posNode {
+	nodes := make([]*posNode, 0, t.length())
+	var stack []*posNode
+	n := t.root
+	for n != nil || len(stack) > 0 {
+		for n != nil {
+			stack = append(stack, n)
+			n = n.left
+		}
+		n = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		nodes = append(nodes, n)
+		n = n.right
+	}
+	return nodes
+}
+
+// reorder rebuilds the tree so that it contains the specified nodes (which
+// must be the nodes currently in the tree) in the specified order.
+func (t *posTree) reorder(nodes []*posNode) {
+	var root *posNode
+	for _, n := range nodes {
+		n.left, n.right, n.parent = nil, nil, nil
+		n.size = 1
+		root = posMerge(root, n)
+	}
+	t.root = root
+	if root != nil {
+		root.parent = nil
+	}
+}
+
+// indexOfIn is like indexOf, but returns -1 if n is not in the tree.
+func (t *posTree) indexOfIn(n *posNode) int {
+	i := posSize(n.left)
+	for n.parent != nil {
+		if n.parent.right == n {
+			i += posSize(n.parent.left) + 1
+		}
+		n = n.parent
+	}
+	if n != t.root {
+		return -1
+	}
+	return i
+}
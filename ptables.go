@@ -33,24 +33,18 @@ very very very rarely assign 30 levels to a short skip list.
 */
 
 func nTosses(l *ISkipList) int {
-	// The PCG state has to be odd, so we know that it's uninitialized if the
-	// state is zero.
-	if l.rand.IsUninitialized() {
-		fastSeed(l)
-	}
-
 	// Note that a binary search isn't the way to go here, since the value is
 	// far more likely to be < one of the first few elements of pTable. A linear
 	// search probably isn't quite the probabilistically optimal algorithm, but
 	// it's simple and close enough.
 
-	r := l.rand.Random()
+	r := random(l)
 	for i := 0; i < len(pTable); i++ {
 		if r < pTable[i] {
 			return int(i)
 		}
 	}
-	r = l.rand.Random()
+	r = random(l)
 	for i := 0; i+len(pTable) < maxLevels; i++ {
 		if r < pTable[i] {
 			return i + len(pTable)
@@ -79,7 +73,7 @@ outer:
 			break
 		}
 
-		r := l.rand.Random()
+		r := random(l)
 		if n < 32 {
 			n -= 8
 			for i, p := range pTable8 {
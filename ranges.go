@@ -0,0 +1,204 @@
+package iskiplist
+
+import "fmt"
+
+// CrossSwap swaps the element at index i of the ISkipList with the element at
+// index j of 'other'. It runs in O(log n + log m) time. 'other' may be the same
+// ISkipList, in which case CrossSwap is equivalent to Swap().
+func (l *ISkipList) CrossSwap(i int, other *ISkipList, j int) {
+	l.CrossSwapRange(i, other, j, 1)
+}
+
+// CrossSwapRange swaps the n elements starting at index i of the ISkipList with
+// the n elements starting at index j of 'other'. It finds the start of each
+// range once and then walks the densest level of each ISkipList, so it runs in
+// O(log n + log m + n) time. If 'other' is the same ISkipList, the ranges must
+// not overlap.
+func (l *ISkipList) CrossSwapRange(i int, other *ISkipList, j int, n int) {
+	if n < 0 {
+		panic(fmt.Sprintf("Negative length %v in call to 'CrossSwapRange'", n))
+	}
+	if i < 0 || i+n > l.length {
+		panic(fmt.Sprintf("Out of bounds range [%v, %v) into ISkipList %+v", i, i+n, l))
+	}
+	if j < 0 || j+n > other.length {
+		panic(fmt.Sprintf("Out of bounds range [%v, %v) into ISkipList %+v", j, j+n, other))
+	}
+	if other == l && i != j && i < j+n && j < i+n {
+		panic(fmt.Sprintf("Overlapping ranges [%v, %v) and [%v, %v) in call to 'CrossSwapRange'", i, i+n, j, j+n))
+	}
+
+	if tracing(l) {
+		trace(l, "CrossSwapRange", i, other, j, n)
+	}
+	if other != l && tracing(other) {
+		trace(other, "CrossSwapRange", j, l, i, n)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+		defer assertValid(other)
+	}
+
+	if n == 0 || (other == l && i == j) {
+		return
+	}
+
+	a := retrieve(l, i)
+	b := retrieve(other, j)
+	for k := 0; k < n; k++ {
+		a.elem, b.elem = b.elem, a.elem
+		if other == l {
+			noteSwap(l, i+k, j+k)
+		} else {
+			noteSet(l, i+k, b.elem, a.elem)
+			noteSet(other, j+k, a.elem, b.elem)
+		}
+		a = a.next
+		b = b.next
+	}
+}
+
+// SwapRanges swaps the n elements starting at index i with the n elements
+// starting at index j. The ranges must not overlap. It walks both ranges
+// simultaneously along the densest level, so it runs in O(log n + n) time and
+// does not allocate. As with Swap(), element IDs move with their elements.
+// SwapRanges(i, j, n) is a shorthand for l.CrossSwapRange(i, l, j, n).
+func (l *ISkipList) SwapRanges(i, j, n int) {
+	l.CrossSwapRange(i, l, j, n)
+}
+
+// RotateRange rotates the elements in the range [from, to) to the left by k
+// positions, so that the element at index from+k ends up at index 'from' (as
+// with C++'s std::rotate). A negative k rotates to the right. Values of k
+// outside the range (-(to-from), to-from) wrap around. The range is split off
+// and spliced back together in a different order, so RotateRange runs in
+// O(log n) time.
+func (l *ISkipList) RotateRange(from, to, k int) {
+	if from < 0 || from > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", from, l))
+	}
+	if to < from || to > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", to, l))
+	}
+
+	if tracing(l) {
+		trace(l, "RotateRange", from, to, k)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	n := to - from
+	if n == 0 {
+		return
+	}
+	k %= n
+	if k < 0 {
+		k += n
+	}
+	if k == 0 {
+		return
+	}
+
+	rotateRange(l, from, to, k)
+}
+
+// MoveElement moves the element at index i so that it ends up at index j,
+// shifting the elements in between up or down by one position. The element
+// keeps its identity, so (for example) its ElemID is unchanged if IDs are
+// enabled. The element is moved by splicing rather than by a separate Remove()
+// and Insert(), so MoveElement runs in O(log n) time.
+func (l *ISkipList) MoveElement(i, j int) {
+	if i < 0 || i >= l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", i, l))
+	}
+	if j < 0 || j >= l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", j, l))
+	}
+
+	if tracing(l) {
+		trace(l, "MoveElement", i, j)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	if i < j {
+		rotateRange(l, i, j+1, 1)
+	} else if i > j {
+		rotateRange(l, j, i+1, i-j)
+	}
+}
+
+// rotateRange does the work of RotateRange for 0 < k < to-from.
+func rotateRange(l *ISkipList, from, to, k int) {
+	tail := splitStructure(l, to)
+	mid := splitStructure(l, from)
+	midB := splitStructure(mid, k)
+	joinStructure(l, midB, l)
+	joinStructure(l, mid, l)
+	joinStructure(l, tail, l)
+	noteRotate(l, from, to, k)
+}
+
+// FillFunc sets each element in the range [from, to) to f(i), where i is the
+// index of the element. It finds the start of the range once and then walks
+// the densest level, so it runs in O(log n + (to - from)) time. The function
+// must not modify the ISkipList. If neither 'from' nor 'to' is out of bounds
+// but to <= from, then this is a no-op.
+func (l *ISkipList) FillFunc(from, to int, f func(i int) ElemType) {
+	if from < 0 || from > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", from, l))
+	}
+	if to < 0 || to > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", to, l))
+	}
+
+	if tracing(l) {
+		trace(l, "FillFunc", from, to, f)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	if to <= from {
+		return
+	}
+
+	node := retrieve(l, from)
+	for i := from; i < to; i++ {
+		old := node.elem
+		node.elem = f(i)
+		noteSet(l, i, old, node.elem)
+		node = node.next
+	}
+}
+
+// SetRange sets the elements in the range [from, from+len(values)) to the
+// elements of 'values'. It is the counterpart of CopyRangeToSlice(): the start
+// of the range is found once and then the densest level is walked, so SetRange
+// runs in O(log n + len(values)) time.
+func (l *ISkipList) SetRange(from int, values []ElemType) {
+	if from < 0 || from+len(values) > l.length {
+		panic(fmt.Sprintf("Out of bounds range [%v, %v) into ISkipList %+v", from, from+len(values), l))
+	}
+
+	if tracing(l) {
+		trace(l, "SetRange", from, values)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	if len(values) == 0 {
+		return
+	}
+
+	node := retrieve(l, from)
+	for i, v := range values {
+		old := node.elem
+		node.elem = v
+		noteSet(l, from+i, old, v)
+		node = node.next
+	}
+}
@@ -0,0 +1,637 @@
+package iskiplist
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/addrummond/iskiplist/v2/sliceutils"
+)
+
+// Recordings start with this magic string followed by a format version byte.
+const recordingMagic = "ISKR"
+const recordingVersion = 1
+
+// Opcodes in recordings.
+const (
+	recSeed byte = iota + 1
+	recSeedStream
+	recSetState
+	recSet
+	recRemove
+	recTruncate
+	recPushFront
+	recPushBack
+	recPopFront
+	recPopBack
+	recInsert
+	recSwap
+	recClear
+	recSetMaxLength
+	recUnreplayable
+	recAppendSlice
+	recPrependSlice
+	recInsertSlice
+	recSetRange
+	recRemoveValues
+	recRotateRange
+	recMoveElement
+	recSwapRanges
+	recExtractRange
+	recSplitAt
+	recRebalance
+	recDedup
+	recDeltaInPlace
+	recApplyOps
+	recContents
+)
+
+// recorder is a Tracer that writes a compact record of each operation to a
+// buffered writer.
+type recorder struct {
+	l   *ISkipList
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+
+	// Some operations can only be recorded once they have completed, so they
+	// are recorded when the next operation is traced (or when recording
+	// stops).
+	pendingUpdate   int // index, or -1 if none
+	pendingContents bool
+	pendingState    bool
+}
+
+// StartRecording starts recording every operation that modifies the ISkipList
+// (see SetTracer()) to w, together with the initial state of the ISkipList and
+// its pseudorandom number generator. The recording can be replayed using
+// Replay() or ReplayFile() to reproduce the exact structure of the ISkipList,
+// which makes it possible to attach a reproducer to a bug report. The format
+// is compact (typically a few bytes per operation), but writes to w are
+// buffered, so StopRecording() must be called to flush the recording. It is a
+// good idea to defer the call to StopRecording(), so that the recording is
+// flushed if an operation panics.
+//
+// Recording uses the ISkipList's Tracer, so any Tracer that was previously set
+// is replaced. StartRecording returns an error if the ISkipList uses a
+// generator other than the built-in PCG32 generator (see SeedPCG64() and
+// SetLevelSource()) or if periodic reseeding is enabled (see
+// SetReseedInterval()), as the structure of the ISkipList can then not be
+// reproduced. If the ISkipList is non-empty when recording starts, its
+// elements are recorded, but the structure of the replayed ISkipList may
+// differ from that of the original. For an exact reproduction, start
+// recording immediately after creating the ISkipList. Similarly, operations
+// whose effect depends on a function or on another ISkipList (e.g. RemoveIf()
+// or Append()) are recorded as the resulting elements of the ISkipList, so the
+// structure of the replayed ISkipList may differ from that of the original
+// following such an operation.
+func (l *ISkipList) StartRecording(w io.Writer) error {
+	if l.ext != nil && l.ext.levelSource != nil {
+		return errors.New("cannot record an ISkipList that does not use the built-in PCG32 generator")
+	}
+	if l.ext != nil && l.ext.reseedInterval != 0 {
+		return errors.New("cannot record an ISkipList with periodic reseeding enabled")
+	}
+
+	if l.rand.IsUninitialized() {
+		fastSeed(l)
+	}
+
+	r := &recorder{
+		l:             l,
+		w:             bufio.NewWriter(w),
+		pendingUpdate: -1,
+	}
+	r.w.WriteString(recordingMagic)
+	r.w.WriteByte(recordingVersion)
+	state, increment := l.rand.State()
+	r.uvarint(state)
+	r.uvarint(increment)
+	r.varint(int64(l.MaxLength()))
+	r.contents()
+	if l.length > 0 {
+		// Replaying the initial elements won't consume the same random
+		// numbers as were consumed when they were originally added, so we
+		// record the state again.
+		r.pendingState = true
+	}
+
+	l.SetTracer(r)
+	return nil
+}
+
+// StopRecording stops a recording started by StartRecording(), flushes it, and
+// returns the first error (if any) encountered while writing it. It is a no-op
+// if the ISkipList is not being recorded.
+func (l *ISkipList) StopRecording() error {
+	if l.ext == nil {
+		return nil
+	}
+	r, ok := l.ext.tracer.(*recorder)
+	if !ok {
+		return nil
+	}
+	l.ext.tracer = nil
+	r.flushPending()
+	return r.w.Flush()
+}
+
+// Write errors are sticky for a bufio.Writer, so they can be ignored here and
+// picked up by the final call to Flush().
+
+func (r *recorder) uvarint(v uint64) {
+	n := binary.PutUvarint(r.buf[:], v)
+	r.w.Write(r.buf[:n])
+}
+
+func (r *recorder) varint(v int64) {
+	n := binary.PutVarint(r.buf[:], v)
+	r.w.Write(r.buf[:n])
+}
+
+func (r *recorder) op(code byte, args ...int64) {
+	r.w.WriteByte(code)
+	for _, a := range args {
+		r.varint(a)
+	}
+}
+
+func (r *recorder) elems(elems []ElemType) {
+	r.varint(int64(len(elems)))
+	for _, e := range elems {
+		r.varint(int64(elemToDist(e)))
+	}
+}
+
+func (r *recorder) contents() {
+	r.varint(int64(r.l.length))
+	r.l.ForAll(func(e *ElemType) {
+		r.varint(int64(elemToDist(*e)))
+	})
+}
+
+func (r *recorder) ops(ops []sliceutils.Op) {
+	r.varint(int64(len(ops)))
+	for i := range ops {
+		op := &ops[i]
+		r.varint(int64(op.Kind))
+		r.varint(int64(op.Index1))
+		switch op.Kind {
+		case sliceutils.OpInsert:
+			r.varint(int64(elemToDist(op.Elem)))
+		case sliceutils.OpSwap:
+			r.varint(int64(op.Index2))
+		}
+	}
+}
+
+func (r *recorder) flushPending() {
+	if r.pendingUpdate != -1 {
+		i := r.pendingUpdate
+		r.pendingUpdate = -1
+		r.op(recSet, int64(i), int64(elemToDist(r.l.At(i))))
+	}
+	if r.pendingContents {
+		r.pendingContents = false
+		r.w.WriteByte(recContents)
+		r.contents()
+	}
+	if r.pendingState {
+		r.pendingState = false
+		if r.l.ext != nil && r.l.ext.levelSource != nil {
+			r.op(recUnreplayable)
+			return
+		}
+		state, increment := r.l.rand.State()
+		r.w.WriteByte(recSetState)
+		r.uvarint(state)
+		r.uvarint(increment)
+	}
+}
+
+func (r *recorder) Trace(method string, args []interface{}) {
+	r.flushPending()
+
+	switch method {
+	case "Seed":
+		r.w.WriteByte(recSeed)
+		r.uvarint(args[0].(uint64))
+		r.uvarint(args[1].(uint64))
+	case "SeedStream":
+		r.w.WriteByte(recSeedStream)
+		r.uvarint(args[0].(uint64))
+		r.uvarint(args[1].(uint64))
+		r.uvarint(uint64(args[2].(uint32)))
+	case "Set":
+		r.op(recSet, int64(args[0].(int)), int64(elemToDist(args[1].(ElemType))))
+	case "Update":
+		r.pendingUpdate = args[0].(int)
+	case "Remove":
+		r.op(recRemove, int64(args[0].(int)))
+	case "Truncate":
+		r.op(recTruncate, int64(args[0].(int)))
+	case "PushFront":
+		r.op(recPushFront, int64(elemToDist(args[0].(ElemType))))
+	case "PushBack":
+		r.op(recPushBack, int64(elemToDist(args[0].(ElemType))))
+	case "PopFront":
+		r.op(recPopFront)
+	case "PopBack":
+		r.op(recPopBack)
+	case "Insert":
+		r.op(recInsert, int64(args[0].(int)), int64(elemToDist(args[1].(ElemType))))
+	case "Swap":
+		r.op(recSwap, int64(args[0].(int)), int64(args[1].(int)))
+	case "Clear":
+		r.op(recClear)
+	case "SetMaxLength":
+		r.op(recSetMaxLength, int64(args[0].(int)))
+	case "AppendSlice":
+		r.w.WriteByte(recAppendSlice)
+		r.elems(args[0].([]ElemType))
+	case "PrependSlice":
+		r.w.WriteByte(recPrependSlice)
+		r.elems(args[0].([]ElemType))
+	case "InsertSlice":
+		r.op(recInsertSlice, int64(args[0].(int)))
+		r.elems(args[1].([]ElemType))
+	case "SetRange":
+		r.op(recSetRange, int64(args[0].(int)))
+		r.elems(args[1].([]ElemType))
+	case "RemoveValues":
+		r.w.WriteByte(recRemoveValues)
+		r.elems(args[0].([]ElemType))
+	case "RotateRange":
+		r.op(recRotateRange, int64(args[0].(int)), int64(args[1].(int)), int64(args[2].(int)))
+	case "MoveElement":
+		r.op(recMoveElement, int64(args[0].(int)), int64(args[1].(int)))
+	case "ExtractRange":
+		r.op(recExtractRange, int64(args[0].(int)), int64(args[1].(int)))
+	case "SplitAt":
+		r.op(recSplitAt, int64(args[0].(int)))
+	case "Rebalance":
+		r.op(recRebalance)
+	case "Dedup":
+		r.op(recDedup)
+	case "DeltaInPlace":
+		r.op(recDeltaInPlace)
+	case "ApplyOps":
+		r.w.WriteByte(recApplyOps)
+		r.ops(args[0].([]sliceutils.Op))
+	case "CrossSwapRange":
+		if args[1].(*ISkipList) == r.l {
+			r.op(recSwapRanges, int64(args[0].(int)), int64(args[2].(int)), int64(args[3].(int)))
+			break
+		}
+		r.pendingContents = true
+		r.pendingState = true
+	case "SetCapacity":
+		// Operations that would exceed the capacity fail before they are
+		// traced, so the capacity has no effect on replay.
+	case "Reseed", "SeedFrom", "SeedPCG64", "SetLevelSource":
+		// The new generator state is only known once the call has completed.
+		r.pendingState = true
+	default:
+		// The effect of the operation can't be recorded from its arguments
+		// (e.g. because it takes a function or another ISkipList), so the
+		// resulting elements and generator state are recorded instead.
+		r.pendingContents = true
+		r.pendingState = true
+	}
+}
+
+// ReplayFile replays a recording made by StartRecording() from the file at the
+// specified path. See Replay().
+func ReplayFile(path string) (*ISkipList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Replay(f)
+}
+
+// Replay replays a recording made by StartRecording() and returns the
+// resulting ISkipList. If the recording reproduces a bug that causes an
+// ISkipList method to panic, then Replay will panic in the same way. An error
+// is returned if the recording is malformed or contains an operation that
+// can't be replayed (e.g. switching to a custom LevelSource). Operands are
+// checked before each operation is applied, so a malformed recording (e.g. one
+// containing an out of bounds index) results in an error rather than a panic;
+// the ISkipList resulting from the operations preceding the malformed
+// operation is returned along with the error. If the recording is truncated
+// (e.g. because it was not flushed), the operations that were recorded in
+// full are replayed and io.ErrUnexpectedEOF is returned along with the
+// resulting ISkipList.
+func Replay(rd io.Reader) (*ISkipList, error) {
+	r := bufio.NewReader(rd)
+
+	var header [len(recordingMagic) + 1]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("reading recording header: %w", err)
+	}
+	if string(header[:len(recordingMagic)]) != recordingMagic {
+		return nil, errors.New("not an ISkipList recording")
+	}
+	if v := header[len(recordingMagic)]; v != recordingVersion {
+		return nil, fmt.Errorf("unsupported recording version %v", v)
+	}
+
+	var err error
+	uvarint := func() uint64 {
+		if err != nil {
+			return 0
+		}
+		var v uint64
+		v, err = binary.ReadUvarint(r)
+		return v
+	}
+	varint := func() int {
+		if err != nil {
+			return 0
+		}
+		var v int64
+		v, err = binary.ReadVarint(r)
+		return int(v)
+	}
+	unexpectedEOF := func(l *ISkipList) (*ISkipList, error) {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return l, err
+	}
+
+	var l ISkipList
+	state := uvarint()
+	increment := uvarint()
+	maxLength := varint()
+	length := varint()
+	if err != nil {
+		return unexpectedEOF(nil)
+	}
+	if maxLength < 0 || length < 0 {
+		return nil, fmt.Errorf("malformed recording header (maximum length %v, length %v)", maxLength, length)
+	}
+	l.rand.SetState(state, increment)
+	for i := 0; i < length; i++ {
+		e := distToElem(varint())
+		if err != nil {
+			return unexpectedEOF(nil)
+		}
+		l.PushBack(e)
+	}
+	if maxLength != 0 {
+		l.SetMaxLength(maxLength)
+	}
+
+	// The elements are not preallocated, so that a malformed length doesn't
+	// cause a huge allocation.
+	elems := func() []ElemType {
+		n := varint()
+		if err == nil && n < 0 {
+			err = fmt.Errorf("negative slice length %v in recording", n)
+		}
+		var es []ElemType
+		for i := 0; i < n && err == nil; i++ {
+			e := distToElem(varint())
+			if err == nil {
+				es = append(es, e)
+			}
+		}
+		return es
+	}
+
+	inBounds := func(i, max int) bool {
+		if err != nil {
+			return false
+		}
+		if i < 0 || i > max {
+			err = fmt.Errorf("out of bounds operand %v in recording (length %v)", i, l.length)
+			return false
+		}
+		return true
+	}
+	inRange := func(from, to int) bool {
+		if !inBounds(from, l.length) || !inBounds(to, l.length) {
+			return false
+		}
+		if to < from {
+			err = fmt.Errorf("malformed range [%v, %v) in recording", from, to)
+			return false
+		}
+		return true
+	}
+	// ops reads the operands of ApplyOps, checking them against the length
+	// that the ISkipList will have when each operation is applied.
+	ops := func() []sliceutils.Op {
+		n := varint()
+		if err == nil && n < 0 {
+			err = fmt.Errorf("negative number of operations %v in recording", n)
+		}
+		var batch []sliceutils.Op
+		length := l.length
+		for i := 0; i < n && err == nil; i++ {
+			var op sliceutils.Op
+			op.Kind = sliceutils.OpKind(varint())
+			op.Index1 = varint()
+			switch op.Kind {
+			case sliceutils.OpInsert:
+				op.Elem = distToElem(varint())
+			case sliceutils.OpSwap:
+				op.Index2 = varint()
+			}
+			if err != nil {
+				break
+			}
+
+			max := length - 1
+			if op.Kind == sliceutils.OpInsert || op.Kind == sliceutils.OpTruncate {
+				max = length
+			}
+			if op.Index1 < 0 || op.Index1 > max {
+				err = fmt.Errorf("out of bounds operand %v in recording (length %v)", op.Index1, length)
+			}
+			if op.Kind == sliceutils.OpSwap && (op.Index2 < 0 || op.Index2 > max) {
+				err = fmt.Errorf("out of bounds operand %v in recording (length %v)", op.Index2, length)
+			}
+
+			switch op.Kind {
+			case sliceutils.OpInsert:
+				length++
+				if maxLength := l.MaxLength(); maxLength != 0 && length > maxLength {
+					length = maxLength
+				}
+			case sliceutils.OpRemove:
+				length--
+			case sliceutils.OpTruncate:
+				length = op.Index1
+			case sliceutils.OpSwap, sliceutils.OpAt:
+			default:
+				err = fmt.Errorf("unrecognized operation kind %v in recording", op.Kind)
+			}
+			batch = append(batch, op)
+		}
+		return batch
+	}
+
+	for {
+		code, rerr := r.ReadByte()
+		if rerr == io.EOF {
+			return &l, nil
+		}
+		if rerr != nil {
+			return &l, rerr
+		}
+
+		switch code {
+		case recSeed:
+			s1, s2 := uvarint(), uvarint()
+			if err == nil {
+				l.Seed(s1, s2)
+			}
+		case recSeedStream:
+			s1, s2, n := uvarint(), uvarint(), uvarint()
+			if err == nil {
+				l.SeedStream(s1, s2, uint32(n))
+			}
+		case recSetState:
+			s, inc := uvarint(), uvarint()
+			if err == nil {
+				l.rand.SetState(s, inc)
+			}
+		case recSet:
+			i, e := varint(), varint()
+			if inBounds(i, l.length-1) {
+				l.Set(i, distToElem(e))
+			}
+		case recRemove:
+			i := varint()
+			if inBounds(i, l.length-1) {
+				l.Remove(i)
+			}
+		case recTruncate:
+			n := varint()
+			if inBounds(n, l.length) {
+				l.Truncate(n)
+			}
+		case recPushFront:
+			e := varint()
+			if err == nil {
+				l.PushFront(distToElem(e))
+			}
+		case recPushBack:
+			e := varint()
+			if err == nil {
+				l.PushBack(distToElem(e))
+			}
+		case recPopFront:
+			l.PopFront()
+		case recPopBack:
+			l.PopBack()
+		case recInsert:
+			i, e := varint(), varint()
+			if inBounds(i, l.length) {
+				l.Insert(i, distToElem(e))
+			}
+		case recSwap:
+			i, j := varint(), varint()
+			if inBounds(i, l.length-1) && inBounds(j, l.length-1) {
+				l.Swap(i, j)
+			}
+		case recClear:
+			l.Clear()
+		case recSetMaxLength:
+			n := varint()
+			if err == nil && n < 0 {
+				err = fmt.Errorf("negative maximum length %v in recording", n)
+			}
+			if err == nil {
+				l.SetMaxLength(n)
+			}
+		case recAppendSlice:
+			es := elems()
+			if err == nil {
+				l.AppendSlice(es)
+			}
+		case recPrependSlice:
+			es := elems()
+			if err == nil {
+				l.PrependSlice(es)
+			}
+		case recInsertSlice:
+			i, es := varint(), elems()
+			if inBounds(i, l.length) {
+				l.InsertSlice(i, es)
+			}
+		case recSetRange:
+			from, es := varint(), elems()
+			if inBounds(from, l.length-len(es)) {
+				l.SetRange(from, es)
+			}
+		case recRemoveValues:
+			es := elems()
+			if err == nil {
+				l.RemoveValues(es)
+			}
+		case recRotateRange:
+			from, to, k := varint(), varint(), varint()
+			if inRange(from, to) {
+				l.RotateRange(from, to, k)
+			}
+		case recMoveElement:
+			i, j := varint(), varint()
+			if inBounds(i, l.length-1) && inBounds(j, l.length-1) {
+				l.MoveElement(i, j)
+			}
+		case recSwapRanges:
+			i, j, n := varint(), varint(), varint()
+			if inBounds(n, l.length) && inBounds(i, l.length-n) && inBounds(j, l.length-n) {
+				if i != j && i < j+n && j < i+n {
+					err = fmt.Errorf("overlapping ranges [%v, %v) and [%v, %v) in recording", i, i+n, j, j+n)
+				} else {
+					l.SwapRanges(i, j, n)
+				}
+			}
+		case recExtractRange:
+			from, to := varint(), varint()
+			if inBounds(from, l.length) && inBounds(to, l.length) {
+				l.ExtractRange(from, to)
+			}
+		case recSplitAt:
+			i := varint()
+			if inBounds(i, l.length) {
+				l.SplitAt(i)
+			}
+		case recRebalance:
+			l.Rebalance()
+		case recDedup:
+			l.Dedup()
+		case recDeltaInPlace:
+			l.DeltaInPlace()
+		case recApplyOps:
+			batch := ops()
+			if err == nil {
+				l.ApplyOps(batch)
+			}
+		case recContents:
+			es := elems()
+			if err == nil {
+				l.Clear()
+				l.AppendSlice(es)
+			}
+		case recUnreplayable:
+			return &l, errors.New("recording contains an operation that can't be replayed")
+		default:
+			return &l, fmt.Errorf("unrecognized opcode %v in recording", code)
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return unexpectedEOF(&l)
+		}
+		if err != nil {
+			return &l, err
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package iskiplist
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/addrummond/iskiplist/v2/pcg"
+)
+
+// Reseed reseeds the ISkipList's pseudorandom number generator with fresh
+// entropy from crypto/rand. Unlike Seed(), it may be called at any time. It has
+// no effect if a custom LevelSource is in use (see SetLevelSource()).
+//
+// An attacker who can observe the timing of operations on an ISkipList may be
+// able to infer the state of its generator and then choose insertions that
+// result in a degenerate structure. Reseeding limits the window in which this
+// kind of inference is useful. See also SetReseedInterval().
+func (l *ISkipList) Reseed() {
+	if tracing(l) {
+		trace(l, "Reseed")
+	}
+	reseed(l)
+}
+
+func reseed(l *ISkipList) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return
+	}
+	s1 := binary.LittleEndian.Uint64(b[0:])
+	s2 := binary.LittleEndian.Uint64(b[8:])
+
+	if l.ext != nil && l.ext.levelSource != nil {
+		if _, ok := l.ext.levelSource.(pcg64LevelSource); ok {
+			s3 := binary.LittleEndian.Uint64(b[16:])
+			s4 := binary.LittleEndian.Uint64(b[24:])
+			l.ext.levelSource = pcg64LevelSource{pcg.NewPCG64().Seed(s1, s2, s3, s4)}
+		}
+		return
+	}
+
+	l.rand.Seed(s1|1, s2)
+}
+
+// SetReseedInterval causes the ISkipList's pseudorandom number generator to be
+// reseeded via Reseed() after every n values drawn from it. (Roughly one value
+// is drawn for each element added to the ISkipList.) An interval of 0 disables
+// periodic reseeding. Reseeding is relatively expensive, so n should not be
+// too small; an interval of a few thousand makes the cost negligible.
+func (l *ISkipList) SetReseedInterval(n int) {
+	if n < 0 {
+		panic(fmt.Sprintf("Negative interval %v in call to 'SetReseedInterval'", n))
+	}
+	if n == 0 {
+		if l.ext != nil {
+			l.ext.reseedInterval = 0
+		}
+		return
+	}
+	ext := getExt(l)
+	ext.reseedInterval = n
+	ext.untilReseed = n
+}
+
+func maybeReseed(l *ISkipList) {
+	if l.ext == nil || l.ext.reseedInterval == 0 {
+		return
+	}
+	l.ext.untilReseed--
+	if l.ext.untilReseed <= 0 {
+		l.ext.untilReseed = l.ext.reseedInterval
+		reseed(l)
+	}
+}
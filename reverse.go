@@ -0,0 +1,106 @@
+package iskiplist
+
+import (
+	"fmt"
+	"math"
+)
+
+// iterateRangeReverse calls f for each element in [from, to), from the last to
+// the first. As the levels of an ISkipList are singly linked, the range is
+// walked forwards once, recording every b-th node (where b is about the square
+// root of the length of the range). The blocks of b nodes between these
+// checkpoints are then visited in reverse order, collecting the nodes of each
+// block into a buffer that is itself walked backwards. This visits each node
+// twice and uses O(sqrt(to - from)) memory.
+func iterateRangeReverse(l *ISkipList, from, to int, f func(int, *ElemType) bool) {
+	n := to - from
+	block := int(math.Sqrt(float64(n))) + 1
+	checkpoints := make([]*listNode, 0, n/block+1)
+	mods := l.mods.get()
+	node := retrieve(l, from)
+	for i := 0; i < n; i++ {
+		if i%block == 0 {
+			checkpoints = append(checkpoints, node)
+		}
+		node = node.next
+	}
+
+	buf := make([]*listNode, block)
+	for c := len(checkpoints) - 1; c >= 0; c-- {
+		start := c * block
+		end := start + block
+		if end > n {
+			end = n
+		}
+		node := checkpoints[c]
+		for j := start; j < end; j++ {
+			buf[j-start] = node
+			node = node.next
+		}
+		for j := end - 1; j >= start; j-- {
+			if !f(from+j, &buf[j-start].elem) {
+				return
+			}
+			checkNotModified(l, mods)
+		}
+	}
+}
+
+// IterateRangeReverseI iterates backwards over a range of the ISkipList, from
+// index to-1 down to index 'from', and passes to the supplied function the
+// index of each visited element and a pointer to it. The iteration is halted
+// if the function returns false. The bounds are as for IterateRangeI(). The
+// ISkipList is walked forwards once to find checkpoints, so iterating
+// backwards over k elements takes O(log n + k) time and O(sqrt(k)) memory
+// (rather than the O(k log n) time taken by calling At() with descending
+// indices).
+func (l *ISkipList) IterateRangeReverseI(from, to int, f func(int, *ElemType) bool) {
+	if from < 0 || from > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", from, l))
+	}
+	if to < 0 || to > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", to, l))
+	}
+
+	if to <= from {
+		return
+	}
+	iterateRangeReverse(l, from, to, f)
+}
+
+// IterateRangeReverse is like IterateRangeReverseI except that the index of
+// each element is not passed to the supplied function.
+func (l *ISkipList) IterateRangeReverse(from, to int, f func(*ElemType) bool) {
+	l.IterateRangeReverseI(from, to, func(_ int, e *ElemType) bool {
+		return f(e)
+	})
+}
+
+// IterateReverse(f) is a shorthand for l.IterateRangeReverse(0, l.Length(), f)
+func (l *ISkipList) IterateReverse(f func(*ElemType) bool) {
+	l.IterateRangeReverse(0, l.length, f)
+}
+
+// IterateReverseI(f) is a shorthand for
+// l.IterateRangeReverseI(0, l.Length(), f)
+func (l *ISkipList) IterateReverseI(f func(int, *ElemType) bool) {
+	l.IterateRangeReverseI(0, l.length, f)
+}
+
+// ForAllReverse is like IterateReverse except that the iteration always
+// continues to the first element.
+func (l *ISkipList) ForAllReverse(f func(*ElemType)) {
+	l.IterateRangeReverse(0, l.length, func(e *ElemType) bool {
+		f(e)
+		return true
+	})
+}
+
+// ForAllReverseI is like IterateReverseI except that the iteration always
+// continues to the first element.
+func (l *ISkipList) ForAllReverseI(f func(int, *ElemType)) {
+	l.IterateRangeReverseI(0, l.length, func(i int, e *ElemType) bool {
+		f(i, e)
+		return true
+	})
+}
@@ -0,0 +1,44 @@
+package iskiplist
+
+import "fmt"
+
+// A Run is a sequence of Count consecutive elements with the same value.
+type Run struct {
+	Value ElemType
+	Count int
+}
+
+// EncodeRuns returns the run-length encoding of the ISkipList: a slice of runs
+// of consecutive equal elements, in order. Adjacent runs always have different
+// values. EncodeRuns runs in O(n) time.
+func (l *ISkipList) EncodeRuns() []Run {
+	var runs []Run
+	l.ForAll(func(e *ElemType) {
+		if n := len(runs); n > 0 && runs[n-1].Value == *e {
+			runs[n-1].Count++
+		} else {
+			runs = append(runs, Run{Value: *e, Count: 1})
+		}
+	})
+	return runs
+}
+
+// FromRuns returns a new ISkipList containing the elements described by a
+// run-length encoding (see EncodeRuns()). Runs with a count of zero are
+// ignored, and adjacent runs need not have different values. FromRuns panics
+// if any count is negative. The ISkipList is built bottom-up, so FromRuns runs
+// in O(m) time, where m is the total number of elements.
+func FromRuns(runs []Run) *ISkipList {
+	var b chainBuilder
+	for _, r := range runs {
+		if r.Count < 0 {
+			panic(fmt.Sprintf("Negative count %v in run passed to FromRuns", r.Count))
+		}
+		for i := 0; i < r.Count; i++ {
+			b.add(r.Value)
+		}
+	}
+	l := new(ISkipList)
+	rebuild(l, b.first, b.n, l)
+	return l
+}
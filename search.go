@@ -0,0 +1,102 @@
+package iskiplist
+
+// denseElem returns the element associated with a node on any level by
+// following nextLevel pointers down to the densest level.
+func denseElem(node *listNode) ElemType {
+	for node.nextLevel != nil {
+		node = node.nextLevel
+	}
+	return node.elem
+}
+
+// searchFirst returns the smallest index i such that pred(l.At(i)) is true,
+// or l.Length() if there is no such index. Like sort.Search, it assumes that
+// pred is false for some (possibly empty) prefix of the ISkipList and true for
+// the remainder. The search descends through the sparser levels, comparing
+// values rather than distances, and visits O(log n) nodes. However, each
+// comparison at a sparse level requires a walk down to the densest level to
+// find the node's element, so the search runs in O(log^2 n) time.
+func searchFirst(l *ISkipList, pred func(ElemType) bool) int {
+	if l.length == 0 || pred(first(l)) {
+		return 0
+	}
+
+	// Invariant: pred is false for the element at 'index'.
+	node := l.root
+	index := 0
+	for node.nextLevel != nil {
+		if node.next != nil && !pred(denseElem(node.next)) {
+			index += elemToDist(node.elem)
+			node = node.next
+		} else {
+			node = node.nextLevel
+		}
+	}
+	for node.next != nil && !pred(node.next.elem) {
+		index++
+		node = node.next
+	}
+
+	return index + 1
+}
+
+// EqualRange returns the range [first, last) of indices of elements equal to v
+// in an ISkipList that is sorted in ascending order according to 'less'. Two
+// elements a and b are considered equal if neither less(a, b) nor less(b, a).
+// If there is no such element, first == last, and both give the index at which
+// v could be inserted while keeping the ISkipList sorted. This has the same
+// semantics as C++'s std::equal_range. EqualRange performs two descents
+// through the sparse levels of the ISkipList. The result is unspecified if the
+// ISkipList is not sorted.
+func (l *ISkipList) EqualRange(v ElemType, less func(a, b ElemType) bool) (first, last int) {
+	first = searchFirst(l, func(e ElemType) bool { return !less(e, v) })
+	last = searchFirst(l, func(e ElemType) bool { return less(v, e) })
+	return
+}
+
+// SearchSorted searches for v in an ISkipList that is sorted in ascending order
+// according to 'less'. It returns the index of the first element equal to v
+// (where a and b are considered equal if neither less(a, b) nor less(b, a))
+// and true, or the index at which v could be inserted while keeping the
+// ISkipList sorted and false if there is no such element. SearchSorted
+// descends through the sparse levels comparing values. As finding the value
+// of a node on a sparse level requires a walk down to the densest level, it
+// runs in O(log^2 n) time. The result is unspecified if the ISkipList is not
+// sorted.
+func (l *ISkipList) SearchSorted(v ElemType, less func(a, b ElemType) bool) (int, bool) {
+	index := searchFirst(l, func(e ElemType) bool { return !less(e, v) })
+	if index == l.length {
+		return index, false
+	}
+	return index, !less(v, getTo(l.root, index, searchObserver(l)).elem)
+}
+
+// FindIndex returns the index of the first element for which 'pred' returns
+// true, or -1 if there is no such element. It scans the densest level of the
+// ISkipList, so it runs in O(n) time. The predicate must not modify the
+// ISkipList.
+func (l *ISkipList) FindIndex(pred func(ElemType) bool) int {
+	index := -1
+	l.IterateI(func(i int, e *ElemType) bool {
+		if pred(*e) {
+			index = i
+			return false
+		}
+		return true
+	})
+	return index
+}
+
+// FindAll returns the indices, in ascending order, of all the elements for
+// which 'pred' returns true. It returns nil if there are no such elements. It
+// scans the densest level of the ISkipList, so it runs in O(n) time. The
+// predicate must not modify the ISkipList.
+func (l *ISkipList) FindAll(pred func(ElemType) bool) []int {
+	var indices []int
+	l.ForAllI(func(i int, e *ElemType) {
+		if pred(*e) {
+			indices = append(indices, i)
+		}
+	})
+	return indices
+}
@@ -0,0 +1,125 @@
+package iskiplist
+
+// SearchCost records the aggregate cost of searches through ISkipLists. It is
+// returned by SearchStats().
+type SearchCost struct {
+	// The number of searches (i.e. descents through the levels of an
+	// ISkipList to find the node at a given index).
+	Searches uint64
+	// The number of searches that started from the index cache rather than
+	// from the root node.
+	CacheHits uint64
+	// The number of searches that could have started from the index cache but
+	// started from the root node instead (because the cache was empty or
+	// invalidated, or pointed beyond the target index). Searches made by
+	// operations that don't consult the cache are not counted as hits or
+	// misses.
+	CacheMisses uint64
+	// The number of steps taken along sparse levels.
+	SparseSteps uint64
+	// The number of times a search moved down to a denser level.
+	Descents uint64
+	// The number of steps taken along the densest level.
+	DenseSteps uint64
+}
+
+// HopsPerSearch returns the mean number of nodes visited per search (i.e. the
+// sum of SparseSteps, Descents and DenseSteps divided by Searches). It returns
+// 0 if no searches have been recorded.
+func (c SearchCost) HopsPerSearch() float64 {
+	if c.Searches == 0 {
+		return 0
+	}
+	return float64(c.SparseSteps+c.Descents+c.DenseSteps) / float64(c.Searches)
+}
+
+// CacheHitRate returns the proportion of searches that consulted the index
+// cache and were able to start from it. It returns 0 if no such searches have
+// been recorded.
+func (c SearchCost) CacheHitRate() float64 {
+	if c.CacheHits+c.CacheMisses == 0 {
+		return 0
+	}
+	return float64(c.CacheHits) / float64(c.CacheHits+c.CacheMisses)
+}
+
+func (c *SearchCost) add(d *SearchCost) {
+	c.Searches += d.Searches
+	c.CacheHits += d.CacheHits
+	c.CacheMisses += d.CacheMisses
+	c.SparseSteps += d.SparseSteps
+	c.Descents += d.Descents
+	c.DenseSteps += d.DenseSteps
+}
+
+// SearchStatsEnabled returns true iff the package was built with the
+// 'iskiplistsearchstats' build tag. Search costs are only recorded if this tag
+// is set, as counting them slows down every search:
+//
+//	go test -tags iskiplistsearchstats ./...
+func SearchStatsEnabled() bool {
+	return searchStatsEnabled
+}
+
+// SearchStats returns the aggregate cost of all searches through all
+// ISkipLists since the program started or ResetSearchStats() was last called.
+// This can be used to check whether the index cache is helping a given access
+// pattern. If the package was not built with the 'iskiplistsearchstats' build
+// tag, SearchStats always returns a zero SearchCost. See also
+// ISkipList.EnableTelemetry() for per-list telemetry.
+func SearchStats() SearchCost {
+	return loadSearchStats()
+}
+
+// ResetSearchStats resets the counts returned by SearchStats() to zero.
+func ResetSearchStats() {
+	resetSearchStats()
+}
+
+// searchObserver returns the extensions of the ISkipList if searches through
+// it have to be recorded, or nil otherwise.
+func searchObserver(l *ISkipList) *extensions {
+	if l.ext == nil || (l.ext.telemetry == nil && l.ext.metrics == nil) {
+		return nil
+	}
+	return l.ext
+}
+
+// Searches are counted by calling the following functions only if
+// searchStatsEnabled is true or searchObserver() returned non-nil, so that
+// they cost nothing otherwise.
+
+func countSearch(ext *extensions, sparseSteps, descents, denseSteps int) {
+	c := SearchCost{
+		Searches:    1,
+		SparseSteps: uint64(sparseSteps),
+		Descents:    uint64(descents),
+		DenseSteps:  uint64(denseSteps),
+	}
+	addSearchCost(ext, &c)
+}
+
+func countCacheLookup(ext *extensions, hit bool) {
+	var c SearchCost
+	if hit {
+		c.CacheHits = 1
+	} else {
+		c.CacheMisses = 1
+	}
+	addSearchCost(ext, &c)
+}
+
+func addSearchCost(ext *extensions, c *SearchCost) {
+	if searchStatsEnabled {
+		addGlobalSearchCost(c)
+	}
+	if ext == nil {
+		return
+	}
+	if ext.telemetry != nil {
+		ext.telemetry.add(c)
+	}
+	if ext.metrics != nil {
+		ext.metrics.OnSearch(*c)
+	}
+}
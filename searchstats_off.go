@@ -0,0 +1,14 @@
+//go:build !iskiplistsearchstats
+// +build !iskiplistsearchstats
+
+package iskiplist
+
+const searchStatsEnabled = false
+
+func addGlobalSearchCost(c *SearchCost) {}
+
+func loadSearchStats() SearchCost {
+	return SearchCost{}
+}
+
+func resetSearchStats() {}
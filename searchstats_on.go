@@ -0,0 +1,39 @@
+//go:build iskiplistsearchstats
+// +build iskiplistsearchstats
+
+package iskiplist
+
+import "sync/atomic"
+
+const searchStatsEnabled = true
+
+var searchCounters SearchCost
+
+func addGlobalSearchCost(c *SearchCost) {
+	atomic.AddUint64(&searchCounters.Searches, c.Searches)
+	atomic.AddUint64(&searchCounters.CacheHits, c.CacheHits)
+	atomic.AddUint64(&searchCounters.CacheMisses, c.CacheMisses)
+	atomic.AddUint64(&searchCounters.SparseSteps, c.SparseSteps)
+	atomic.AddUint64(&searchCounters.Descents, c.Descents)
+	atomic.AddUint64(&searchCounters.DenseSteps, c.DenseSteps)
+}
+
+func loadSearchStats() SearchCost {
+	return SearchCost{
+		Searches:    atomic.LoadUint64(&searchCounters.Searches),
+		CacheHits:   atomic.LoadUint64(&searchCounters.CacheHits),
+		CacheMisses: atomic.LoadUint64(&searchCounters.CacheMisses),
+		SparseSteps: atomic.LoadUint64(&searchCounters.SparseSteps),
+		Descents:    atomic.LoadUint64(&searchCounters.Descents),
+		DenseSteps:  atomic.LoadUint64(&searchCounters.DenseSteps),
+	}
+}
+
+func resetSearchStats() {
+	atomic.StoreUint64(&searchCounters.Searches, 0)
+	atomic.StoreUint64(&searchCounters.CacheHits, 0)
+	atomic.StoreUint64(&searchCounters.CacheMisses, 0)
+	atomic.StoreUint64(&searchCounters.SparseSteps, 0)
+	atomic.StoreUint64(&searchCounters.Descents, 0)
+	atomic.StoreUint64(&searchCounters.DenseSteps, 0)
+}
@@ -4,8 +4,9 @@ package sliceutils
 
 import (
 	"fmt"
+	"strings"
 
-	"github.com/addrummond/iskiplist/pcg"
+	"github.com/addrummond/iskiplist/v2/pcg"
 )
 
 type elemType = int
@@ -46,6 +47,11 @@ const (
 	OpInsert = iota
 	OpRemove
 	OpSwap
+	// Truncates the list to Index1 elements.
+	OpTruncate
+	// Reads the element at Index1 without modifying the list. This exercises
+	// the index cache.
+	OpAt
 )
 
 type Op struct {
@@ -63,6 +69,9 @@ func ApplyOpToSlice(op *Op, a *[]elemType) {
 		SliceRemove(a, op.Index1)
 	case OpSwap:
 		SliceSwap(a, op.Index1, op.Index2)
+	case OpTruncate:
+		*a = (*a)[:op.Index1]
+	case OpAt:
 	}
 }
 
@@ -74,6 +83,10 @@ func PrintOp(op *Op) string {
 		return fmt.Sprintf("Remove element at index %v\n", op.Index1)
 	case OpSwap:
 		return fmt.Sprintf("Swap element at index %v with element at index %v\n", op.Index1, op.Index2)
+	case OpTruncate:
+		return fmt.Sprintf("Truncate to length %v\n", op.Index1)
+	case OpAt:
+		return fmt.Sprintf("Get element at index %v\n", op.Index1)
 	default:
 		panic("Unrecognized op")
 	}
@@ -142,3 +155,149 @@ func genOpsHelper(n int, initialLength int, lotsOfPushing bool) []Op {
 
 	return ops
 }
+
+// ValidOps returns true iff every op in the sequence has in-bounds indices when
+// the sequence is applied to a list of length initialLength.
+func ValidOps(ops []Op, initialLength int) bool {
+	l := initialLength
+	for _, o := range ops {
+		switch o.Kind {
+		case OpInsert:
+			if o.Index1 < 0 || o.Index1 > l {
+				return false
+			}
+			l++
+		case OpRemove:
+			if o.Index1 < 0 || o.Index1 >= l {
+				return false
+			}
+			l--
+		case OpSwap:
+			if o.Index1 < 0 || o.Index1 >= l || o.Index2 < 0 || o.Index2 >= l {
+				return false
+			}
+		case OpTruncate:
+			if o.Index1 < 0 || o.Index1 > l {
+				return false
+			}
+			l = o.Index1
+		case OpAt:
+			if o.Index1 < 0 || o.Index1 >= l {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Shrink reduces a failing op sequence to a (locally) minimal failing
+// sequence. The 'fails' function is called on candidate sequences and should
+// return true iff the candidate still exhibits the failure. Only sequences
+// that are valid according to ValidOps are passed to 'fails'.
+//
+// Shrink first removes chunks of ops of decreasing size, then tries to
+// simplify the remaining ops by reducing their indices and elements to zero.
+func Shrink(ops []Op, initialLength int, fails func([]Op) bool) []Op {
+	ops = append([]Op(nil), ops...)
+
+	for chunk := len(ops) / 2; chunk >= 1; {
+		progress := false
+		for start := 0; start+chunk <= len(ops); {
+			cand := make([]Op, 0, len(ops)-chunk)
+			cand = append(cand, ops[:start]...)
+			cand = append(cand, ops[start+chunk:]...)
+			if ValidOps(cand, initialLength) && fails(cand) {
+				ops = cand
+				progress = true
+			} else {
+				start += chunk
+			}
+		}
+		if !progress {
+			chunk /= 2
+		}
+	}
+
+	try := func(i int, o Op) {
+		old := ops[i]
+		if o == old {
+			return
+		}
+		ops[i] = o
+		if !ValidOps(ops, initialLength) || !fails(ops) {
+			ops[i] = old
+		}
+	}
+	for i := range ops {
+		o := ops[i]
+		o.Elem = intToElem(0)
+		try(i, o)
+		o = ops[i]
+		o.Index1 = 0
+		try(i, o)
+		o = ops[i]
+		o.Index2 = 0
+		try(i, o)
+	}
+
+	return ops
+}
+
+// GoCode returns Go source code that applies the given op sequence to the
+// ISkipList (or BufferedISkipList) variable named 'receiver'. This is useful
+// for turning a failing op sequence into a regression test.
+func GoCode(ops []Op, receiver string) string {
+	var sb strings.Builder
+	for _, o := range ops {
+		switch o.Kind {
+		case OpInsert:
+			fmt.Fprintf(&sb, "%s.Insert(%v, %v)\n", receiver, o.Index1, o.Elem)
+		case OpRemove:
+			fmt.Fprintf(&sb, "%s.Remove(%v)\n", receiver, o.Index1)
+		case OpSwap:
+			fmt.Fprintf(&sb, "%s.Swap(%v, %v)\n", receiver, o.Index1, o.Index2)
+		case OpTruncate:
+			fmt.Fprintf(&sb, "%s.Truncate(%v)\n", receiver, o.Index1)
+		case OpAt:
+			fmt.Fprintf(&sb, "%s.At(%v)\n", receiver, o.Index1)
+		default:
+			panic("Unrecognized op")
+		}
+	}
+	return sb.String()
+}
+
+// DecodeOps decodes a byte sequence into a valid op sequence for a list of
+// length initialLength. It is used to turn fuzzer input into op sequences.
+// Each op consumes four bytes. Any trailing bytes are ignored.
+func DecodeOps(data []byte, initialLength int) []Op {
+	ops := make([]Op, 0, len(data)/4)
+	l := initialLength
+	for ; len(data) >= 4; data = data[4:] {
+		var o Op
+		switch k := data[0] % 5; {
+		case l == 0 || k == 0:
+			o.Kind = OpInsert
+			o.Index1 = int(data[1]) % (l + 1)
+			o.Elem = intToElem(int(data[3]))
+			l++
+		case k == 1:
+			o.Kind = OpRemove
+			o.Index1 = int(data[1]) % l
+			l--
+		case k == 2:
+			o.Kind = OpSwap
+			o.Index1 = int(data[1]) % l
+			o.Index2 = int(data[2]) % l
+		case k == 3:
+			o.Kind = OpTruncate
+			o.Index1 = int(data[1]) % (l + 1)
+			l = o.Index1
+		default:
+			o.Kind = OpAt
+			o.Index1 = int(data[1]) % l
+		}
+		ops = append(ops, o)
+	}
+	return ops
+}
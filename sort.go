@@ -0,0 +1,150 @@
+package iskiplist
+
+// This file contains sorting methods. The densest level of the ISkipList is
+// sorted as a linked list using a bottom-up merge sort, which requires no
+// additional memory for the elements, and then the sparse levels are rebuilt
+// (see rebuild()).
+
+// Sort sorts the ISkipList in ascending order according to 'less'. It runs in
+// O(n log n) time. Sort is not guaranteed to be stable (see SortStable()).
+// The sparse levels of the ISkipList are rebuilt, so Sort is a good time to
+// discard any accumulated imbalance in the structure. The comparison function
+// must not access the ISkipList.
+func (l *ISkipList) Sort(less func(a, b ElemType) bool) {
+	if tracing(l) {
+		trace(l, "Sort", less)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	sortList(l, less)
+}
+
+func sortList(l *ISkipList, less func(a, b ElemType) bool) {
+	n := l.length
+	if n < 2 {
+		return
+	}
+
+	// If positions are tracked, carry each element's posNode along with it,
+	// so that IDs and the value index follow their elements.
+	var pos map[*listNode]*posNode
+	if l.ext != nil && l.ext.positions != nil {
+		pos = make(map[*listNode]*posNode, n)
+		pnodes := l.ext.positions.nodes()
+		i := 0
+		for node := densest(l); node != nil; node = node.next {
+			pos[node] = pnodes[i]
+			i++
+		}
+	}
+
+	first := mergeSort(densest(l), n, less)
+
+	if pos != nil {
+		pnodes := make([]*posNode, 0, n)
+		for node := first; node != nil; node = node.next {
+			pnodes = append(pnodes, pos[node])
+		}
+		l.ext.positions.reorder(pnodes)
+	}
+
+	rebuild(l, first, n, l)
+}
+
+// mergeSort stably sorts a chain of n densest-level nodes and returns the
+// first node of the sorted chain.
+func mergeSort(first *listNode, n int, less func(a, b ElemType) bool) *listNode {
+	for width := 1; width < n; width *= 2 {
+		var head, tail *listNode
+		rest := first
+		for rest != nil {
+			a := rest
+			b := cutAfter(a, width)
+			rest = cutAfter(b, width)
+			h, t := mergeChains(a, b, less)
+			if tail == nil {
+				head = h
+			} else {
+				tail.next = h
+			}
+			tail = t
+		}
+		first = head
+	}
+	return first
+}
+
+// cutAfter cuts a chain of nodes after its first n nodes and returns the
+// remainder (or nil if the chain has no more than n nodes).
+func cutAfter(node *listNode, n int) *listNode {
+	for i := 1; i < n && node != nil; i++ {
+		node = node.next
+	}
+	if node == nil {
+		return nil
+	}
+	rest := node.next
+	node.next = nil
+	return rest
+}
+
+// mergeChains merges two sorted chains of nodes and returns the first and last
+// nodes of the result. Where elements compare equal, those from 'a' come
+// first.
+func mergeChains(a, b *listNode, less func(a, b ElemType) bool) (head, tail *listNode) {
+	var start listNode
+	t := &start
+	for a != nil && b != nil {
+		if less(b.elem, a.elem) {
+			t.next = b
+			b = b.next
+		} else {
+			t.next = a
+			a = a.next
+		}
+		t = t.next
+	}
+	if a != nil {
+		t.next = a
+	} else {
+		t.next = b
+	}
+	for t.next != nil {
+		t = t.next
+	}
+	return start.next, t
+}
+
+// SortStable sorts the ISkipList in ascending order according to 'less',
+// keeping elements that compare equal in their original order. It runs in
+// O(n log n) time. See Sort().
+func (l *ISkipList) SortStable(less func(a, b ElemType) bool) {
+	if tracing(l) {
+		trace(l, "SortStable", less)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	// The merge sort used by Sort() happens to be stable, but Sort() makes no
+	// promises.
+	sortList(l, less)
+}
+
+// IsSorted returns true iff the ISkipList is sorted in ascending order
+// according to 'less'. It runs in O(n) time.
+func (l *ISkipList) IsSorted(less func(a, b ElemType) bool) bool {
+	if l.length < 2 {
+		return true
+	}
+	node := densest(l)
+	for node.next != nil {
+		if less(node.next.elem, node.elem) {
+			return false
+		}
+		node = node.next
+	}
+	return true
+}
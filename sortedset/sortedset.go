@@ -0,0 +1,106 @@
+// Package sortedset provides an order-statistic multiset implemented using an
+// ISkipList. Elements are kept in ascending order in the ISkipList, whose
+// sparse levels already record the number of elements that each link spans.
+// The key with a given rank is therefore found by indexing, in O(log n) time.
+// The rank of a key is found by a search that descends through the sparse
+// levels comparing keys (see ISkipList.SearchSorted). As the key of a node on
+// a sparse level is found by walking down to the densest level, searching by
+// key takes O(log^2 n) time.
+//
+// A SortedSet shares its node type, level structure and random number
+// generation with ISkipList. In particular, unless Seed() is called, it is
+// automatically seeded in the same way as an ISkipList.
+package sortedset
+
+import (
+	"fmt"
+
+	"github.com/addrummond/iskiplist/v2"
+)
+
+// ElemType is the type of an element of a SortedSet.
+type ElemType = iskiplist.ElemType
+
+func less(a, b ElemType) bool {
+	return a < b
+}
+
+// SortedSet is an order-statistic multiset. The same key may be inserted more
+// than once. The zero value is an empty SortedSet.
+type SortedSet struct {
+	list iskiplist.ISkipList
+}
+
+// Seed seeds the random number generator used for the SortedSet. If Seed is
+// called, it should be called immediately following creation of the
+// SortedSet.
+func (s *SortedSet) Seed(seed1, seed2 uint64) {
+	s.list.Seed(seed1, seed2)
+}
+
+// Length returns the number of elements in the SortedSet, counting duplicates.
+func (s *SortedSet) Length() int {
+	return s.list.Length()
+}
+
+// Clear empties the SortedSet.
+func (s *SortedSet) Clear() {
+	s.list.Clear()
+}
+
+// Insert adds a key to the SortedSet. If the key is already present, the new
+// element is added after the existing occurrences. Insert runs in
+// O(log^2 n) time.
+func (s *SortedSet) Insert(key ElemType) {
+	_, last := s.list.EqualRange(key, less)
+	s.list.Insert(last, key)
+}
+
+// Delete removes one occurrence of a key from the SortedSet. It returns false
+// iff the key was not present. Delete runs in O(log^2 n) time.
+func (s *SortedSet) Delete(key ElemType) bool {
+	i, found := s.list.SearchSorted(key, less)
+	if !found {
+		return false
+	}
+	s.list.Remove(i)
+	return true
+}
+
+// Rank returns the number of elements in the SortedSet that are less than the
+// specified key. If the key is present, this is the index of its first
+// occurrence. Rank runs in O(log^2 n) time.
+func (s *SortedSet) Rank(key ElemType) int {
+	i, _ := s.list.SearchSorted(key, less)
+	return i
+}
+
+// Count returns the number of occurrences of a key in the SortedSet. It runs in
+// O(log^2 n) time.
+func (s *SortedSet) Count(key ElemType) int {
+	first, last := s.list.EqualRange(key, less)
+	return last - first
+}
+
+// Contains returns true iff the key is present in the SortedSet.
+func (s *SortedSet) Contains(key ElemType) bool {
+	_, found := s.list.SearchSorted(key, less)
+	return found
+}
+
+// Select returns the element with the specified rank, i.e. the element at
+// index 'rank' in ascending order. Select runs in O(log n) time.
+func (s *SortedSet) Select(rank int) ElemType {
+	if rank < 0 || rank >= s.list.Length() {
+		panic(fmt.Sprintf("Out of bounds rank %v into SortedSet of length %v", rank, s.list.Length()))
+	}
+	return s.list.At(rank)
+}
+
+// Iterate iterates through the elements of the SortedSet in ascending order,
+// halting if the supplied function returns false.
+func (s *SortedSet) Iterate(f func(ElemType) bool) {
+	s.list.Iterate(func(e *ElemType) bool {
+		return f(*e)
+	})
+}
@@ -0,0 +1,86 @@
+package sortedset
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/addrummond/iskiplist/v2/pcg"
+)
+
+const (
+	randSeed1 = 12345
+	randSeed2 = 67891
+)
+
+// This test applies a random sequence of insertions and deletions to both a
+// SortedSet and a sorted slice, checking that Rank and Select agree after each
+// operation.
+func TestRandomOps(t *testing.T) {
+	const nops = 2000
+
+	var s SortedSet
+	s.Seed(randSeed1, randSeed2)
+	rand := pcg.NewPCG32()
+	rand.Seed(randSeed1, randSeed2)
+
+	var a []ElemType
+	for i := 0; i < nops; i++ {
+		r := rand.Random()
+		k := ElemType(r % 200)
+		if r&(1<<20) == 0 || len(a) == 0 {
+			s.Insert(k)
+			j := sort.SearchInts(a, k+1)
+			a = append(a, 0)
+			copy(a[j+1:], a[j:])
+			a[j] = k
+		} else {
+			j := sort.SearchInts(a, k)
+			found := j < len(a) && a[j] == k
+			if s.Delete(k) != found {
+				t.Fatalf("Delete(%v) disagrees with model\n", k)
+			}
+			if found {
+				a = append(a[:j], a[j+1:]...)
+			}
+		}
+
+		if s.Length() != len(a) {
+			t.Fatalf("Length %v, expected %v\n", s.Length(), len(a))
+		}
+		for j, v := range a {
+			if s.Select(j) != v {
+				t.Fatalf("Select(%v) = %v, expected %v\n", j, s.Select(j), v)
+			}
+		}
+		for k := ElemType(-1); k <= 201; k += 7 {
+			if s.Rank(k) != sort.SearchInts(a, k) {
+				t.Fatalf("Rank(%v) = %v, expected %v\n", k, s.Rank(k), sort.SearchInts(a, k))
+			}
+		}
+	}
+
+	n := 0
+	s.Iterate(func(v ElemType) bool {
+		if v != a[n] {
+			t.Errorf("Iterate yielded %v at %v, expected %v\n", v, n, a[n])
+		}
+		n++
+		return true
+	})
+	if n != len(a) {
+		t.Errorf("Iterate yielded %v elements, expected %v\n", n, len(a))
+	}
+}
+
+func TestCount(t *testing.T) {
+	var s SortedSet
+	for _, k := range []ElemType{5, 3, 5, 1, 5, 3} {
+		s.Insert(k)
+	}
+	if s.Count(5) != 3 || s.Count(3) != 2 || s.Count(1) != 1 || s.Count(2) != 0 {
+		t.Errorf("Unexpected counts\n")
+	}
+	if !s.Contains(1) || s.Contains(4) {
+		t.Errorf("Unexpected result from Contains\n")
+	}
+}
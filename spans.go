@@ -0,0 +1,407 @@
+package iskiplist
+
+import (
+	"fmt"
+	"math"
+)
+
+// Span aggregates are stored in the nodes of the sparse levels. A node on a
+// sparse level spans the elements from its own position up to (but not
+// including) the position of the next node on its level, or up to the end of
+// the ISkipList if it is the last node on its level. The nodes below it that
+// lie within its span form a run on the next level down, so each node's
+// aggregates can be computed from those of its run, and the aggregate of any
+// range can be found by a single O(log n) descent from the root that uses the
+// aggregates of the spans lying entirely within the range.
+//
+// Aggregates are computed lazily and cached in the 'aug' field of each sparse
+// node. The structural operations that change the span of a node (remove(),
+// addSparserLevel(), splitStructure() and so on) discard its cached
+// aggregates as they update the nodes on their 'prevs' path, and noteSet()
+// and noteSwap() do the same for the nodes above a replaced element. A
+// discarded aggregate is recomputed from the run below it when it is next
+// needed.
+
+// A Monoid describes an associative operation with an identity element, which
+// is used to aggregate ranges of an ISkipList (see EnableAggregate()). For
+// example, Monoid{Identity: 0, Combine: add} aggregates sums, and adding
+// Lift: func(e ElemType) ElemType { if pred(e) { return 1 }; return 0 } turns
+// this into a count of matching elements.
+type Monoid struct {
+	// Identity is the aggregate of an empty range. Combine(Identity, x) and
+	// Combine(x, Identity) must both equal x.
+	Identity ElemType
+	// Combine combines the aggregates of two adjacent ranges. It must be
+	// associative, but need not be commutative.
+	Combine func(a, b ElemType) ElemType
+	// Lift, if non-nil, maps each element to its aggregate. If Lift is nil, the
+	// aggregate of an element is the element itself.
+	Lift func(e ElemType) ElemType
+}
+
+func (m *Monoid) lift(e ElemType) ElemType {
+	if m.Lift == nil {
+		return e
+	}
+	return m.Lift(e)
+}
+
+// A spanAug holds the aggregates of the elements in the span of a sparse
+// node. It is never modified once computed, so copies of a node can share it.
+type spanAug struct {
+	sum      int64
+	min, max ElemType // only maintained if minMax is set
+	agg      ElemType // only maintained if a Monoid is registered
+}
+
+// spanConfig records which span aggregates are enabled.
+type spanConfig struct {
+	sums   bool
+	minMax bool
+	monoid *Monoid
+}
+
+func (c *spanConfig) enabled() bool {
+	return c.sums || c.minMax || c.monoid != nil
+}
+
+// A spanFold accumulates the aggregates of a sequence of elements and spans.
+type spanFold struct {
+	c   *spanConfig
+	aug spanAug
+	// Set once anything has been added, so that the first aggregate can be
+	// taken as it is (rather than, e.g., combined with the Monoid's
+	// identity).
+	nonEmpty bool
+	// If set, the cached aggregates of sparse nodes are neither used nor
+	// computed (see validateSpans()).
+	noCache bool
+}
+
+func (f *spanFold) addElem(e ElemType) {
+	f.aug.sum += int64(elemToDist(e))
+	if f.c.minMax {
+		if f.nonEmpty {
+			f.aug.min, f.aug.max = min(f.aug.min, e), max(f.aug.max, e)
+		} else {
+			f.aug.min, f.aug.max = e, e
+		}
+	}
+	if m := f.c.monoid; m != nil {
+		if f.nonEmpty {
+			f.aug.agg = m.Combine(f.aug.agg, m.lift(e))
+		} else {
+			f.aug.agg = m.lift(e)
+		}
+	}
+	f.nonEmpty = true
+}
+
+func (f *spanFold) addAug(a *spanAug) {
+	f.aug.sum += a.sum
+	if f.c.minMax {
+		if f.nonEmpty {
+			f.aug.min, f.aug.max = min(f.aug.min, a.min), max(f.aug.max, a.max)
+		} else {
+			f.aug.min, f.aug.max = a.min, a.max
+		}
+	}
+	if m := f.c.monoid; m != nil {
+		if f.nonEmpty {
+			f.aug.agg = m.Combine(f.aug.agg, a.agg)
+		} else {
+			f.aug.agg = a.agg
+		}
+	}
+	f.nonEmpty = true
+}
+
+// runEnd returns the node that follows the run below the sparse node n, or
+// nil if n is the last node on its level.
+func runEnd(n *listNode) *listNode {
+	if n.next == nil {
+		return nil
+	}
+	return n.next.nextLevel
+}
+
+// foldRun folds the elements in [from, to) of a run of nodes into f. The run
+// starts at 'first', ends before 'stop' and spans 'size' elements. Positions
+// are relative to the start of the run.
+func foldRun(f *spanFold, first, stop *listNode, size, from, to int) {
+	pos := 0
+	for n := first; n != stop && pos < to; n = n.next {
+		if n.nextLevel == nil {
+			if pos >= from {
+				f.addElem(n.elem)
+			}
+			pos++
+			continue
+		}
+
+		s := size - pos
+		if n.next != stop {
+			s = elemToDist(n.elem)
+		}
+		if pos >= from && pos+s <= to && !f.noCache {
+			f.addAug(spanAggregate(f.c, n))
+		} else if pos+s > from {
+			foldRun(f, n.nextLevel, runEnd(n), s, from-pos, to-pos)
+		}
+		pos += s
+	}
+}
+
+// spanAggregate returns the aggregates of the span of the sparse node n,
+// computing them if they aren't cached.
+func spanAggregate(c *spanConfig, n *listNode) *spanAug {
+	if n.aug == nil {
+		f := spanFold{c: c}
+		foldRun(&f, n.nextLevel, runEnd(n), math.MaxInt, 0, math.MaxInt)
+		n.aug = &f.aug
+	}
+	return n.aug
+}
+
+// foldRange returns the aggregates of the elements in [from, to).
+func foldRange(l *ISkipList, from, to int) spanAug {
+	f := spanFold{c: l.ext.spans}
+	foldRun(&f, l.root, nil, l.length, from, to)
+	return f.aug
+}
+
+// invalidateSpans discards the cached aggregates of every sparse node whose
+// span includes an element in [from, to], where 0 <= from <= to < length.
+// Only the sparse levels are visited, so this is safe to call while the
+// densest level is being relinked.
+func invalidateSpans(l *ISkipList, from, to int) {
+	node, i := l.root, 0
+	for node.nextLevel != nil {
+		for node.next != nil && i+elemToDist(node.elem) <= from {
+			i += elemToDist(node.elem)
+			node = node.next
+		}
+		for n, j := node, i; ; {
+			n.aug = nil
+			if n.next == nil || j+elemToDist(n.elem) > to {
+				break
+			}
+			j += elemToDist(n.elem)
+			n = n.next
+		}
+		node = node.nextLevel
+	}
+}
+
+// clearSpans discards the cached aggregates of every sparse node. It runs in
+// O(n) time.
+func clearSpans(l *ISkipList) {
+	for level := l.root; level != nil && level.nextLevel != nil; level = level.nextLevel {
+		for n := level; n != nil; n = n.next {
+			n.aug = nil
+		}
+	}
+}
+
+func enableSpans(l *ISkipList) *spanConfig {
+	ext := getExt(l)
+	if ext.spans == nil {
+		ext.spans = &spanConfig{}
+	}
+	// The cached aggregates don't include the one being enabled.
+	clearSpans(l)
+	return ext.spans
+}
+
+// releaseSpans is called after an aggregate has been disabled. It discards
+// the cached aggregates, which include the disabled one, and the span
+// configuration if no aggregates remain enabled.
+func releaseSpans(l *ISkipList) {
+	clearSpans(l)
+	if !l.ext.spans.enabled() {
+		l.ext.spans = nil
+	}
+}
+
+// validateSpans checks that every cached aggregate matches the elements in
+// the span of its node.
+func validateSpans(l *ISkipList) error {
+	for level, li := l.root, 0; level != nil && level.nextLevel != nil; level, li = level.nextLevel, li+1 {
+		for n := level; n != nil; n = n.next {
+			if n.aug == nil {
+				continue
+			}
+			f := spanFold{c: l.ext.spans, noCache: true}
+			foldRun(&f, n.nextLevel, runEnd(n), math.MaxInt, 0, math.MaxInt)
+			if f.aug != *n.aug {
+				return fmt.Errorf("node on level %v has span aggregates %+v, expected %+v", li, *n.aug, f.aug)
+			}
+		}
+	}
+	return nil
+}
+
+// EnableRangeSums enables the maintenance of sums of spans of the ISkipList,
+// so that SumRange() runs in O(log n) time. This allows an ISkipList to be
+// used in place of a Fenwick tree (binary indexed tree) when elements also
+// need to be inserted and removed. Each node on a sparse level caches the sum
+// of the elements between it and the next node on its level. Operations that
+// add, remove or replace elements discard the cached sums of the O(log n)
+// nodes above the affected elements, and SumRange() recomputes them as
+// needed, so its running time is amortized. Modifications made directly via
+// element pointers are not seen. Enabling range sums takes O(n) time. Copies
+// of an ISkipList do not inherit range sums.
+func (l *ISkipList) EnableRangeSums() {
+	if l.HasRangeSums() {
+		return
+	}
+	enableSpans(l).sums = true
+}
+
+// DisableRangeSums disables the range sums enabled by EnableRangeSums.
+func (l *ISkipList) DisableRangeSums() {
+	if !l.HasRangeSums() {
+		return
+	}
+	l.ext.spans.sums = false
+	releaseSpans(l)
+}
+
+// HasRangeSums returns true iff range sums are enabled.
+func (l *ISkipList) HasRangeSums() bool {
+	return l.ext != nil && l.ext.spans != nil && l.ext.spans.sums
+}
+
+// SumRange returns the sum of the elements in the range [from, to). If
+// neither 'from' nor 'to' is out of bounds but to <= from, it returns 0. It
+// runs in O(log n) amortized time. It panics if range sums have not been
+// enabled.
+func (l *ISkipList) SumRange(from, to int) int64 {
+	if !l.HasRangeSums() {
+		panic("Range sums have not been enabled for this ISkipList; call EnableRangeSums first")
+	}
+	if from < 0 || from > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", from, l))
+	}
+	if to < 0 || to > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", to, l))
+	}
+
+	if to <= from {
+		return 0
+	}
+	return foldRange(l, from, to).sum
+}
+
+// EnableAggregate enables the maintenance of aggregates of spans of the
+// ISkipList according to the specified Monoid, so that AggregateRange() runs
+// in O(log n) amortized time. Only one Monoid can be registered at a time;
+// enabling another replaces it. The costs are as for EnableRangeSums(), plus
+// the cost of calling the Monoid's functions when aggregates are recomputed.
+// The functions must not access the ISkipList. EnableAggregate panics if
+// m.Combine is nil.
+func (l *ISkipList) EnableAggregate(m Monoid) {
+	if m.Combine == nil {
+		panic("Monoid passed to EnableAggregate has no Combine function")
+	}
+	enableSpans(l).monoid = &m
+}
+
+// DisableAggregate disables the aggregates enabled by EnableAggregate.
+func (l *ISkipList) DisableAggregate() {
+	if !l.HasAggregate() {
+		return
+	}
+	l.ext.spans.monoid = nil
+	releaseSpans(l)
+}
+
+// HasAggregate returns true iff a Monoid has been registered using
+// EnableAggregate().
+func (l *ISkipList) HasAggregate() bool {
+	return l.ext != nil && l.ext.spans != nil && l.ext.spans.monoid != nil
+}
+
+// AggregateRange returns the aggregate of the elements in the range
+// [from, to) according to the Monoid registered using EnableAggregate(). If
+// neither 'from' nor 'to' is out of bounds but to <= from, it returns the
+// Monoid's identity. It runs in O(log n) amortized time. It panics if no
+// Monoid has been registered.
+func (l *ISkipList) AggregateRange(from, to int) ElemType {
+	if !l.HasAggregate() {
+		panic("Aggregates have not been enabled for this ISkipList; call EnableAggregate first")
+	}
+	if from < 0 || from > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", from, l))
+	}
+	if to < 0 || to > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", to, l))
+	}
+
+	if to <= from {
+		return l.ext.spans.monoid.Identity
+	}
+	return foldRange(l, from, to).agg
+}
+
+// EnableRangeMinMax enables the maintenance of the minimum and maximum
+// elements of spans of the ISkipList, so that RangeMin() and RangeMax() run in
+// O(log n) amortized time. This is useful for sliding window minimum and
+// maximum queries. The costs are as for EnableRangeSums().
+func (l *ISkipList) EnableRangeMinMax() {
+	if l.HasRangeMinMax() {
+		return
+	}
+	enableSpans(l).minMax = true
+}
+
+// DisableRangeMinMax disables the maintenance of span minima and maxima
+// enabled by EnableRangeMinMax.
+func (l *ISkipList) DisableRangeMinMax() {
+	if !l.HasRangeMinMax() {
+		return
+	}
+	l.ext.spans.minMax = false
+	releaseSpans(l)
+}
+
+// HasRangeMinMax returns true iff span minima and maxima are enabled.
+func (l *ISkipList) HasRangeMinMax() bool {
+	return l.ext != nil && l.ext.spans != nil && l.ext.spans.minMax
+}
+
+// RangeMin returns the minimum element in the range [from, to). The second
+// return value is false iff the range is empty (i.e. to <= from). It runs in
+// O(log n) amortized time. It panics if span minima and maxima have not been
+// enabled.
+func (l *ISkipList) RangeMin(from, to int) (ElemType, bool) {
+	lo, _, ok := rangeMinMax(l, from, to)
+	return lo, ok
+}
+
+// RangeMax returns the maximum element in the range [from, to). The second
+// return value is false iff the range is empty (i.e. to <= from). It runs in
+// O(log n) amortized time. It panics if span minima and maxima have not been
+// enabled.
+func (l *ISkipList) RangeMax(from, to int) (ElemType, bool) {
+	_, hi, ok := rangeMinMax(l, from, to)
+	return hi, ok
+}
+
+func rangeMinMax(l *ISkipList, from, to int) (lo, hi ElemType, ok bool) {
+	if !l.HasRangeMinMax() {
+		panic("Span minima and maxima have not been enabled for this ISkipList; call EnableRangeMinMax first")
+	}
+	if from < 0 || from > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", from, l))
+	}
+	if to < 0 || to > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", to, l))
+	}
+
+	if to <= from {
+		return
+	}
+	aug := foldRange(l, from, to)
+	return aug.min, aug.max, true
+}
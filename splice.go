@@ -0,0 +1,779 @@
+package iskiplist
+
+import "fmt"
+
+// This file contains the structural primitives used to split and join
+// ISkipLists without visiting every element.
+
+// splitAt splits an ISkipList so that it retains the elements in [0, index)
+// and returns a new ISkipList containing the elements in [index, length). The
+// new ISkipList does not inherit optional features. splitAt runs in O(log n)
+// time if no optional features that track elements are enabled.
+func splitAt(l *ISkipList, index int) *ISkipList {
+	noteTruncate(l, index)
+	return splitStructure(l, index)
+}
+
+// splitStructure does the work of splitAt without notifying optional
+// features. It is used by operations that put the elements back together
+// again and account for their movement themselves.
+func splitStructure(l *ISkipList, index int) *ISkipList {
+	var tail ISkipList
+	if index == l.length {
+		return &tail
+	}
+	noteModified(l)
+
+	if index == 0 {
+		tail.length = l.length
+		tail.nLevels = l.nLevels
+		tail.root = l.root
+		l.length = 0
+		l.nLevels = 0
+		l.root = nil
+		l.cache = nil
+		return &tail
+	}
+
+	prevs := make([]*listNode, l.nLevels)
+	prevIndices := make([]int, l.nLevels)
+	node := getToWithPrevIndices(l.root, index-1, prevs, prevIndices, searchObserver(l))
+
+	// Build the root column of the tail from the densest level up. If a level
+	// already has a node at 'index' then we can use it (and, since every node
+	// is also present on all denser levels, the same goes for all denser
+	// levels). Otherwise, we need a new node that points to the next node on
+	// the level (if any).
+	below := node.next
+	node.next = nil
+	for li := len(prevs) - 1; li >= 0; li-- {
+		p := prevs[li]
+		var col *listNode
+		if p.next != nil && prevIndices[li]+elemToDist(p.elem) == index {
+			col = p.next
+		} else {
+			col = &listNode{nextLevel: below}
+			if p.next != nil {
+				col.next = p.next
+				col.elem = distToElem(prevIndices[li] + elemToDist(p.elem) - index)
+			}
+		}
+		p.next = nil
+		p.aug = nil
+		below = col
+	}
+
+	tail.root = below
+	tail.length = l.length - index
+	tail.nLevels = l.nLevels
+	l.length = index
+	l.cache = nil
+
+	trimEmptyLevels(l)
+	trimEmptyLevels(&tail)
+
+	return &tail
+}
+
+// trimEmptyLevels removes the sparsest levels of the ISkipList on which the
+// first node is the only node. After a split, both halves keep all the levels
+// of the original ISkipList, so many of these levels may be empty. Levels that
+// still have other nodes on them are kept: dropping them (e.g. to match
+// estimateNLevelsFromLength) would discard promoted nodes, and an ISkipList
+// that was repeatedly split and joined would gradually lose its sparse levels.
+func trimEmptyLevels(l *ISkipList) {
+	n := 0
+	for node := l.root; n < int(l.nLevels) && node.next == nil; node = node.nextLevel {
+		n++
+	}
+	shrink(l, n)
+}
+
+// concat appends the elements of 'other' to l by linking the levels of other
+// onto the end of the levels of l. It consumes 'other', which is left empty.
+// Random numbers are drawn from the generator of 'rnd' (usually l itself).
+// concat runs in O(log n) time if no optional features that track elements
+// are enabled for l.
+func concat(l *ISkipList, other *ISkipList, rnd *ISkipList) {
+	if other.length == 0 {
+		return
+	}
+
+	oldLength := l.length
+	otherLength := other.length
+	joinStructure(l, other, rnd)
+	noteClear(other, otherLength)
+	noteInsertRange(l, oldLength, oldLength+otherLength)
+}
+
+// joinStructure does the work of concat without notifying optional features.
+func joinStructure(l *ISkipList, other *ISkipList, rnd *ISkipList) {
+	if other.length == 0 {
+		return
+	}
+	noteModified(l)
+	noteModified(other)
+
+	if l.length == 0 {
+		l.root = other.root
+		l.nLevels = other.nLevels
+		l.length = other.length
+		l.cache = nil
+	} else {
+		// The first node of 'other' is present on every level. If we kept all
+		// of these levels, then building an ISkipList by repeated
+		// concatenation of short ISkipLists would give a degenerate
+		// structure. So, as in insertAtBeginning, we randomly choose again
+		// the number of levels for this node, and link past it on the levels
+		// above. As in addSparserLevel, new levels are added if the node
+		// should be taller than both ISkipLists; otherwise, concatenating
+		// short ISkipLists would never add any levels.
+		height := nTosses(rnd)
+		if height >= maxLevels {
+			height = maxLevels - 1
+		}
+
+		// Make sure that both ISkipLists have the same number of levels.
+		nLevels := l.nLevels
+		if other.nLevels > nLevels {
+			nLevels = other.nLevels
+		}
+		if int32(height) > nLevels {
+			nLevels = int32(height)
+		}
+		addNRootLevels(l, int(nLevels-l.nLevels))
+		l.nLevels = nLevels
+		addNRootLevels(other, int(nLevels-other.nLevels))
+		other.nLevels = nLevels
+		l.cache = nil
+
+		// Descending to the last element gives the last node on each level.
+		prevs := make([]*listNode, l.nLevels)
+		prevIndices := make([]int, l.nLevels)
+		last := getToWithPrevIndices(l.root, l.length-1, prevs, prevIndices, searchObserver(l))
+
+		col := other.root
+		for li, p := range prevs {
+			p.aug = nil
+			if int(l.nLevels)-li <= height {
+				p.next = col
+				p.elem = distToElem(l.length - prevIndices[li])
+			} else {
+				p.next = col.next
+				if col.next != nil {
+					p.elem = distToElem(l.length - prevIndices[li] + elemToDist(col.elem))
+				}
+			}
+			col = col.nextLevel
+		}
+		last.next = col
+
+		l.length += other.length
+	}
+
+	other.root = nil
+	other.length = 0
+	other.nLevels = 0
+	other.cache = nil
+}
+
+// Rebalance discards the sparse levels of the ISkipList and rebuilds them
+// from scratch, drawing a fresh level for every element, so that the level
+// structure (including the number of levels) once again follows the ideal
+// distribution for the current length. This can restore search performance
+// after a workload that has caused the structure to drift: for example,
+// Remove() never reduces the number of levels, and long runs of insertions
+// and removals at the same position can leave some regions of the ISkipList
+// with too many or too few promoted nodes (see DepthReport() and Stats()).
+// The elements themselves and optional features such as the value index are
+// unaffected. Rebalance runs in O(n) time.
+func (l *ISkipList) Rebalance() {
+	if tracing(l) {
+		trace(l, "Rebalance")
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	if l.length == 0 {
+		return
+	}
+	rebuild(l, densest(l), l.length, l)
+}
+
+// rebuild makes a chain of n densest-level nodes starting at 'first' (the last
+// of which must have a nil 'next' pointer) the contents of l, building new
+// sparse levels above it. Level assignments are drawn from the generator of
+// 'rnd' (usually l itself). This is used by operations that reorder or filter
+// the nodes of the densest level, as it's easier (and no slower
+// asymptotically) to start from scratch than to patch up the existing sparse
+// levels. rebuild runs in O(n) time.
+func rebuild(l *ISkipList, first *listNode, n int, rnd *ISkipList) {
+	noteModified(l)
+	l.cache = nil
+	l.length = n
+	if n == 0 {
+		l.root = nil
+		l.nLevels = 0
+		return
+	}
+
+	heights := make([]uint8, n)
+	nLevels := 0
+	for i := 0; i < n; i++ {
+		h := nTosses(rnd)
+		if h >= maxLevels {
+			h = maxLevels - 1
+		}
+		heights[i] = uint8(h)
+		if h > nLevels {
+			nLevels = h
+		}
+	}
+	// The first node is present on every level. Its own height is included
+	// in the maximum above, so that a chain of one node still gets a random
+	// number of levels.
+	heights[0] = uint8(nLevels)
+
+	buildLevels(l, first, heights)
+}
+
+// buildLevels builds the sparse levels of l above a chain of len(heights)
+// densest-level nodes starting at 'first', which must be non-empty. The node
+// at index i appears on heights[i] sparse levels. heights[0] is the number of
+// sparse levels, and no other height may exceed it. The caller is responsible
+// for updating the length of l and invalidating its cache.
+func buildLevels(l *ISkipList, first *listNode, heights []uint8) {
+	b := newLevelBuilder(int(heights[0]))
+	node := first
+	for _, h := range heights {
+		b.add(node, int(h))
+		node = node.next
+	}
+	b.finish(l)
+}
+
+// levelBuilder builds sparse levels incrementally above the nodes of a
+// densest level, which are added in order.
+type levelBuilder struct {
+	// The last node (and its index) on each sparse level, indexed by height
+	// above the densest level.
+	last        []*listNode
+	lastIndices []int
+	root        *listNode
+	i           int
+}
+
+func newLevelBuilder(nLevels int) *levelBuilder {
+	return &levelBuilder{
+		last:        make([]*listNode, nLevels+1),
+		lastIndices: make([]int, nLevels+1),
+	}
+}
+
+// add adds a node of the densest level that appears on 'height' sparse
+// levels. The first node added must appear on every level.
+func (b *levelBuilder) add(node *listNode, height int) {
+	below := node
+	for h := 1; h <= height; h++ {
+		sn := &listNode{nextLevel: below}
+		if p := b.last[h]; p != nil {
+			p.next = sn
+			p.elem = distToElem(b.i - b.lastIndices[h])
+		}
+		b.last[h] = sn
+		b.lastIndices[h] = b.i
+		below = sn
+	}
+	if b.i == 0 {
+		b.root = below
+	}
+	b.i++
+}
+
+// finish sets the root and number of levels of l. See buildLevels().
+func (b *levelBuilder) finish(l *ISkipList) {
+	l.root = b.root
+	l.nLevels = int32(len(b.last) - 1)
+}
+
+// densest returns the first node on the densest level of the ISkipList, or nil
+// if it is empty.
+func densest(l *ISkipList) *listNode {
+	n := l.root
+	if n == nil {
+		return nil
+	}
+	for n.nextLevel != nil {
+		n = n.nextLevel
+	}
+	return n
+}
+
+// noteInsertRange calls noteInsert for each of the elements in [from, to),
+// which must already have been added to the ISkipList.
+func noteInsertRange(l *ISkipList, from, to int) {
+	if l.ext == nil {
+		return
+	}
+	// The nodes may have come from another ISkipList, whose changes weren't
+	// reflected in their cached span aggregates.
+	if l.ext.spans != nil && from < to {
+		invalidateSpans(l, from, to-1)
+	}
+	if !tracksElements(l) {
+		return
+	}
+	l.ForAllRangeI(from, to, func(i int, e *ElemType) {
+		noteInsert(l, i, *e)
+	})
+}
+
+// tracksElements returns true iff an optional feature that has to be notified
+// of each added or removed element is enabled.
+func tracksElements(l *ISkipList) bool {
+	return l.ext != nil && (l.ext.positions != nil || l.ext.summary != nil || l.ext.metrics != nil)
+}
+
+// enforceMaxLengthAfterBulkInsert evicts elements following the insertion of
+// a range of elements at 'index', in the same way as enforceMaxLength.
+func enforceMaxLengthAfterBulkInsert(l *ISkipList, index int) {
+	if l.ext == nil || l.ext.maxLength == 0 {
+		return
+	}
+	if index == 0 {
+		evictAfter(l, l.ext.maxLength)
+		return
+	}
+	for l.length > l.ext.maxLength {
+		evict(l, 0)
+	}
+}
+
+// Extend appends all the elements of 'other' to the ISkipList. The ISkipList
+// 'other' is not modified (and may be the same ISkipList). Extend makes a
+// structural copy of 'other' (see Copy()) and links its levels onto the end
+// of the ISkipList, so it runs in O(m + log n) time, where m is the length of
+// 'other'.
+func (l *ISkipList) Extend(other *ISkipList) {
+	checkCapacity(l, other.length)
+
+	if tracing(l) {
+		trace(l, "Extend", other)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	concat(l, other.Copy(), l)
+	enforceMaxLengthAfterBulkInsert(l, l.length)
+}
+
+// Append appends all the elements of 'other' to the ISkipList. Unlike
+// Extend(), Append links the levels of 'other' directly onto the end of the
+// ISkipList, so 'other' is consumed (i.e. left empty). This runs in
+// O(log n + log m) time, where m is the length of 'other', unless optional
+// features that track elements (such as the value index) are enabled, in which
+// case each element appended has to be accounted for. 'other' must not be the
+// same ISkipList.
+func (l *ISkipList) Append(other *ISkipList) {
+	if other == l {
+		panic("An ISkipList cannot be appended to itself")
+	}
+	checkCapacity(l, other.length)
+
+	if tracing(l) {
+		trace(l, "Append", other)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	if other.length == 0 {
+		return
+	}
+
+	concat(l, other, l)
+	enforceMaxLengthAfterBulkInsert(l, l.length)
+}
+
+// AppendSlice appends the elements of a slice to the ISkipList. The new nodes
+// are linked together first and then joined onto the end of the ISkipList as
+// for Append(), so this runs in O(log n + k) time, where k is the length of the
+// slice, and is considerably faster than calling PushBack() for each element.
+func (l *ISkipList) AppendSlice(elems []ElemType) {
+	checkCapacity(l, len(elems))
+
+	if tracing(l) {
+		trace(l, "AppendSlice", elems)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	if len(elems) == 0 {
+		return
+	}
+
+	var b chainBuilder
+	for _, e := range elems {
+		b.add(e)
+	}
+	concat(l, b.build(l), l)
+	enforceMaxLengthAfterBulkInsert(l, l.length)
+}
+
+// PrependList adds all the elements of 'other' to the beginning of the
+// ISkipList, preserving their order. The ISkipList 'other' is not modified
+// (and may be the same ISkipList). Like Extend(), PrependList makes a
+// structural copy of 'other' and links the levels of the ISkipList onto its
+// end, so it runs in O(m + log n) time, where m is the length of 'other'.
+// Building an ISkipList from chunks in reverse order using PrependList is
+// therefore much faster than inserting the elements of each chunk
+// individually.
+func (l *ISkipList) PrependList(other *ISkipList) {
+	checkCapacity(l, other.length)
+
+	if tracing(l) {
+		trace(l, "PrependList", other)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	if other.length == 0 {
+		return
+	}
+
+	prependList(l, other.Copy())
+}
+
+// PrependSlice adds the elements of a slice to the beginning of the ISkipList,
+// preserving their order. The new nodes are linked together first and the
+// levels of the ISkipList are then linked onto their end, so this runs in
+// O(log n + k) time, where k is the length of the slice, and is considerably
+// faster than calling PushFront() for each element in reverse order.
+func (l *ISkipList) PrependSlice(elems []ElemType) {
+	checkCapacity(l, len(elems))
+
+	if tracing(l) {
+		trace(l, "PrependSlice", elems)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	if len(elems) == 0 {
+		return
+	}
+
+	var b chainBuilder
+	for _, e := range elems {
+		b.add(e)
+	}
+	prependList(l, b.build(l))
+}
+
+// prependList adds the elements of 'head' to the beginning of l, consuming
+// 'head'.
+func prependList(l *ISkipList, head *ISkipList) {
+	m := head.length
+
+	// Move the structure of l to a temporary ISkipList, so that joining
+	// doesn't touch any optional features enabled for l.
+	var rest ISkipList
+	rest.root, rest.length, rest.nLevels = l.root, l.length, l.nLevels
+	joinStructure(head, &rest, l)
+
+	noteModified(l)
+	l.root, l.length, l.nLevels = head.root, head.length, head.nLevels
+	l.cache = nil
+	noteInsertRange(l, 0, m)
+	enforceMaxLengthAfterBulkInsert(l, 0)
+}
+
+// ExtractIf removes all the elements of the ISkipList for which 'pred' returns
+// true, and returns them (in their original order) as a new ISkipList. The
+// removed nodes are spliced into the new ISkipList rather than being copied.
+// ExtractIf runs in O(n) time. It does not modify the ISkipList if no
+// elements are removed; otherwise, the levels of the ISkipList are rebuilt.
+// The predicate must not access the ISkipList.
+func (l *ISkipList) ExtractIf(pred func(ElemType) bool) *ISkipList {
+	if tracing(l) {
+		trace(l, "ExtractIf", pred)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	var extracted ISkipList
+	var kept, keptLast, exFirst, exLast *listNode
+	nKept, nEx := 0, 0
+	for node := densest(l); node != nil; {
+		next := node.next
+		if pred(node.elem) {
+			noteRemove(l, nKept, node.elem)
+			node.next = nil
+			if exLast == nil {
+				exFirst = node
+			} else {
+				exLast.next = node
+			}
+			exLast = node
+			nEx++
+		} else {
+			if keptLast == nil {
+				kept = node
+			} else {
+				keptLast.next = node
+			}
+			keptLast = node
+			nKept++
+		}
+		node = next
+	}
+
+	if nEx == 0 {
+		return &extracted
+	}
+
+	if keptLast != nil {
+		keptLast.next = nil
+	}
+	rebuild(l, kept, nKept, l)
+	rebuild(&extracted, exFirst, nEx, l)
+	return &extracted
+}
+
+// ExtractRange removes the elements in [from, to) from the ISkipList and
+// returns them as a new ISkipList. The removed nodes are spliced into the new
+// ISkipList rather than being copied, so ExtractRange runs in O(log n) time
+// unless optional features that track elements (such as the value index) are
+// enabled, in which case each element removed has to be accounted for. The
+// new ISkipList does not inherit optional features. If neither 'from' nor
+// 'to' is out of bounds but to <= from, then an empty ISkipList is returned.
+func (l *ISkipList) ExtractRange(from, to int) *ISkipList {
+	if from < 0 || from > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", from, l))
+	}
+	if to < 0 || to > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", to, l))
+	}
+
+	if tracing(l) {
+		trace(l, "ExtractRange", from, to)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	if to <= from {
+		return &ISkipList{}
+	}
+
+	tail := splitStructure(l, to)
+	extracted := splitAt(l, from)
+	joinStructure(l, tail, l)
+	return extracted
+}
+
+// SplitAt splits the ISkipList before the element at the specified index, or
+// at the end of the ISkipList if the index is equal to its length. The
+// ISkipList retains the elements preceding the index and is returned as the
+// first return value. The second return value is a new ISkipList containing
+// the element at the index and all subsequent elements. The links at each
+// level are severed rather than the elements being copied, so SplitAt runs in
+// O(log n) time unless optional features that track elements (such as the
+// value index) are enabled. The new ISkipList does not inherit optional
+// features.
+func (l *ISkipList) SplitAt(index int) (*ISkipList, *ISkipList) {
+	if index < 0 || index > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", index, l))
+	}
+
+	if tracing(l) {
+		trace(l, "SplitAt", index)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	return l, splitAt(l, index)
+}
+
+// SplitAtFunc splits the ISkipList before the first element for which 'pred'
+// returns true. The ISkipList retains the elements preceding that element and
+// is returned as the first return value. The second return value is a new
+// ISkipList containing the matching element and all subsequent elements (or
+// an empty ISkipList if no element matches). SplitAtFunc scans the ISkipList
+// up to the first matching element, and then splits it in O(log n) time. The
+// new ISkipList does not inherit optional features such as the value index.
+func (l *ISkipList) SplitAtFunc(pred func(ElemType) bool) (*ISkipList, *ISkipList) {
+	if tracing(l) {
+		trace(l, "SplitAtFunc", pred)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	index := l.length
+	l.IterateI(func(i int, e *ElemType) bool {
+		if pred(*e) {
+			index = i
+			return false
+		}
+		return true
+	})
+
+	return l, splitAt(l, index)
+}
+
+// SplitInto splits the ISkipList into k contiguous pieces whose lengths differ
+// by at most one, with longer pieces first. The ISkipList retains the first
+// piece and is returned as the first element of the result; the other pieces
+// are new ISkipLists, which do not inherit optional features. If k is greater
+// than the length of the ISkipList then some pieces are empty. SplitInto
+// panics if k < 1. Each split takes O(log n) time, so SplitInto runs in
+// O(k log n) time, which makes it a cheap way to divide up an ISkipList for
+// parallel processing.
+func (l *ISkipList) SplitInto(k int) []*ISkipList {
+	if k < 1 {
+		panic(fmt.Sprintf("Invalid number of pieces %v passed to SplitInto", k))
+	}
+
+	if tracing(l) {
+		trace(l, "SplitInto", k)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	size, extra := l.length/k, l.length%k
+	pieces := make([]*ISkipList, k)
+	for i := k - 1; i > 0; i-- {
+		start := i*size + extra
+		if i < extra {
+			start = i * (size + 1)
+		}
+		pieces[i] = splitAt(l, start)
+	}
+	pieces[0] = l
+	return pieces
+}
+
+// InsertListAt inserts all the elements of 'other' before the element at the
+// specified index, or at the end of the ISkipList if the index is equal to its
+// length. The levels of 'other' are spliced into the ISkipList, so 'other' is
+// consumed (i.e. left empty). This runs in O(log n + log m) time, where m is
+// the length of 'other', unless optional features that track elements (such
+// as the value index) are enabled, in which case each element inserted has to
+// be accounted for. 'other' must not be the same ISkipList.
+func (l *ISkipList) InsertListAt(index int, other *ISkipList) {
+	if index < 0 || index > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", index, l))
+	}
+	if other == l {
+		panic("An ISkipList cannot be inserted into itself")
+	}
+	checkCapacity(l, other.length)
+
+	if tracing(l) {
+		trace(l, "InsertListAt", index, other)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	if other.length == 0 {
+		return
+	}
+
+	insertListAt(l, index, other)
+}
+
+// MoveRange removes the elements in [from, to) from the ISkipList and inserts
+// them into 'dst' before the element at index dstIndex (or at the end of 'dst'
+// if dstIndex is equal to its length). The nodes are moved rather than
+// copied, so MoveRange runs in O(log n + log m) time, where m is the length of
+// 'dst', unless optional features that track elements (such as the value
+// index) are enabled for either ISkipList. 'dst' must not be the same
+// ISkipList (see RotateRange() and MoveElement() for moving elements within an
+// ISkipList). If neither 'from' nor 'to' is out of bounds but to <= from, then
+// this is a no-op.
+func (l *ISkipList) MoveRange(dst *ISkipList, from, to, dstIndex int) {
+	if dst == l {
+		panic("MoveRange cannot move elements within the same ISkipList")
+	}
+	if from < 0 || from > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", from, l))
+	}
+	if to < 0 || to > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", to, l))
+	}
+	if dstIndex < 0 || dstIndex > dst.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", dstIndex, dst))
+	}
+	if to > from {
+		checkCapacity(dst, to-from)
+	}
+
+	if tracing(l) {
+		trace(l, "MoveRange", dst, from, to, dstIndex)
+	}
+	if tracing(dst) {
+		trace(dst, "MoveRange", l, from, to, dstIndex)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+		defer assertValid(dst)
+	}
+
+	if to <= from {
+		return
+	}
+
+	tail := splitStructure(l, to)
+	moved := splitAt(l, from)
+	joinStructure(l, tail, l)
+	insertListAt(dst, dstIndex, moved)
+}
+
+// InsertSlice inserts the elements of a slice before the element at the
+// specified index, or at the end of the ISkipList if the index is equal to its
+// length. The new nodes are linked together first and then spliced into the
+// ISkipList as for InsertListAt(), so this runs in O(log n + k) time, where k
+// is the length of the slice, rather than the O(k log n) time required to
+// Insert() each element separately.
+func (l *ISkipList) InsertSlice(index int, elems []ElemType) {
+	if index < 0 || index > l.length {
+		panic(fmt.Sprintf("Out of bounds index %v into ISkipList %+v", index, l))
+	}
+	checkCapacity(l, len(elems))
+
+	if tracing(l) {
+		trace(l, "InsertSlice", index, elems)
+	}
+	if debugAssertions {
+		defer assertValid(l)
+	}
+
+	if len(elems) == 0 {
+		return
+	}
+
+	var b chainBuilder
+	for _, e := range elems {
+		b.add(e)
+	}
+	insertListAt(l, index, b.build(l))
+}
+
+func insertListAt(l *ISkipList, index int, other *ISkipList) {
+	m := other.length
+	tail := splitStructure(l, index)
+	joinStructure(l, other, l)
+	noteClear(other, m)
+	joinStructure(l, tail, l)
+	noteInsertRange(l, index, index+m)
+	enforceMaxLengthAfterBulkInsert(l, index)
+}
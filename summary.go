@@ -0,0 +1,189 @@
+package iskiplist
+
+import (
+	"math"
+	"math/big"
+	"math/bits"
+	"sort"
+)
+
+type summaryStats struct {
+	sum int64
+	// The sum of squares is kept as an exact 128-bit integer (high and low
+	// words), since a floating point sum would accumulate rounding error
+	// over a long sequence of additions and removals.
+	sumSqHi, sumSqLo uint64
+	bounds           []ElemType
+	buckets          []int
+}
+
+func (s *summaryStats) add(e ElemType, sign int) {
+	d := elemToDist(e)
+	s.sum += int64(sign * d)
+	a := uint64(d)
+	if d < 0 {
+		a = -a
+	}
+	hi, lo := bits.Mul64(a, a)
+	var c uint64
+	if sign > 0 {
+		s.sumSqLo, c = bits.Add64(s.sumSqLo, lo, 0)
+		s.sumSqHi, _ = bits.Add64(s.sumSqHi, hi, c)
+	} else {
+		s.sumSqLo, c = bits.Sub64(s.sumSqLo, lo, 0)
+		s.sumSqHi, _ = bits.Sub64(s.sumSqHi, hi, c)
+	}
+	if s.buckets != nil {
+		s.buckets[bucketOf(s.bounds, e)] += sign
+	}
+}
+
+func (s *summaryStats) reset() {
+	s.sum = 0
+	s.sumSqHi, s.sumSqLo = 0, 0
+	for i := range s.buckets {
+		s.buckets[i] = 0
+	}
+}
+
+func bucketOf(bounds []ElemType, e ElemType) int {
+	return sort.Search(len(bounds), func(i int) bool { return elemToDist(e) < elemToDist(bounds[i]) })
+}
+
+// EnableSummaryStats enables the incremental maintenance of summary statistics
+// (sum, sum of squares and, optionally, a histogram) over the elements of the
+// ISkipList. Once enabled, the statistics are updated by every operation that
+// adds, removes or replaces elements, so that Sum(), MeanStd() and Histogram()
+// run in constant time.
+//
+// If 'bucketBounds' is non-empty, a histogram is also maintained. The bounds
+// must be in ascending order. Bucket 0 counts elements less than
+// bucketBounds[0], bucket i counts elements e such that
+// bucketBounds[i-1] <= e < bucketBounds[i], and the last bucket counts elements
+// greater than or equal to the last bound. Keep the number of buckets small, as
+// each update requires a binary search over the bounds.
+//
+// As with the value index, modifications made directly via element pointers
+// are not seen. Enabling summary statistics on a non-empty ISkipList takes
+// O(n) time. Copies of an ISkipList do not inherit summary statistics.
+func (l *ISkipList) EnableSummaryStats(bucketBounds []ElemType) {
+	s := &summaryStats{}
+	if len(bucketBounds) > 0 {
+		s.bounds = make([]ElemType, len(bucketBounds))
+		copy(s.bounds, bucketBounds)
+		s.buckets = make([]int, len(bucketBounds)+1)
+	}
+	l.ForAll(func(e *ElemType) {
+		s.add(*e, 1)
+	})
+	getExt(l).summary = s
+}
+
+// DisableSummaryStats disables the statistics enabled by EnableSummaryStats.
+func (l *ISkipList) DisableSummaryStats() {
+	if l.ext != nil {
+		l.ext.summary = nil
+	}
+}
+
+// HasSummaryStats returns true iff summary statistics are enabled.
+func (l *ISkipList) HasSummaryStats() bool {
+	return l.ext != nil && l.ext.summary != nil
+}
+
+func summary(l *ISkipList) *summaryStats {
+	if !l.HasSummaryStats() {
+		panic("Summary statistics have not been enabled for this ISkipList; call EnableSummaryStats first")
+	}
+	return l.ext.summary
+}
+
+// Sum returns the sum of the elements of the ISkipList. It panics if summary
+// statistics have not been enabled.
+func (l *ISkipList) Sum() int64 {
+	return summary(l).sum
+}
+
+// MeanStd returns the mean and (population) standard deviation of the elements
+// of the ISkipList. Both values are NaN if the ISkipList is empty. It panics if
+// summary statistics have not been enabled. The variance is computed exactly
+// from the integer sum and sum of squares before being converted to floating
+// point, so the result does not drift however many updates have been made
+// (provided that Sum() does not overflow).
+func (l *ISkipList) MeanStd() (mean, std float64) {
+	s := summary(l)
+	if l.length == 0 {
+		return math.NaN(), math.NaN()
+	}
+	n := float64(l.length)
+	mean = float64(s.sum) / n
+	return mean, math.Sqrt(s.scaledVariance(uint64(l.length)) / (n * n))
+}
+
+// scaledVariance returns n^2 times the variance of the n elements, computed
+// exactly as n * sumSq - sum^2 before being converted to floating point. The
+// computation is done in 128 bits unless n * sumSq overflows.
+func (s *summaryStats) scaledVariance(n uint64) float64 {
+	hiHi, hiLo := bits.Mul64(s.sumSqHi, n)
+	hi, lo := bits.Mul64(s.sumSqLo, n)
+	hi, c := bits.Add64(hi, hiLo, 0)
+	if hiHi != 0 || c != 0 {
+		return s.scaledVarianceBig(n)
+	}
+
+	a := uint64(s.sum)
+	if s.sum < 0 {
+		a = -a
+	}
+	sqHi, sqLo := bits.Mul64(a, a)
+	var b uint64
+	lo, b = bits.Sub64(lo, sqLo, 0)
+	hi, b = bits.Sub64(hi, sqHi, b)
+	if b != 0 { // only if Sum() has overflowed
+		return 0
+	}
+	return uint128ToFloat64(hi, lo)
+}
+
+func (s *summaryStats) scaledVarianceBig(n uint64) float64 {
+	var v, t big.Int
+	v.SetUint64(s.sumSqHi)
+	v.Lsh(&v, 64)
+	v.Or(&v, t.SetUint64(s.sumSqLo))
+	v.Mul(&v, t.SetUint64(n))
+	t.SetInt64(s.sum)
+	v.Sub(&v, t.Mul(&t, &t))
+	if v.Sign() < 0 { // only if Sum() has overflowed
+		return 0
+	}
+	f, _ := new(big.Float).SetInt(&v).Float64()
+	return f
+}
+
+// uint128ToFloat64 returns the float64 nearest to hi<<64 | lo. The bits that
+// don't fit into 64 bits are folded into a sticky bit, so that the conversion
+// of the remaining 64 bits rounds correctly.
+func uint128ToFloat64(hi, lo uint64) float64 {
+	if hi == 0 {
+		return float64(lo)
+	}
+	shift := uint(64 - bits.LeadingZeros64(hi))
+	m := hi<<(64-shift) | lo>>shift
+	if lo<<(64-shift) != 0 {
+		m |= 1
+	}
+	return math.Ldexp(float64(m), int(shift))
+}
+
+// Histogram returns a copy of the histogram buckets described in the
+// documentation for EnableSummaryStats(). It returns nil if no bucket bounds
+// were given. It panics if summary statistics have not been enabled.
+func (l *ISkipList) Histogram() []int {
+	s := summary(l)
+	if s.buckets == nil {
+		return nil
+	}
+	r := make([]int, len(s.buckets))
+	copy(r, s.buckets)
+	return r
+}
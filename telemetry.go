@@ -0,0 +1,90 @@
+package iskiplist
+
+import "math/bits"
+
+// Telemetry records the cost of searches through a single ISkipList. It is
+// returned by ISkipList.Telemetry(). The costs are counted in the same way as
+// for SearchStats(), but unlike SearchStats(), which aggregates searches
+// through all ISkipLists and requires a build tag, Telemetry can be enabled at
+// run time for individual ISkipLists.
+type Telemetry struct {
+	SearchCost
+	// A histogram of the number of nodes visited by each search after the
+	// starting node. PathLengths[0] counts searches that visited no further
+	// nodes, and PathLengths[k] for k > 0 counts searches that visited
+	// between 2^(k-1) and 2^k-1 nodes.
+	PathLengths []uint64
+	// The greatest number of nodes visited by a single search.
+	LongestPath uint64
+}
+
+func (t *Telemetry) add(c *SearchCost) {
+	t.SearchCost.add(c)
+	if c.Searches == 0 {
+		return
+	}
+
+	path := c.SparseSteps + c.Descents + c.DenseSteps
+	k := bits.Len64(path)
+	for len(t.PathLengths) <= k {
+		t.PathLengths = append(t.PathLengths, 0)
+	}
+	t.PathLengths[k]++
+	if path > t.LongestPath {
+		t.LongestPath = path
+	}
+}
+
+// EnableTelemetry starts recording the cost of every search through the
+// ISkipList (i.e. every descent through its levels to find the node at a given
+// index), including the number of nodes visited and whether the search was
+// able to start from the index cache. This makes it possible to verify that
+// the cache is helping a particular access pattern. Recording costs a few
+// additions per search. Copies of an ISkipList do not inherit telemetry.
+func (l *ISkipList) EnableTelemetry() {
+	ext := getExt(l)
+	if ext.telemetry == nil {
+		ext.telemetry = &Telemetry{}
+	}
+}
+
+// DisableTelemetry stops recording telemetry and discards the telemetry
+// recorded so far.
+func (l *ISkipList) DisableTelemetry() {
+	if l.ext != nil {
+		l.ext.telemetry = nil
+	}
+}
+
+// HasTelemetry returns true iff telemetry is enabled.
+func (l *ISkipList) HasTelemetry() bool {
+	return l.ext != nil && l.ext.telemetry != nil
+}
+
+// Telemetry returns the telemetry recorded since EnableTelemetry() or
+// ResetTelemetry() was last called. It returns a zero Telemetry if telemetry
+// is not enabled.
+func (l *ISkipList) Telemetry() Telemetry {
+	t := telemetryOf(l)
+	if t == nil {
+		return Telemetry{}
+	}
+	r := *t
+	r.PathLengths = append([]uint64(nil), t.PathLengths...)
+	return r
+}
+
+// ResetTelemetry resets the telemetry returned by Telemetry() to zero. It has
+// no effect if telemetry is not enabled.
+func (l *ISkipList) ResetTelemetry() {
+	if t := telemetryOf(l); t != nil {
+		*t = Telemetry{}
+	}
+}
+
+func telemetryOf(l *ISkipList) *Telemetry {
+	if l.ext == nil {
+		return nil
+	}
+	return l.ext.telemetry
+}
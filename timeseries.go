@@ -0,0 +1,112 @@
+package iskiplist
+
+import "fmt"
+
+// TimeSeries is an ISkipList in which each element carries a timestamp.
+// Elements are kept in order of non-decreasing timestamp, so ranges of
+// elements can be located by time as well as by index. Timestamps are ints
+// (e.g. Unix nanoseconds on 64-bit platforms), and their units are up to the
+// user. The zero value is an empty TimeSeries.
+type TimeSeries struct {
+	times  ISkipList
+	values ISkipList
+}
+
+// Seed seeds the random number generators used by the TimeSeries. If Seed is
+// called, it should be called immediately following creation of the
+// TimeSeries.
+func (ts *TimeSeries) Seed(seed1 uint64, seed2 uint64) {
+	ts.times.Seed(seed1, seed2)
+	ts.values.Seed(seed1, seed2+1)
+}
+
+// Length returns the number of elements in the TimeSeries.
+func (ts *TimeSeries) Length() int {
+	return ts.values.Length()
+}
+
+// Clear empties the TimeSeries.
+func (ts *TimeSeries) Clear() {
+	ts.times.Clear()
+	ts.values.Clear()
+}
+
+// Append adds an element to the end of the TimeSeries. It panics if the
+// timestamp is less than that of the current last element.
+func (ts *TimeSeries) Append(t int, elem ElemType) {
+	if n := ts.times.Length(); n > 0 && ts.times.At(n-1) > t {
+		panic(fmt.Sprintf("Timestamp %v precedes last timestamp %v in call to 'Append'", t, ts.times.At(n-1)))
+	}
+	ts.times.PushBack(distToElem(t))
+	ts.values.PushBack(elem)
+}
+
+// Insert adds an element to the TimeSeries at the position determined by its
+// timestamp. If there are existing elements with the same timestamp, the new
+// element is placed after them. Insert returns the index of the new element.
+func (ts *TimeSeries) Insert(t int, elem ElemType) int {
+	i := searchFirst(&ts.times, func(e ElemType) bool { return elemToDist(e) > t })
+	ts.times.Insert(i, distToElem(t))
+	ts.values.Insert(i, elem)
+	return i
+}
+
+// At returns the timestamp and value of the element at the specified index.
+func (ts *TimeSeries) At(i int) (int, ElemType) {
+	return elemToDist(ts.times.At(i)), ts.values.At(i)
+}
+
+// Set updates the value of the element at the specified index. The timestamp
+// is unchanged.
+func (ts *TimeSeries) Set(i int, elem ElemType) {
+	ts.values.Set(i, elem)
+}
+
+// Remove removes the element at the specified index and returns its timestamp
+// and value.
+func (ts *TimeSeries) Remove(i int) (int, ElemType) {
+	return elemToDist(ts.times.Remove(i)), ts.values.Remove(i)
+}
+
+// PopFront removes the first (i.e. oldest) element of the TimeSeries. The third
+// return value is true iff the TimeSeries was non-empty prior to the pop.
+func (ts *TimeSeries) PopFront() (t int, elem ElemType, ok bool) {
+	if ts.values.Length() == 0 {
+		return
+	}
+	t, elem = ts.Remove(0)
+	ok = true
+	return
+}
+
+// RangeByTime returns the range of indices [from, to) of the elements with
+// timestamps t such that t1 <= t < t2. The result can be passed to
+// IterateRange() etc. via Values(). RangeByTime runs in O(log^2 n) time (see
+// SearchSorted()).
+func (ts *TimeSeries) RangeByTime(t1, t2 int) (from, to int) {
+	from = searchFirst(&ts.times, func(e ElemType) bool { return elemToDist(e) >= t1 })
+	to = searchFirst(&ts.times, func(e ElemType) bool { return elemToDist(e) >= t2 })
+	if to < from {
+		to = from
+	}
+	return
+}
+
+// RemoveBefore removes all elements with timestamps less than t. It returns the
+// number of elements removed. The removed elements are split off rather than
+// being removed one at a time, so RemoveBefore runs in O(log^2 n) time (see
+// RangeByTime()).
+func (ts *TimeSeries) RemoveBefore(t int) int {
+	n := searchFirst(&ts.times, func(e ElemType) bool { return elemToDist(e) >= t })
+	ts.times.ExtractRange(0, n)
+	ts.values.ExtractRange(0, n)
+	return n
+}
+
+// Values returns the ISkipList containing the values of the TimeSeries. It may
+// be used for read-only access, e.g. to iterate over a range returned by
+// RangeByTime(). Adding or removing elements directly via the returned
+// ISkipList will cause values and timestamps to get out of sync.
+func (ts *TimeSeries) Values() *ISkipList {
+	return &ts.values
+}
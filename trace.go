@@ -0,0 +1,41 @@
+package iskiplist
+
+// A Tracer is notified of each call to a method that modifies an ISkipList.
+// See SetTracer().
+type Tracer interface {
+	// Trace is called with the name of the method (e.g. "Insert") and its
+	// arguments, in the order in which they appear in the method's signature.
+	// It is called after the arguments have been checked but before the
+	// ISkipList is modified, so the Tracer may inspect the current state of
+	// the ISkipList (e.g. to record the element that is about to be removed).
+	// The Tracer must not modify the ISkipList.
+	Trace(method string, args []interface{})
+}
+
+// SetTracer sets a Tracer that is notified of each call to a method that
+// modifies the ISkipList (including the methods that seed its pseudorandom
+// number generator). Only the outermost call is traced: for example, a call
+// to PopFront() is traced as "PopFront" and not also as "Remove", and
+// elements evicted to enforce a maximum length (see SetMaxLength()) are not
+// traced separately. This makes it possible to layer logging, metrics or a
+// journal of operations on top of an ISkipList. Passing nil removes the
+// Tracer. Copies of an ISkipList do not inherit the Tracer.
+func (l *ISkipList) SetTracer(t Tracer) {
+	if t == nil {
+		if l.ext != nil {
+			l.ext.tracer = nil
+		}
+		return
+	}
+	getExt(l).tracer = t
+}
+
+// tracing is checked before calling trace so that the arguments don't have to
+// be boxed when no Tracer is set.
+func tracing(l *ISkipList) bool {
+	return l.ext != nil && l.ext.tracer != nil
+}
+
+func trace(l *ISkipList, method string, args ...interface{}) {
+	l.ext.tracer.Trace(method, args)
+}
@@ -0,0 +1,147 @@
+package iskiplist
+
+import "fmt"
+
+// This file contains operations that derive a new ISkipList from the elements
+// of an existing one. The new ISkipList is built bottom-up: a chain of
+// densest-level nodes is created in a single pass, and then sparse levels are
+// added above it (see rebuild()). This is considerably faster than calling
+// PushBack() for each element.
+
+// chainBuilder builds a chain of densest-level nodes.
+type chainBuilder struct {
+	first, last *listNode
+	n           int
+}
+
+func (b *chainBuilder) add(elem ElemType) {
+	node := &listNode{elem: elem}
+	if b.last == nil {
+		b.first = node
+	} else {
+		b.last.next = node
+	}
+	b.last = node
+	b.n++
+}
+
+// build returns a new ISkipList with the elements of the chain, using the
+// pseudorandom number generator of 'rnd' to assign levels.
+func (b *chainBuilder) build(rnd *ISkipList) *ISkipList {
+	var l ISkipList
+	rebuild(&l, b.first, b.n, rnd)
+	return &l
+}
+
+// FromSlice returns a new ISkipList containing the elements of a slice. The
+// nodes of the densest level are created in a single pass and the sparse
+// levels are then built on top of them, so FromSlice runs in O(n) time and is
+// considerably faster than calling PushFront() or PushBack() for each element.
+func FromSlice(elems []ElemType) *ISkipList {
+	var b chainBuilder
+	for _, e := range elems {
+		b.add(e)
+	}
+	l := new(ISkipList)
+	rebuild(l, b.first, b.n, l)
+	return l
+}
+
+// Map returns a new ISkipList whose element at index i is f(l.At(i)). The
+// ISkipList is copied as for Copy(), so the result has the same level
+// structure and no random numbers are drawn. Map runs in O(n) time. The
+// function must not modify the ISkipList.
+func (l *ISkipList) Map(f func(ElemType) ElemType) *ISkipList {
+	cp := l.Copy()
+	for node := densest(cp); node != nil; node = node.next {
+		node.elem = f(node.elem)
+	}
+	return cp
+}
+
+// Scan returns a new ISkipList containing the running accumulation of the
+// elements of the ISkipList: the element at index i of the result is
+// f(acc, l.At(i)), where acc is the element at index i-1 of the result (or
+// init if i is 0). If f is nil, addition is used, so that l.Scan(0, nil)
+// gives the prefix sums of the ISkipList. Scan runs in O(n) time.
+func (l *ISkipList) Scan(init ElemType, f func(acc, v ElemType) ElemType) *ISkipList {
+	var b chainBuilder
+	acc := init
+	l.ForAll(func(e *ElemType) {
+		if f == nil {
+			acc = distToElem(elemToDist(acc) + elemToDist(*e))
+		} else {
+			acc = f(acc, *e)
+		}
+		b.add(acc)
+	})
+	return b.build(l)
+}
+
+// Delta returns a new ISkipList containing the differences between adjacent
+// elements of the ISkipList: the element at index i of the result is
+// l.At(i) - l.At(i-1), and the element at index 0 is l.At(0). Delta is thus
+// the inverse of l.Scan(0, nil). It runs in O(n) time.
+func (l *ISkipList) Delta() *ISkipList {
+	var b chainBuilder
+	prev := 0
+	l.ForAll(func(e *ElemType) {
+		d := elemToDist(*e)
+		b.add(distToElem(d - prev))
+		prev = d
+	})
+	return b.build(l)
+}
+
+// DeltaInPlace replaces each element of the ISkipList with the difference
+// between it and the preceding element, as for Delta(). It runs in O(n) time.
+func (l *ISkipList) DeltaInPlace() {
+	if tracing(l) {
+		trace(l, "DeltaInPlace")
+	}
+
+	prev := 0
+	l.ForAllI(func(i int, e *ElemType) {
+		old := *e
+		d := elemToDist(old)
+		*e = distToElem(d - prev)
+		prev = d
+		noteSet(l, i, old, *e)
+	})
+}
+
+// Scatter deals the elements of the ISkipList round-robin into k new
+// ISkipLists, so that the element at index i goes to the ISkipList at index
+// i%k of the result. The relative order of elements is preserved within each
+// new ISkipList. The ISkipList itself is not modified. Scatter panics if
+// k < 1. It runs in O(n + k) time.
+func (l *ISkipList) Scatter(k int) []*ISkipList {
+	return l.ScatterFunc(k, func(i int, e ElemType) int { return i % k })
+}
+
+// ScatterFunc distributes the elements of the ISkipList into k new ISkipLists
+// in a single pass. The element at index i goes to the ISkipList at index
+// classify(i, elem) of the result, which must be in the range [0, k). The
+// relative order of elements is preserved within each new ISkipList. The
+// ISkipList itself is not modified. ScatterFunc panics if k < 1. It runs in
+// O(n + k) time.
+func (l *ISkipList) ScatterFunc(k int, classify func(i int, e ElemType) int) []*ISkipList {
+	if k < 1 {
+		panic(fmt.Sprintf("Invalid number of ISkipLists %v passed to ScatterFunc", k))
+	}
+
+	builders := make([]chainBuilder, k)
+	l.ForAllI(func(i int, e *ElemType) {
+		c := classify(i, *e)
+		if c < 0 || c >= k {
+			panic(fmt.Sprintf("Classifier returned out of range index %v (k = %v)", c, k))
+		}
+		builders[c].add(*e)
+	})
+
+	lists := make([]*ISkipList, k)
+	for i := range builders {
+		lists[i] = builders[i].build(l)
+	}
+	return lists
+}
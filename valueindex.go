@@ -0,0 +1,178 @@
+package iskiplist
+
+import "sort"
+
+// EnableValueIndex enables an index mapping element values to their current
+// positions in the ISkipList. Once the index is enabled, PositionOf runs in
+// O(log n) time rather than requiring a linear scan, and the same goes for
+// IndexOf, LastIndexOf, IndicesOf and Contains. The index is maintained
+// by every operation that adds, removes or replaces elements, at the cost of
+// an additional O(log n) of work and some additional memory per element.
+//
+// The index is designed for the common case where each element is a unique
+// handle into a backing slice. Duplicate values are permitted, but PositionOf
+// then has to compare the positions of all occurrences.
+//
+// Modifications made directly via element pointers (as obtained from PtrAt()
+// or passed to iteration callbacks) are not seen by the index. Use Set() or
+// Update() to modify elements of an ISkipList with an index enabled.
+//
+// Enabling the index on a non-empty ISkipList takes O(n log n) time. Copies of
+// an ISkipList do not inherit the index.
+func (l *ISkipList) EnableValueIndex() {
+	ext := getExt(l)
+	if ext.byValue != nil {
+		return
+	}
+	enablePositions(l)
+	ext.byValue = make(map[ElemType][]*posNode)
+	for i := 0; i < ext.positions.length(); i++ {
+		n := ext.positions.at(i)
+		addToValueIndex(ext.byValue, n.value, n)
+	}
+}
+
+// DisableValueIndex disables the index enabled by EnableValueIndex.
+func (l *ISkipList) DisableValueIndex() {
+	if l.ext == nil {
+		return
+	}
+	l.ext.byValue = nil
+	releasePositions(l.ext)
+}
+
+// HasValueIndex returns true iff the value index is enabled.
+func (l *ISkipList) HasValueIndex() bool {
+	return l.ext != nil && l.ext.byValue != nil
+}
+
+// PositionOf returns the index of the first element with the specified value.
+// The second return value is false iff there is no such element. If the value
+// index is enabled, PositionOf runs in O(log n) time (assuming that the value
+// is unique); otherwise it performs a linear scan.
+func (l *ISkipList) PositionOf(v ElemType) (int, bool) {
+	if l.HasValueIndex() {
+		nodes := l.ext.byValue[v]
+		if len(nodes) == 0 {
+			return -1, false
+		}
+		index := l.ext.positions.indexOf(nodes[0])
+		for _, n := range nodes[1:] {
+			if i := l.ext.positions.indexOf(n); i < index {
+				index = i
+			}
+		}
+		return index, true
+	}
+
+	index := -1
+	l.IterateI(func(i int, e *ElemType) bool {
+		if *e == v {
+			index = i
+			return false
+		}
+		return true
+	})
+	return index, index != -1
+}
+
+// IndexOf returns the index of the first element with the specified value, or
+// -1 if there is no such element. It is otherwise the same as PositionOf().
+func (l *ISkipList) IndexOf(v ElemType) int {
+	index, _ := l.PositionOf(v)
+	return index
+}
+
+// LastIndexOf returns the index of the last element with the specified value,
+// or -1 if there is no such element. If the value index is enabled,
+// LastIndexOf runs in O(log n) time (assuming that the value is unique);
+// otherwise it scans the entire densest level of the ISkipList.
+func (l *ISkipList) LastIndexOf(v ElemType) int {
+	if l.HasValueIndex() {
+		index := -1
+		for _, n := range l.ext.byValue[v] {
+			if i := l.ext.positions.indexOf(n); i > index {
+				index = i
+			}
+		}
+		return index
+	}
+
+	index := -1
+	l.ForAllI(func(i int, e *ElemType) {
+		if *e == v {
+			index = i
+		}
+	})
+	return index
+}
+
+// IndicesOf returns the indices, in ascending order, of all the elements with
+// the specified value, or nil if there are no such elements. If the value
+// index is enabled, IndicesOf runs in O(k log n) time, where k is the number
+// of occurrences of the value (plus O(k log k) to sort the result); otherwise
+// it scans the entire densest level of the ISkipList.
+func (l *ISkipList) IndicesOf(v ElemType) []int {
+	if l.HasValueIndex() {
+		nodes := l.ext.byValue[v]
+		if len(nodes) == 0 {
+			return nil
+		}
+		indices := make([]int, len(nodes))
+		for i, n := range nodes {
+			indices[i] = l.ext.positions.indexOf(n)
+		}
+		sort.Ints(indices)
+		return indices
+	}
+
+	var indices []int
+	l.ForAllI(func(i int, e *ElemType) {
+		if *e == v {
+			indices = append(indices, i)
+		}
+	})
+	return indices
+}
+
+// Contains returns true iff the ISkipList contains an element with the
+// specified value. If the value index is enabled, Contains runs in constant
+// time; otherwise it performs a linear scan.
+func (l *ISkipList) Contains(v ElemType) bool {
+	if l.HasValueIndex() {
+		return len(l.ext.byValue[v]) > 0
+	}
+	return l.IndexOf(v) != -1
+}
+
+func enablePositions(l *ISkipList) {
+	ext := getExt(l)
+	if ext.positions != nil {
+		return
+	}
+	ext.positions = newPosTree()
+	l.ForAllI(func(i int, e *ElemType) {
+		ext.positions.insert(i, *e)
+	})
+}
+
+func addToValueIndex(m map[ElemType][]*posNode, v ElemType, n *posNode) {
+	m[v] = append(m[v], n)
+}
+
+func removeFromValueIndex(m map[ElemType][]*posNode, v ElemType, n *posNode) {
+	nodes := m[v]
+	for i, o := range nodes {
+		if o == n {
+			nodes[i] = nodes[len(nodes)-1]
+			nodes[len(nodes)-1] = nil
+			nodes = nodes[:len(nodes)-1]
+			break
+		}
+	}
+	if len(nodes) == 0 {
+		delete(m, v)
+	} else {
+		m[v] = nodes
+	}
+}